@@ -0,0 +1,125 @@
+package migration_test
+
+import (
+	"context"
+	"io/fs"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+)
+
+// The exported API is asserted at compile time so that moving code around
+// can't change a signature existing callers depend on.
+var (
+	_ migration.Migration         = (*migration.Definition)(nil)
+	_ migration.Logger            = migration.NopLogger{}
+	_ migration.Event             = migration.RunStarted{}
+	_ migration.Checksummer       = (*migration.Definition)(nil)
+	_ error                       = (*migration.ChecksumMismatchError)(nil)
+	_ error                       = (*migration.NotRecordedError)(nil)
+	_ error                       = (*migration.DirtyError)(nil)
+	_ error                       = (*migration.InterruptedError)(nil)
+	_ error                       = (*migration.DestructiveError)(nil)
+	_ error                       = (*migration.ReadOnlyError)(nil)
+	_ migration.Reverter          = (*redoMigration)(nil)
+	_ migration.Idempotent        = (*flakyMigration)(nil)
+	_ migration.ExecutionStrategy = (*migration.OnlineSchemaChange)(nil)
+	_ migration.Runner            = (*migration.Migrator)(nil)
+	_ migration.Namer             = (*migration.ChunkedMigration)(nil)
+	_ migration.Idempotent        = (*migration.ChunkedMigration)(nil)
+
+	_ func(context.Context, string, []migration.Migration, ...migration.Option)                                       = migration.MustMigrate
+	_ func(context.Context, string, []migration.Migration, ...migration.Option) error                                 = migration.Migrate
+	_ func(context.Context, *mysql.Config, []migration.Migration, ...migration.Option) error                          = migration.MigrateConfig
+	_ func(context.Context, migration.Source, []migration.Migration, ...migration.Option) error                       = migration.MigrateSource
+	_ func(context.Context, string, []migration.Migration, ...migration.Option) (migration.Report, error)             = migration.MigrateWithReport
+	_ func(context.Context, string, string, ...migration.Option) error                                                = migration.LoadSchema
+	_ func(context.Context, string, string, ...migration.Option) error                                                = migration.DumpSchema
+	_ func(context.Context, string, migration.SchemaWriter, ...migration.Option) error                                = migration.DumpSchemaTo
+	_ func(context.Context, *mysql.Config, migration.SchemaWriter, ...migration.Option) error                         = migration.DumpSchemaToConfig
+	_ func(context.Context, string, []migration.Migration, int, ...migration.Option) ([]int, error)                   = migration.Baseline
+	_ func(context.Context, *mysql.Config, []migration.Migration, int, ...migration.Option) ([]int, error)            = migration.BaselineConfig
+	_ func(context.Context, string, []migration.Migration, ...migration.Option) ([]migration.PlannedMigration, error) = migration.Plan
+	_ func(context.Context, string, ...migration.Option) ([]migration.Applied, error)                                 = migration.AppliedVersions
+	_ func(context.Context, string, ...migration.Option) (int, error)                                                 = migration.CurrentVersion
+	_ func(context.Context, string, []migration.Migration, ...migration.Option) ([]int, error)                        = migration.CheckPending
+	_ func(context.Context, string, []migration.Migration, ...migration.Option) error                                 = migration.CheckNoPending
+	_ func(context.Context, string, []migration.Migration, ...migration.Option) (bool, error)                         = migration.IsUpToDate
+	_ func(context.Context, string, []migration.Migration, time.Duration, ...migration.Option) error                  = migration.WaitUntilMigrated
+	_ func(context.Context, string, []migration.Migration, int, ...migration.Option) error                            = migration.SetApplied
+	_ func(context.Context, string, int, ...migration.Option) error                                                   = migration.ResetVersion
+	_ func(fs.FS, string, ...migration.LoadOption) ([]migration.Migration, error)                                     = migration.FromFS
+	_ func(context.Context, string, []migration.Migration, int, ...migration.Option) error                            = migration.Redo
+	_ func(context.Context, string, []*migration.Seed, ...migration.Option) ([]string, error)                         = migration.SeedDatabase
+
+	_ func(string, ...migration.Option) (*migration.Migrator, error)           = migration.New
+	_ func(*mysql.Config, ...migration.Option) (*migration.Migrator, error)    = migration.NewConfig
+	_ func(migration.Source, ...migration.Option) (*migration.Migrator, error) = migration.NewSource
+
+	_ func(*migration.Migrator, context.Context, []migration.Migration) error                     = (*migration.Migrator).Migrate
+	_ func(*migration.Migrator, context.Context, []migration.Migration) (migration.Report, error) = (*migration.Migrator).MigrateWithReport
+	_ func(*migration.Migrator, context.Context, string) error                                    = (*migration.Migrator).LoadSchema
+	_ func(*migration.Migrator, context.Context, string) error                                    = (*migration.Migrator).DumpSchema
+	_ func(*migration.Migrator, context.Context, migration.SchemaWriter) error                    = (*migration.Migrator).DumpSchemaTo
+
+	_ func(string) migration.Option                                                                                         = migration.WithTableName
+	_ func(time.Duration) migration.Option                                                                                  = migration.WithLock
+	_ func(bool) migration.Option                                                                                           = migration.WithCreateDatabase
+	_ func(migration.Logger) migration.Option                                                                               = migration.WithLogger
+	_ func(map[string]string) migration.Option                                                                              = migration.WithRunMetadata
+	_ func(int, migration.Migration) migration.Option                                                                       = migration.WithOverride
+	_ func(chan<- migration.Event) migration.Option                                                                         = migration.WithEvents
+	_ func(migration.MetricsCollector) migration.Option                                                                     = migration.WithMetrics
+	_ func(migration.Tracer) migration.Option                                                                               = migration.WithTracer
+	_ func() migration.Option                                                                                               = migration.WithConfirmReset
+	_ func() migration.Option                                                                                               = migration.WithForceRedo
+	_ func(int, time.Duration) migration.Option                                                                             = migration.WithRetry
+	_ func(time.Duration) migration.Option                                                                                  = migration.WithMigrationTimeout
+	_ func() migration.Option                                                                                               = migration.WithSingleStatements
+	_ func(...*migration.Repeatable) migration.Option                                                                       = migration.WithRepeatables
+	_ func(...*migration.Seed) migration.Option                                                                             = migration.WithSeeds
+	_ func(string) migration.Option                                                                                         = migration.WithEnvironment
+	_ func() migration.Option                                                                                               = migration.WithProductionSeeds
+	_ func(func(int, string) (string, error)) migration.Option                                                              = migration.WithStatementRewriter
+	_ func(func(int, string) error) migration.Option                                                                        = migration.WithValidator
+	_ func() migration.Option                                                                                               = migration.WithAllowDestructive
+	_ func(int, migration.ExecutionStrategy) migration.Option                                                               = migration.WithExecutionStrategy
+	_ func(int64) migration.Option                                                                                          = migration.WithLargeTableWarning
+	_ func(int64) migration.Option                                                                                          = migration.WithMaxTableRows
+	_ func() migration.Option                                                                                               = migration.WithoutPlanTableSizes
+	_ func(map[int]time.Duration) migration.Option                                                                          = migration.WithRecordedDurations
+	_ func([]migration.PlannedMigration) (time.Duration, []int)                                                             = migration.EstimateTotal
+	_ func(time.Duration) migration.Option                                                                                  = migration.WithVerificationTimeout
+	_ func() migration.Option                                                                                               = migration.WithEnforceOnlineDDL
+	_ func(...string) migration.Option                                                                                      = migration.WithTags
+	_ func(int) migration.Option                                                                                            = migration.WithConcurrency
+	_ func() migration.Option                                                                                               = migration.WithFailFast
+	_ func(context.Context, []string, []migration.Migration, ...migration.Option) ([]migration.DatabaseResult, error)       = migration.MigrateAll
+	_ func(context.Context, string, string, []migration.Migration, ...migration.Option) ([]migration.DatabaseResult, error) = migration.MigrateMatching
+	_ func(...string) migration.Option                                                                                      = migration.WithExpectedDatabases
+	_ func(string) migration.Option                                                                                         = migration.WithNamespace
+	_ func(...string) migration.Option                                                                                      = migration.WithNamespaces
+	_ func(string, string) migration.Option                                                                                 = migration.WithDatabaseCharset
+	_ func(int, time.Duration) migration.Option                                                                             = migration.WithConnectRetries
+	_ func(time.Duration) migration.Option                                                                                  = migration.WithConnectTimeout
+	_ func(int, time.Duration) migration.Option                                                                             = migration.WithFailoverRetry
+	_ func(...string) migration.Option                                                                                      = migration.WithMinServerVersion
+	_ func(map[string]string) migration.Option                                                                              = migration.WithSessionVariables
+	_ func(...int) migration.Option                                                                                         = migration.WithSkipBinlog
+	_ func() migration.Option                                                                                               = migration.WithKeepAutoIncrement
+	_ func() migration.Option                                                                                               = migration.WithStripDefiner
+	_ func() migration.Option                                                                                               = migration.WithSingleFile
+	_ func() migration.Option                                                                                               = migration.WithGzip
+	_ func(int) migration.Option                                                                                            = migration.WithDumpConcurrency
+
+	_ func(migration.Logger) migration.LoadOption = migration.WithUnlistedWarning
+
+	_ func(...string) *migration.OnlineSchemaChange = migration.GhOst
+	_ func(...string) *migration.OnlineSchemaChange = migration.PtOnlineSchemaChange
+
+	_ func(int, string, int, ...migration.ChunkOption) *migration.ChunkedMigration                   = migration.Chunked
+	_ func(int, string, string, string, int, ...migration.ChunkOption) *migration.ResumableMigration = migration.Resumable
+	_ func(string) migration.ChunkOption                                                             = migration.WithChunkName
+	_ func(time.Duration) migration.ChunkOption                                                      = migration.WithChunkPause
+)
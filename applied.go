@@ -0,0 +1,179 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// Applied is a migration recorded in the tracking table. It marshals to JSON
+// for status output.
+type Applied struct {
+	Version int `json:"version"`
+	// Name is empty for migrations recorded before names were.
+	Name string `json:"name,omitempty"`
+	// CreatedAt is the zero time when the stored value isn't a valid
+	// datetime, see WithStrictDates.
+	CreatedAt time.Time `json:"created_at"`
+	// AppliedBy is who applied the migration, see WithAppliedBy. It's empty
+	// for migrations recorded before it was.
+	AppliedBy string `json:"applied_by,omitempty"`
+	// Metadata is nil when none was recorded, see WithRunMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Binlog is nil when it wasn't measured.
+	Binlog *BinlogDelta `json:"binlog,omitempty"`
+	// Dirty is set while the migration is executing, and left set if it
+	// failed part way, see DirtyError.
+	Dirty bool `json:"dirty,omitempty"`
+	// Failure is why a dirty migration failed, nil when it hasn't or the
+	// failure couldn't be recorded.
+	Failure *Failure `json:"failure,omitempty"`
+}
+
+// Failure is the last failure of a migration.
+type Failure struct {
+	// Error is the error the migration failed with, truncated to 4096 bytes.
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+func AppliedVersions(ctx context.Context, dsn string, opts ...Option) ([]Applied, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.AppliedVersions(ctx)
+}
+
+func AppliedVersionsConfig(ctx context.Context, cfg *mysql.Config, opts ...Option) ([]Applied, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.AppliedVersions(ctx)
+}
+
+func AppliedVersionsSource(ctx context.Context, src Source, opts ...Option) ([]Applied, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.AppliedVersions(ctx)
+}
+
+// AppliedVersions returns the migrations recorded in the tracking table,
+// ordered by version. It never creates anything, so it's empty rather than an
+// error when the database or tracking table doesn't exist yet.
+func (m *Migrator) AppliedVersions(ctx context.Context) ([]Applied, error) {
+	conn, err := m.openIfExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return []Applied{}, nil
+	}
+	defer conn.Close()
+
+	exists, err := m.migrationsTableExists(ctx, conn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed checking if table %q exists", m.tableName)
+	}
+	if !exists {
+		return []Applied{}, nil
+	}
+
+	history, err := m.readHistory(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]Applied, 0, len(history))
+	for _, row := range history {
+		entry := Applied{Version: row.ID, Name: row.Name, CreatedAt: row.CreatedAt, AppliedBy: row.AppliedBy, Dirty: row.Dirty}
+		if row.Metadata.Valid {
+			if err := json.Unmarshal([]byte(row.Metadata.String), &entry.Metadata); err != nil {
+				return nil, errors.Wrapf(err, "migration %d has invalid metadata", row.ID)
+			}
+		}
+		if row.Failure.Valid {
+			entry.Failure = &Failure{Error: row.Failure.String, FailedAt: row.FailedAt}
+		}
+		if row.Binlog.Valid {
+			entry.Binlog = &BinlogDelta{}
+			if err := json.Unmarshal([]byte(row.Binlog.String), entry.Binlog); err != nil {
+				return nil, errors.Wrapf(err, "migration %d has an invalid binlog delta", row.ID)
+			}
+		}
+		applied = append(applied, entry)
+	}
+
+	return applied, nil
+}
+
+func CurrentVersion(ctx context.Context, dsn string, opts ...Option) (int, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return m.CurrentVersion(ctx)
+}
+
+func CurrentVersionConfig(ctx context.Context, cfg *mysql.Config, opts ...Option) (int, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return m.CurrentVersion(ctx)
+}
+
+func CurrentVersionSource(ctx context.Context, src Source, opts ...Option) (int, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return m.CurrentVersion(ctx)
+}
+
+// CurrentVersion returns the highest version recorded in the tracking table
+// that isn't dirty, or 0 when none has been, including when the database or
+// tracking table doesn't exist. It never creates anything.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int, error) {
+	conn, err := m.openIfExists(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if conn == nil {
+		return 0, nil
+	}
+	defer conn.Close()
+
+	exists, err := m.migrationsTableExists(ctx, conn)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed checking if table %q exists", m.tableName)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	columns, err := m.migrationsTableColumns(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	where := ""
+	if columns["dirty"] {
+		where = " WHERE dirty = 0"
+	}
+
+	var version int
+	err = conn.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s%s", dialect.QuoteIdentifier(m.tableName), where)).Scan(&version)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed reading current version from %q", m.tableName)
+	}
+
+	return version, nil
+}
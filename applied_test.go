@@ -0,0 +1,118 @@
+package migration_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppliedVersions(t *testing.T) {
+	dbname := "appliedtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:       2,
+			Up:       `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Metadata: map[string]string{"author": "rbone"},
+		},
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+	before := time.Now().Add(-time.Second)
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	applied, err = migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.Equal(t, 1, applied[0].Version)
+	require.Equal(t, 2, applied[1].Version)
+	require.Equal(t, map[string]string{"author": "rbone"}, applied[1].Metadata)
+	for _, entry := range applied {
+		require.True(t, entry.CreatedAt.After(before), "created_at %s", entry.CreatedAt)
+	}
+}
+
+func TestAppliedVersionsWithoutTrackingTable(t *testing.T) {
+	dbname := "appliedtest"
+	dropDB(dbname)
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_"+dbname)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	require.False(t, tableExists(fullDSN(dbname), "_migrations"))
+}
+
+func TestCurrentVersion(t *testing.T) {
+	dbname := "currentversiontest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	version, err := migration.CurrentVersion(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Equal(t, 0, version)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 3,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 7,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	version, err = migration.CurrentVersion(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Equal(t, 7, version)
+}
+
+func TestAppliedVersionsExposesRunMetadata(t *testing.T) {
+	dbname := "appliedmetadatatest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithRunMetadata(map[string]string{"git_sha": "abc123", "app_version": "1.4.2"}),
+		migration.WithAppliedBy("deploy-1234"),
+	)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, map[string]string{"git_sha": "abc123", "app_version": "1.4.2"}, applied[0].Metadata)
+
+	status, err := json.Marshal(applied)
+	require.NoError(t, err)
+
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal(status, &decoded))
+	require.Equal(t, float64(1), decoded[0]["version"])
+	require.Equal(t, "deploy-1234", decoded[0]["applied_by"])
+	require.Equal(t, map[string]interface{}{"git_sha": "abc123", "app_version": "1.4.2"}, decoded[0]["metadata"])
+	require.Contains(t, decoded[0], "created_at")
+	require.NotContains(t, decoded[0], "name")
+}
@@ -0,0 +1,47 @@
+package migration
+
+import (
+	"os"
+	"os/user"
+)
+
+// maxAppliedByLength is the size of the tracking table's applied_by column.
+const maxAppliedByLength = 255
+
+// WithAppliedBy changes what's recorded as having applied each migration from
+// the OS user and hostname, e.g. to a deploy ID.
+func WithAppliedBy(appliedBy string) Option {
+	return func(m *Migrator) {
+		m.appliedBy = appliedBy
+	}
+}
+
+// defaultAppliedBy identifies this process as user@hostname, or whichever of
+// the two is known.
+func defaultAppliedBy() string {
+	username := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	hostname, _ := os.Hostname()
+
+	switch {
+	case len(username) > 0 && len(hostname) > 0:
+		return username + "@" + hostname
+	case len(username) > 0:
+		return username
+	default:
+		return hostname
+	}
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
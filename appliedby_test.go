@@ -0,0 +1,55 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordsWhoAppliedMigrations(t *testing.T) {
+	dbname := "appliedbytest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 2,
+		Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+	})
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithAppliedBy("deploy-1234"),
+	)
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Contains(t, applied[0].AppliedBy, hostname)
+	require.Equal(t, "deploy-1234", applied[1].AppliedBy)
+
+	// it survives a dump and load
+	dir := fmt.Sprintf("%s/appliedbytest", os.TempDir())
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+	must(migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+	dropDB(dbname)
+	must(migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+
+	reloaded, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Equal(t, applied[0].AppliedBy, reloaded[0].AppliedBy)
+	require.Equal(t, "deploy-1234", reloaded[1].AppliedBy)
+}
@@ -0,0 +1,167 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// WithSchemaOnly makes LoadSchema load the tables without the migration
+// history, and without creating the migrations table at all, for databases
+// that are thrown away rather than migrated. It marks the database with a
+// _schema_only table instead, and Migrate refuses to run against a marked
+// database until Baseline records the version its schema is at.
+func WithSchemaOnly() Option {
+	return func(m *Migrator) {
+		m.schemaOnly = true
+	}
+}
+
+func Baseline(ctx context.Context, dsn string, migrations []Migration, throughVersion int, opts ...Option) ([]int, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Baseline(ctx, migrations, throughVersion)
+}
+
+func BaselineConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, throughVersion int, opts ...Option) ([]int, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Baseline(ctx, migrations, throughVersion)
+}
+
+func BaselineSource(ctx context.Context, src Source, migrations []Migration, throughVersion int, opts ...Option) ([]int, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Baseline(ctx, migrations, throughVersion)
+}
+
+// Baseline records every migration up to and including throughVersion as
+// applied without executing it, for databases whose schema was built some
+// other way, and returns the versions it recorded.
+func (m *Migrator) Baseline(ctx context.Context, migrations []Migration, throughVersion int) ([]int, error) {
+	if err := validateMigrations(migrations); err != nil {
+		return nil, err
+	}
+
+	if err := m.validateMetadata(migrations); err != nil {
+		return nil, err
+	}
+
+	conn, err := m.openDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	if err := m.createMigrationsTableIfNotExists(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	baseline := []Migration{}
+	for _, migration := range migrations {
+		if migration.Version() <= throughVersion {
+			baseline = append(baseline, migration)
+		}
+	}
+	baseline = sortMigrations(baseline)
+
+	applied, err := m.appliedVersionSet(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range baseline {
+		if applied[migration.Version()] {
+			return nil, errors.Errorf("can't baseline migration %d, it has already been recorded", migration.Version())
+		}
+	}
+
+	marked := []int{}
+	for _, migration := range baseline {
+		if err := m.recordMigration(ctx, conn, migration, m.appliedBy, false); err != nil {
+			return marked, errors.Wrapf(err, "failed recording migration %d", migration.Version())
+		}
+		marked = append(marked, migration.Version())
+	}
+
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("baselined db %q through migration %d, recorded %d migrations without executing them", m.src.DBName, throughVersion, len(marked)),
+		slog.String("db", m.src.DBName),
+		slog.Int("version", throughVersion),
+		slog.Any("versions", marked),
+	)
+
+	return marked, nil
+}
+
+// schemaOnlyTable marks a database loaded WithSchemaOnly, with the time of
+// each such load. It's shared by every namespace, as a schema only load leaves
+// out all their histories.
+const schemaOnlyTable = "_schema_only"
+
+func (m *Migrator) markSchemaOnly(ctx context.Context, conn *sql.DB) error {
+	_, err := conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s (
+				loaded_at DATETIME(6) NOT NULL
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci`,
+			dialect.QuoteIdentifier(schemaOnlyTable),
+		),
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed creating table %q", schemaOnlyTable)
+	}
+
+	_, err = conn.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (loaded_at) VALUES (?)", dialect.QuoteIdentifier(schemaOnlyTable)),
+		time.Now().UTC().Format(createdAtWriteFormat),
+	)
+	return errors.Wrapf(err, "failed recording schema only load in %q", schemaOnlyTable)
+}
+
+// checkTracked refuses to migrate a database loaded WithSchemaOnly that has
+// no migrations table, as its migrations would all run again on top of the
+// schema that was loaded. Databases built any other way without a migrations
+// table are migrated from the start, as they always have been.
+func (m *Migrator) checkTracked(ctx context.Context, conn *sql.DB) error {
+	exists, err := m.migrationsTableExists(ctx, conn)
+	if err != nil {
+		return errors.Wrapf(err, "failed checking if table %q exists", m.tableName)
+	}
+	if exists {
+		return nil
+	}
+
+	schemaOnly, err := oneExists(ctx, conn, "SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", schemaOnlyTable)
+	if err != nil {
+		return errors.Wrapf(err, "failed checking if table %q exists", schemaOnlyTable)
+	}
+	if schemaOnly {
+		return errors.Errorf(
+			"db %q was loaded without its history and has no %s table, call Baseline with the version its schema is at before migrating it",
+			m.src.DBName, m.tableName,
+		)
+	}
+
+	return nil
+}
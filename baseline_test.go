@@ -0,0 +1,120 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaOnlyLoadRequiresBaseline(t *testing.T) {
+	dbname := "schemaonlytest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/schemaonlytest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir)
+	require.NoError(t, err)
+
+	dropDB(dbname)
+
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithSchemaOnly())
+	require.NoError(t, err)
+	require.True(t, tableExists(fullDSN(dbname), "blarg"))
+	require.True(t, tableExists(fullDSN(dbname), "gralb"))
+	require.False(t, tableExists(fullDSN(dbname), "_migrations"))
+	require.True(t, tableExists(fullDSN(dbname), "_schema_only"))
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 3,
+		Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(255) NOT NULL`,
+	})
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.EqualError(t, err, `db "migration_test_schemaonlytest" was loaded without its history and has no _migrations table, call Baseline with the version its schema is at before migrating it`)
+	require.False(t, tableExists(fullDSN(dbname), "_migrations"))
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithNamespace("billing"))
+	require.EqualError(t, err, `db "migration_test_schemaonlytest" was loaded without its history and has no _migrations_billing table, call Baseline with the version its schema is at before migrating it`)
+
+	marked, err := migration.Baseline(context.Background(), fullDSN(dbname), migrations, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, marked)
+
+	_, err = migration.Baseline(context.Background(), fullDSN(dbname), migrations, 2)
+	require.EqualError(t, err, "can't baseline migration 1, it has already been recorded")
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(report.Applied))
+	require.Equal(t, 3, report.Applied[0].Version)
+	require.Equal(t, []int{1, 2}, report.Skipped)
+}
+
+func tableExists(dsn string, table string) bool {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	return oneExists(conn, "SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", table)
+}
+
+func TestBaselineAdoptsHandBuiltDatabase(t *testing.T) {
+	dbname := "adopttest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	// built by hand before the library was adopted
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_"+dbname)
+	execSQL(fullDSN(dbname), `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`)
+	execSQL(fullDSN(dbname), `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 3,
+			Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(64)`,
+		},
+	}
+
+	marked, err := migration.Baseline(context.Background(), fullDSN(dbname), migrations, 2, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, marked)
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, report.Skipped)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, 3, report.Applied[0].Version)
+	require.Contains(t, showSchema(fullDSN(dbname), "blarg"), "`name`")
+}
@@ -0,0 +1,44 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+)
+
+// BenchmarkMigrateNothingPending measures a boot where every migration has
+// already been applied, which used to take a query per migration.
+func BenchmarkMigrateNothingPending(b *testing.B) {
+	dbname := "benchtest"
+	dropDB(dbname)
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_"+dbname)
+
+	migrations := make([]migration.Migration, 1000)
+	for i := range migrations {
+		migrations[i] = &migration.Definition{ID: i + 1, Up: `DO 1`}
+	}
+
+	_, err := migration.Baseline(context.Background(), fullDSN(dbname), migrations, len(migrations), migration.WithLogger(migration.NopLogger{}))
+	must(err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+		must(err)
+	}
+}
+
+// BenchmarkDumpSchema measures dumping a few hundred tables, which used to
+// be done one table at a time.
+func BenchmarkDumpSchema(b *testing.B) {
+	dbname := "dumpbenchtest"
+	dropDB(dbname)
+	must(migration.Migrate(context.Background(), fullDSN(dbname), manyTables(300), migration.WithLogger(migration.NopLogger{})))
+	dir := b.TempDir()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		must(migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+	}
+}
@@ -0,0 +1,199 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// BinlogDelta approximates the binary log written while migrations ran, as a
+// guide to their impact on replicas. Writes from other clients at the same
+// time are included.
+type BinlogDelta struct {
+	Bytes int64 `json:"bytes"`
+	// Transactions and GTIDs are only known when GTIDs are enabled.
+	Transactions int64  `json:"transactions,omitempty"`
+	GTIDs        string `json:"gtids,omitempty"`
+}
+
+type binlogPosition struct {
+	file     string
+	position int64
+	gtids    string
+}
+
+// binlogTracker measures the binlog around each migration for one run. When
+// the binlog isn't available, or the user can't see it, tracking is turned off
+// for the rest of the run.
+type binlogTracker struct {
+	m        *Migrator
+	conn     *sql.DB
+	disabled bool
+}
+
+func (m *Migrator) newBinlogTracker(conn *sql.DB) *binlogTracker {
+	return &binlogTracker{m: m, conn: conn}
+}
+
+func (t *binlogTracker) position(ctx context.Context) (binlogPosition, bool) {
+	if t.disabled {
+		return binlogPosition{}, false
+	}
+
+	position, err := readBinlogPosition(ctx, t.conn)
+	if err != nil {
+		t.unavailable(ctx, err)
+		return binlogPosition{}, false
+	}
+	return position, true
+}
+
+func (t *binlogTracker) delta(ctx context.Context, before binlogPosition, after binlogPosition) (*BinlogDelta, bool) {
+	delta := &BinlogDelta{}
+
+	if before.file == after.file {
+		delta.Bytes = after.position - before.position
+	} else {
+		sizes, err := binlogSizes(ctx, t.conn)
+		if err != nil {
+			t.unavailable(ctx, err)
+			return nil, false
+		}
+		delta.Bytes = binlogBytesBetween(sizes, before, after)
+	}
+
+	if len(after.gtids) > 0 {
+		err := t.conn.QueryRowContext(ctx, "SELECT GTID_SUBTRACT(?, ?)", after.gtids, before.gtids).Scan(&delta.GTIDs)
+		if err != nil {
+			t.unavailable(ctx, err)
+			return nil, false
+		}
+		delta.Transactions = dialect.CountGTIDs(delta.GTIDs)
+	}
+
+	return delta, true
+}
+
+func (t *binlogTracker) unavailable(ctx context.Context, err error) {
+	t.disabled = true
+	t.m.log(ctx, slog.LevelDebug,
+		fmt.Sprintf("not tracking binlog written by migrations: %s", err),
+		slog.String("db", t.m.src.DBName),
+		slog.Any("error", err),
+	)
+}
+
+// readBinlogPosition reads the current binlog file, position and executed
+// GTIDs. MySQL 8.4 replaced SHOW MASTER STATUS with SHOW BINARY LOG STATUS.
+func readBinlogPosition(ctx context.Context, conn *sql.DB) (binlogPosition, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		rows, err = conn.QueryContext(ctx, "SHOW BINARY LOG STATUS")
+	}
+	if err != nil {
+		return binlogPosition{}, errors.Wrap(err, "unable to read binlog position")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return binlogPosition{}, errors.Wrap(err, "unable to read binlog position")
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return binlogPosition{}, errors.Wrap(err, "unable to read binlog position")
+		}
+		return binlogPosition{}, errors.New("binary logging is disabled")
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return binlogPosition{}, errors.Wrap(err, "unable to read binlog position")
+	}
+
+	position := binlogPosition{}
+	for i, column := range columns {
+		switch column {
+		case "File":
+			position.file = values[i].String
+		case "Position":
+			position.position, err = strconv.ParseInt(values[i].String, 10, 64)
+			if err != nil {
+				return binlogPosition{}, errors.Wrapf(err, "invalid binlog position %q", values[i].String)
+			}
+		case "Executed_Gtid_Set":
+			position.gtids = strings.Replace(values[i].String, "\n", "", -1)
+		}
+	}
+
+	return position, nil
+}
+
+func binlogSizes(ctx context.Context, conn *sql.DB) (map[string]int64, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW BINARY LOGS")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list binlogs")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list binlogs")
+	}
+
+	sizes := map[string]int64{}
+	for rows.Next() {
+		// Log_name and File_size, followed by Encrypted on newer servers
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errors.Wrap(err, "unable to list binlogs")
+		}
+		size, err := strconv.ParseInt(values[1].String, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid size for binlog %q", values[0].String)
+		}
+		sizes[values[0].String] = size
+	}
+
+	return sizes, rows.Err()
+}
+
+// binlogBytesBetween adds up the rest of before's file, any files written in
+// between and after's file up to its position. Binlog files sort by name.
+func binlogBytesBetween(sizes map[string]int64, before binlogPosition, after binlogPosition) int64 {
+	bytes := sizes[before.file] - before.position + after.position
+	for file, size := range sizes {
+		if file > before.file && file < after.file {
+			bytes += size
+		}
+	}
+	return bytes
+}
+
+func encodeBinlogDelta(delta *BinlogDelta) (sql.NullString, error) {
+	if delta == nil {
+		return sql.NullString{}, nil
+	}
+
+	encoded, err := json.Marshal(delta)
+	if err != nil {
+		return sql.NullString{}, errors.Wrap(err, "unable to encode binlog delta")
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, nil
+}
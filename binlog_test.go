@@ -0,0 +1,63 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+// The mysql:8.0 image used for the tests has binary logging on by default.
+func TestRecordsBinlogWrittenByMigrations(t *testing.T) {
+	dbname := "binlogtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(255) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `INSERT INTO blarg (id, name) VALUES (1, REPEAT('a', 255)), (2, REPEAT('b', 255)), (3, REPEAT('c', 255))`,
+		},
+	}
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(report.Applied))
+
+	inserted := report.Applied[1].Binlog
+	require.NotNil(t, inserted)
+	require.True(t, inserted.Bytes > 765, "expected the inserted rows in the binlog, got %d bytes", inserted.Bytes)
+	require.True(t, report.Binlog.Bytes >= inserted.Bytes)
+
+	stored := queryBinlogDelta(fullDSN(dbname), 2)
+	require.NotNil(t, stored)
+	require.Equal(t, inserted.Bytes, stored.Bytes)
+}
+
+func queryBinlogDelta(dsn string, id int) *migration.BinlogDelta {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var raw sql.NullString
+	if err := conn.QueryRow("SELECT binlog_delta FROM _migrations WHERE id = ?", id).Scan(&raw); err != nil {
+		panic(err)
+	}
+
+	if !raw.Valid {
+		return nil
+	}
+
+	var delta migration.BinlogDelta
+	must(json.Unmarshal([]byte(raw.String), &delta))
+	return &delta
+}
@@ -0,0 +1,99 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// killTimeout bounds how long KILL QUERY is given once ctx is done.
+const killTimeout = 10 * time.Second
+
+// InterruptedError is returned when ctx is done while a migration, or a file
+// in LoadSchema, is executing. Its statement is killed rather than left
+// running on the server, which can leave the schema partially modified.
+type InterruptedError struct {
+	// Version is the migration that was interrupted, zero in LoadSchema.
+	Version int
+	// File is the schema file that was interrupted, empty in Migrate.
+	File string
+	Err  error
+}
+
+func (e *InterruptedError) Error() string {
+	if len(e.File) > 0 {
+		return fmt.Sprintf("loading %q was interrupted and its statement killed, the schema may be partially loaded: %s", e.File, e.Err)
+	}
+	return fmt.Sprintf("migration %d was interrupted and its statement killed, the schema may be partially modified: %s", e.Version, e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// killableConn is a pool of a single connection, so that the statements
+// executed through it can be killed by its connection ID.
+type killableConn struct {
+	db *sql.DB
+	id int64
+}
+
+// openKillable connects to the database on a single connection, which
+// migrations and schema files are executed on, with WithSessionVariables set.
+func (m *Migrator) openKillable(ctx context.Context) (*killableConn, error) {
+	db, err := m.openDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	var id int64
+	if err := db.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed reading connection id")
+	}
+	if err := m.setSessionVariables(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &killableConn{db: db, id: id}, nil
+}
+
+func (s *killableConn) Close() error {
+	return s.db.Close()
+}
+
+// killOnDone issues KILL QUERY for s, through conn, as soon as ctx is done.
+// The returned func stops it, waiting for a KILL that's under way, and
+// reports whether one was issued.
+func (m *Migrator) killOnDone(ctx context.Context, conn *sql.DB, s *killableConn) func() bool {
+	killed := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(killed)
+
+		killCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), killTimeout)
+		defer cancel()
+
+		if _, err := conn.ExecContext(killCtx, fmt.Sprintf("KILL QUERY %d", s.id)); err != nil {
+			m.log(ctx, slog.LevelWarn,
+				fmt.Sprintf("failed killing query on connection %d: %s", s.id, err),
+				slog.String("db", m.src.DBName),
+				slog.Any("error", err),
+			)
+		}
+	})
+
+	return func() bool {
+		if stop() {
+			return false
+		}
+		<-killed
+		return true
+	}
+}
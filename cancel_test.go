@@ -0,0 +1,75 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancellingMigrateKillsStatement(t *testing.T) {
+	dbname := "canceltest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `SELECT SLEEP(30) AS canceltest_migrate`,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err := migration.Migrate(ctx, fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	var interrupted *migration.InterruptedError
+	require.True(t, errors.As(err, &interrupted), "got %v", err)
+	require.Equal(t, 1, interrupted.Version)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+
+	require.Equal(t, 0, runningQueries("canceltest_migrate"))
+}
+
+func TestCancellingLoadSchemaKillsStatement(t *testing.T) {
+	dbname := "canceltest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/canceltest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+	must(ioutil.WriteFile(dir+"/blarg.sql", []byte("SELECT SLEEP(30) AS canceltest_load"), 0644))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err := migration.LoadSchema(ctx, fullDSN(dbname), dir, migration.WithSchemaOnly(), migration.WithLogger(migration.NopLogger{}))
+	var interrupted *migration.InterruptedError
+	require.True(t, errors.As(err, &interrupted), "got %v", err)
+	require.Equal(t, "blarg.sql", interrupted.File)
+
+	require.Equal(t, 0, runningQueries("canceltest_load"))
+}
+
+func runningQueries(marker string) int {
+	conn, err := sql.Open("mysql", partialDSN())
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var running int
+	must(conn.QueryRow(
+		"SELECT COUNT(*) FROM information_schema.PROCESSLIST WHERE INFO LIKE ? AND ID != CONNECTION_ID()",
+		"%"+marker+"%",
+	).Scan(&running))
+	return running
+}
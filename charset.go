@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCharset   = "utf8mb4"
+	defaultCollation = "utf8mb4_unicode_520_ci"
+)
+
+// WithDatabaseCharset creates the database, and the tracking table, with
+// charset and collation rather than utf8mb4 and utf8mb4_unicode_520_ci, e.g.
+// utf8mb4_0900_ai_ci on MySQL 8. The server is checked to support the
+// collation, for charset, before the database is created.
+func WithDatabaseCharset(charset, collation string) Option {
+	return func(m *Migrator) {
+		m.dbCharset = charset
+		m.dbCollation = collation
+	}
+}
+
+// checkDatabaseCharset fails when the server doesn't support the collation
+// the database is to be created with, or it isn't one of the charset's.
+func (m *Migrator) checkDatabaseCharset(ctx context.Context, conn *sql.DB) error {
+	var charset string
+	err := conn.QueryRowContext(ctx,
+		"SELECT CHARACTER_SET_NAME FROM information_schema.COLLATIONS WHERE COLLATION_NAME = ?",
+		m.dbCollation,
+	).Scan(&charset)
+	if err == sql.ErrNoRows {
+		return errors.Errorf("the server doesn't support collation %q", m.dbCollation)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed looking up collation %q", m.dbCollation)
+	}
+	if charset != m.dbCharset {
+		return errors.Errorf("collation %q is for character set %q, not %q", m.dbCollation, charset, m.dbCharset)
+	}
+	return nil
+}
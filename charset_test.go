@@ -0,0 +1,92 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func databaseCollation(dbname string) (string, string) {
+	conn, err := sql.Open("mysql", partialDSN())
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var charset, collation string
+	must(conn.QueryRow(
+		"SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?",
+		"migration_test_"+dbname,
+	).Scan(&charset, &collation))
+	return charset, collation
+}
+
+func TestDefaultDatabaseCharset(t *testing.T) {
+	dbname := "charsettest"
+	dropDB(dbname)
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), nil, migration.WithLogger(migration.NopLogger{})))
+	charset, collation := databaseCollation(dbname)
+	require.Equal(t, "utf8mb4", charset)
+	require.Equal(t, "utf8mb4_unicode_520_ci", collation)
+}
+
+func TestWithDatabaseCharset(t *testing.T) {
+	dbname := "charsettest"
+	dropDB(dbname)
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), nil,
+		migration.WithDatabaseCharset("latin1", "latin1_swedish_ci"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	charset, collation := databaseCollation(dbname)
+	require.Equal(t, "latin1", charset)
+	require.Equal(t, "latin1_swedish_ci", collation)
+	require.Contains(t, showSchema(fullDSN(dbname), "_migrations"), "DEFAULT CHARSET=latin1")
+}
+
+func TestWithDatabaseCharsetValidation(t *testing.T) {
+	dbname := "charsettest"
+
+	tests := []struct {
+		name      string
+		charset   string
+		collation string
+		err       string
+	}{
+		{
+			name:      "unsupported collation",
+			charset:   "utf8mb4",
+			collation: "utf8mb4_klingon_ci",
+			err:       `the server doesn't support collation "utf8mb4_klingon_ci"`,
+		},
+		{
+			name:      "collation of another charset",
+			charset:   "utf8mb4",
+			collation: "latin1_swedish_ci",
+			err:       `collation "latin1_swedish_ci" is for character set "latin1", not "utf8mb4"`,
+		},
+		{
+			name:      "invalid name",
+			charset:   "utf8mb4",
+			collation: "utf8mb4_bin; DROP DATABASE mysql",
+			err:       `invalid character set "utf8mb4" or collation "utf8mb4_bin; DROP DATABASE mysql"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dropDB(dbname)
+			err := migration.Migrate(context.Background(), fullDSN(dbname), nil,
+				migration.WithDatabaseCharset(test.charset, test.collation),
+				migration.WithLogger(migration.NopLogger{}),
+			)
+			require.EqualError(t, err, test.err)
+			require.False(t, dbExists(dbname))
+		})
+	}
+}
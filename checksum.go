@@ -0,0 +1,255 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// Checksummer is implemented by migrations that can fingerprint what they
+// run, so that editing them once they've been applied is caught.
+// Definition implements it.
+type Checksummer interface {
+	Checksum() string
+}
+
+// Checksum is the hex encoded SHA-256 of Up, or of UpStatements or UpFile's
+// contents when set. It's empty when UpFile can't be read, which Migrate
+// fails on.
+func (s *Definition) Checksum() string {
+	if len(s.UpFile) > 0 {
+		checksum, _ := fileChecksum(s.UpFile)
+		return checksum
+	}
+
+	sum := sha256.Sum256([]byte(s.Up))
+	if len(s.UpStatements) > 0 {
+		sum = sha256.Sum256([]byte(strings.Join(s.UpStatements, "\x00")))
+	}
+	return hex.EncodeToString(sum[:])
+}
+
+// ChecksumMismatchError is returned by Migrate when applied migrations no
+// longer match the checksum recorded when they were applied.
+type ChecksumMismatchError struct {
+	Versions []int
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("migrations %v have changed since they were applied, revert them or call AcceptChecksums", e.Versions)
+}
+
+func migrationChecksum(migration Migration) (sql.NullString, error) {
+	if definition, ok := migration.(*Definition); ok && len(definition.UpFile) > 0 {
+		checksum, err := fileChecksum(definition.UpFile)
+		if err != nil {
+			return sql.NullString{}, errors.Wrapf(err, "failed checksumming migration %d", migration.Version())
+		}
+		return sql.NullString{String: checksum, Valid: true}, nil
+	}
+	if checksummer, ok := migration.(Checksummer); ok {
+		return sql.NullString{String: checksummer.Checksum(), Valid: true}, nil
+	}
+	return sql.NullString{}, nil
+}
+
+// verifyChecksums fails when an applied migration's checksum doesn't match
+// the recorded one. Rows recorded before checksums were, or by a migration
+// that had none at the time, have theirs filled in instead.
+func (m *Migrator) verifyChecksums(ctx context.Context, conn *sql.DB, migrations []Migration, recorded map[int]sql.NullString) error {
+	if err := checkChecksums(migrations, recorded); err != nil {
+		return err
+	}
+
+	backfill := map[int]sql.NullString{}
+	for _, migration := range migrations {
+		checksum, applied := recorded[migration.Version()]
+		if !applied || checksum.Valid {
+			continue
+		}
+		current, err := migrationChecksum(migration)
+		if err != nil {
+			return err
+		}
+		if current.Valid {
+			backfill[migration.Version()] = current
+		}
+	}
+
+	for _, migration := range migrations {
+		current, ok := backfill[migration.Version()]
+		if !ok {
+			continue
+		}
+		_, err := conn.ExecContext(ctx,
+			fmt.Sprintf("UPDATE %s SET checksum = ? WHERE id = ? AND checksum IS NULL", dialect.QuoteIdentifier(m.tableName)),
+			current, migration.Version(),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed recording checksum of migration %d", migration.Version())
+		}
+	}
+	if len(backfill) > 0 {
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("recorded checksums of %d migrations applied without one", len(backfill)),
+			slog.String("db", m.src.DBName),
+			slog.Int("migrations", len(backfill)),
+		)
+	}
+
+	return nil
+}
+
+// checkChecksums fails with a ChecksumMismatchError when an applied
+// migration's checksum doesn't match the recorded one.
+func checkChecksums(migrations []Migration, recorded map[int]sql.NullString) error {
+	mismatched := []int{}
+	for _, migration := range migrations {
+		checksum, applied := recorded[migration.Version()]
+		if !applied || !checksum.Valid {
+			continue
+		}
+		current, err := migrationChecksum(migration)
+		if err != nil {
+			return err
+		}
+		if current.Valid && checksum.String != current.String {
+			mismatched = append(mismatched, migration.Version())
+		}
+	}
+
+	if len(mismatched) > 0 {
+		sort.Ints(mismatched)
+		return &ChecksumMismatchError{Versions: mismatched}
+	}
+	return nil
+}
+
+// readChecksums reads the checksum recorded for each applied version, without
+// upgrading the tracking table, so versions recorded before checksums were
+// have none.
+func (m *Migrator) readChecksums(ctx context.Context, conn *sql.DB) (map[int]sql.NullString, error) {
+	columns, err := m.migrationsTableColumns(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	checksumColumn := "NULL"
+	if columns["checksum"] {
+		checksumColumn = "checksum"
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT id, %s FROM %s", checksumColumn, dialect.QuoteIdentifier(m.tableName)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading checksums from %q", m.tableName)
+	}
+	defer rows.Close()
+
+	recorded := map[int]sql.NullString{}
+	for rows.Next() {
+		var version int
+		var checksum sql.NullString
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, errors.Wrapf(err, "failed reading checksums from %q", m.tableName)
+		}
+		recorded[version] = checksum
+	}
+	return recorded, rows.Err()
+}
+
+func AcceptChecksums(ctx context.Context, dsn string, migrations []Migration, opts ...Option) ([]int, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.AcceptChecksums(ctx, migrations)
+}
+
+func AcceptChecksumsConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) ([]int, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.AcceptChecksums(ctx, migrations)
+}
+
+func AcceptChecksumsSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) ([]int, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.AcceptChecksums(ctx, migrations)
+}
+
+// AcceptChecksums records the current checksum of every applied migration
+// whose checksum has changed, for edits that were deliberate such as
+// reformatting, and returns their versions.
+func (m *Migrator) AcceptChecksums(ctx context.Context, migrations []Migration) ([]int, error) {
+	if err := validateMigrations(migrations); err != nil {
+		return nil, err
+	}
+
+	conn, err := m.openDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	if err := m.createMigrationsTableIfNotExists(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	recorded, err := m.readApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := []int{}
+	for _, migration := range migrations {
+		checksum, applied := recorded[migration.Version()]
+		if !applied || !checksum.Valid {
+			continue
+		}
+		current, err := migrationChecksum(migration)
+		if err != nil {
+			return accepted, err
+		}
+		if !current.Valid || checksum.String == current.String {
+			continue
+		}
+
+		_, err = conn.ExecContext(ctx,
+			fmt.Sprintf("UPDATE %s SET checksum = ? WHERE id = ?", dialect.QuoteIdentifier(m.tableName)),
+			current, migration.Version(),
+		)
+		if err != nil {
+			return accepted, errors.Wrapf(err, "failed recording checksum of migration %d", migration.Version())
+		}
+		accepted = append(accepted, migration.Version())
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("accepted changed checksum of migration %d", migration.Version()),
+			slog.Int("version", migration.Version()),
+			slog.String("db", m.src.DBName),
+		)
+	}
+
+	sort.Ints(accepted)
+	return accepted, nil
+}
@@ -0,0 +1,86 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectsEditedMigrations(t *testing.T) {
+	dbname := "checksumtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Equal(t, migrations[1].(*migration.Definition).Checksum(), queryChecksum(fullDSN(dbname), 2).String)
+
+	edited := []migration.Migration{
+		migrations[0],
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di BIGINT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 3,
+			Up: `CREATE TABLE bralg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), edited, migration.WithLogger(migration.NopLogger{}))
+	var mismatch *migration.ChecksumMismatchError
+	require.True(t, errors.As(err, &mismatch), "got %v", err)
+	require.Equal(t, []int{2}, mismatch.Versions)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+
+	accepted, err := migration.AcceptChecksums(context.Background(), fullDSN(dbname), edited, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, accepted)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), edited, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 3)
+}
+
+func TestBackfillsMissingChecksums(t *testing.T) {
+	dbname := "checksumtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	execSQL(fullDSN(dbname), "UPDATE _migrations SET checksum = NULL")
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, migrations[0].(*migration.Definition).Checksum(), queryChecksum(fullDSN(dbname), 1).String)
+}
+
+func queryChecksum(dsn string, id int) sql.NullString {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var checksum sql.NullString
+	must(conn.QueryRow("SELECT checksum FROM _migrations WHERE id = ?", id).Scan(&checksum))
+	return checksum
+}
@@ -0,0 +1,142 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ChunkedMigration executes an UPDATE or DELETE in batches, see Chunked.
+type ChunkedMigration struct {
+	version   int
+	query     string
+	batchSize int
+	chunking
+}
+
+// chunking is configured by the ChunkOptions of Chunked and Resumable.
+type chunking struct {
+	name  string
+	pause time.Duration
+}
+
+type ChunkOption func(*chunking)
+
+// WithChunkName names the migration in the logs and its history row.
+func WithChunkName(name string) ChunkOption {
+	return func(c *chunking) {
+		c.name = name
+	}
+}
+
+// WithChunkPause sleeps for pause between batches, to give replicas a chance
+// to keep up.
+func WithChunkPause(pause time.Duration) ChunkOption {
+	return func(c *chunking) {
+		c.pause = pause
+	}
+}
+
+// Chunked returns a migration executing query, a single table UPDATE or
+// DELETE, with LIMIT batchSize appended until it affects no rows, so large
+// data migrations don't hold locks on the whole table at once. query has to
+// stop matching rows once they're migrated, e.g.
+//
+//	UPDATE orders SET status = 'closed' WHERE status = 'expired'
+//
+// Its Migrate method can also be called from a Go migration. The rows
+// affected are logged and reported in AppliedMigration.RowsAffected.
+func Chunked(version int, query string, batchSize int, opts ...ChunkOption) *ChunkedMigration {
+	c := &ChunkedMigration{version: version, query: query, batchSize: batchSize}
+	for _, opt := range opts {
+		opt(&c.chunking)
+	}
+	return c
+}
+
+func (c *ChunkedMigration) Version() int {
+	return c.version
+}
+
+func (c *ChunkedMigration) Name() string {
+	return c.name
+}
+
+// Idempotent is true, as the batches that already ran no longer match.
+func (c *ChunkedMigration) Idempotent() bool {
+	return true
+}
+
+func (c *ChunkedMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	if c.batchSize <= 0 {
+		return errors.Errorf("batch size of chunked migration %d is %d, it has to be above 0", c.version, c.batchSize)
+	}
+
+	e := executionFrom(ctx)
+	statement := fmt.Sprintf("%s LIMIT %d", c.query, c.batchSize)
+	start := time.Now()
+
+	var total int64
+	for batches := 1; ; batches++ {
+		result, err := conn.ExecContext(ctx, statement)
+		if err != nil {
+			return errors.Wrapf(err, "batch %d failed after %d rows", batches, total)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return errors.Wrapf(err, "failed reading rows affected by batch %d", batches)
+		}
+		total += affected
+		if e != nil {
+			e.rowsAffected += affected
+		}
+
+		if affected == 0 {
+			logChunk(ctx, e, c.version, slog.LevelInfo,
+				fmt.Sprintf("chunked migration %d affected %d rows in %d batches", c.version, total, batches),
+				total, batches, start)
+			return nil
+		}
+		logChunk(ctx, e, c.version, slog.LevelDebug,
+			fmt.Sprintf("chunked migration %d batch %d affected %d rows, %d so far", c.version, batches, affected, total),
+			total, batches, start)
+
+		if err := sleep(ctx, c.pause); err != nil {
+			return errors.Wrapf(err, "stopped after %d rows", total)
+		}
+	}
+}
+
+// logChunk logs the progress of a batched migration, when it's executed by a
+// Migrator.
+func logChunk(ctx context.Context, e *execution, version int, level slog.Level, msg string, total int64, batches int, start time.Time) {
+	if e == nil || e.m == nil {
+		return
+	}
+	e.m.log(ctx, level, msg,
+		slog.Int("version", version),
+		slog.String("db", e.m.src.DBName),
+		slog.Int64("rows", total),
+		slog.Int("batches", batches),
+		slog.Duration("elapsed", time.Now().Sub(start)),
+	)
+}
+
+// sleep waits for d, returning early with ctx's error if it's done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
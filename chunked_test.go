@@ -0,0 +1,95 @@
+package migration_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunked(t *testing.T) {
+	dbname := "chunkedtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			UpStatements: []string{
+				`CREATE TABLE orders ( id INT NOT NULL, status VARCHAR(10) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+				`INSERT INTO orders VALUES (1, 'expired'), (2, 'expired'), (3, 'open'), (4, 'expired'), (5, 'expired'), (6, 'expired')`,
+			},
+		},
+		migration.Chunked(2, `UPDATE orders SET status = 'closed' WHERE status = 'expired'`, 2,
+			migration.WithChunkName("close_expired_orders"),
+			migration.WithChunkPause(time.Millisecond),
+		),
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations, migration.WithSlog(logger))
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 2)
+	require.Equal(t, int64(0), report.Applied[0].RowsAffected)
+	require.Equal(t, int64(5), report.Applied[1].RowsAffected)
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.False(t, oneExists(conn, "SELECT 1 FROM orders WHERE status = 'expired'"))
+	require.True(t, oneExists(conn, "SELECT 1 FROM orders WHERE status = 'open'"))
+
+	batch := findRecord(t, &buf, "chunked migration 2 batch 1 affected 2 rows")
+	require.Equal(t, "DEBUG", batch["level"])
+	done := findRecord(t, &buf, "chunked migration 2 affected 5 rows in 4 batches")
+	require.Equal(t, "INFO", done["level"])
+	require.Equal(t, float64(5), done["rows"])
+	require.Equal(t, float64(4), done["batches"])
+}
+
+func TestChunkedRespectsContext(t *testing.T) {
+	dbname := "chunkedtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			UpStatements: []string{
+				`CREATE TABLE orders ( id INT NOT NULL, status VARCHAR(10) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+				`INSERT INTO orders VALUES (1, 'expired'), (2, 'expired'), (3, 'expired')`,
+			},
+		},
+		migration.Chunked(2, `UPDATE orders SET status = 'closed' WHERE status = 'expired'`, 1,
+			migration.WithChunkPause(time.Hour),
+		),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := migration.Migrate(ctx, fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "stopped after 1 rows")
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.True(t, oneExists(conn, "SELECT 1 FROM orders WHERE id = 2 AND status = 'expired'"))
+}
+
+func TestChunkedNeedsBatchSize(t *testing.T) {
+	dbname := "chunkedtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		migration.Chunked(1, `DELETE FROM orders`, 0),
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "failed executing migration 1: batch size of chunked migration 1 is 0, it has to be above 0")
+}
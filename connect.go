@@ -0,0 +1,121 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultConnectBackoff is how long to wait before retrying to connect
+	// when only WithConnectTimeout is given.
+	defaultConnectBackoff = 500 * time.Millisecond
+
+	// maxConnectBackoff caps the doubling wait between attempts, so a long
+	// WithConnectTimeout still notices the server coming up promptly.
+	maxConnectBackoff = 10 * time.Second
+)
+
+// WithConnectRetries tries connecting to the server up to attempts times in
+// all before giving up, waiting backoff before the first retry and twice as
+// long before each one after it, for when the database may still be starting
+// up alongside the application.
+func WithConnectRetries(attempts int, backoff time.Duration) Option {
+	return func(m *Migrator) {
+		m.connectAttempts = attempts
+		m.connectBackoff = backoff
+	}
+}
+
+// WithConnectTimeout keeps retrying to connect to the server until timeout
+// has passed. Given along with WithConnectRetries, whichever runs out first
+// stops the retries.
+func WithConnectTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.connectTimeout = timeout
+	}
+}
+
+// waitForServer pings the server until it accepts connections, retrying
+// WithConnectRetries and WithConnectTimeout. Without either it tries once, so
+// an unreachable server fails up front rather than on the first query.
+func (m *Migrator) waitForServer(ctx context.Context) error {
+	conn, err := m.src.Server(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	backoff := m.connectBackoff
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := m.ping(ctx, conn.PingContext, start)
+		if err == nil {
+			return nil
+		}
+		elapsed := time.Now().Sub(start)
+		if ctx.Err() != nil || !m.retryConnect(attempt, elapsed) {
+			return errors.Wrapf(err, "unable to connect to %s after %d attempts", m.serverLabel(), attempt)
+		}
+
+		wait := backoff
+		if m.connectTimeout > 0 && m.connectTimeout-elapsed < wait {
+			wait = m.connectTimeout - elapsed
+		}
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("failed connecting to %s on attempt %d, retrying in %s: %s", m.serverLabel(), attempt, wait, err),
+			slog.String("server", m.serverLabel()),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", wait),
+			slog.Any("error", err),
+		)
+		if err := sleep(ctx, wait); err != nil {
+			return errors.Wrapf(err, "gave up connecting to %s after %d attempts", m.serverLabel(), attempt)
+		}
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+// ping runs a single ping, cut short when WithConnectTimeout runs out so a
+// server that never answers doesn't hold things up past it.
+func (m *Migrator) ping(ctx context.Context, ping func(context.Context) error, start time.Time) error {
+	if m.connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(m.connectTimeout))
+		defer cancel()
+	}
+	return ping(ctx)
+}
+
+// retryConnect reports whether to try connecting again after attempt failed.
+func (m *Migrator) retryConnect(attempt int, elapsed time.Duration) bool {
+	if m.connectAttempts <= 0 && m.connectTimeout <= 0 {
+		return false
+	}
+	if m.connectAttempts > 0 && attempt >= m.connectAttempts {
+		return false
+	}
+	if m.connectTimeout > 0 && elapsed >= m.connectTimeout {
+		return false
+	}
+	return true
+}
+
+// serverLabel names the server in connection errors, without credentials.
+func (m *Migrator) serverLabel() string {
+	if m.src.cfg != nil && len(m.src.cfg.Addr) > 0 {
+		return m.src.cfg.Addr
+	}
+	return fmt.Sprintf("the server of db %q", m.src.DBName)
+}
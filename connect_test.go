@@ -0,0 +1,54 @@
+package migration_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func unreachableConfig() *mysql.Config {
+	cfg := mysql.NewConfig()
+	cfg.User = "root"
+	cfg.Passwd = "secret"
+	cfg.Net = "tcp"
+	cfg.Addr = "127.0.0.1:1"
+	cfg.DBName = "migration_test_connecttest"
+	return cfg
+}
+
+func TestConnectRetries(t *testing.T) {
+	logger := &capturingLogger{}
+	err := migration.MigrateConfig(context.Background(), unreachableConfig(), nil,
+		migration.WithConnectRetries(3, 10*time.Millisecond),
+		migration.WithLogger(logger),
+	)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "unable to connect to 127.0.0.1:1 after 3 attempts: "), err.Error())
+	require.Contains(t, err.Error(), "connection refused")
+	require.NotContains(t, err.Error(), "secret")
+
+	require.Len(t, findLines(logger.lines, "failed connecting to 127.0.0.1:1 on attempt"), 2)
+	require.NotEmpty(t, findLines(logger.lines, "failed connecting to 127.0.0.1:1 on attempt 2, retrying in 20ms"))
+}
+
+func TestConnectTimeout(t *testing.T) {
+	start := time.Now()
+	err := migration.DumpSchemaConfig(context.Background(), unreachableConfig(), t.TempDir(),
+		migration.WithConnectTimeout(300*time.Millisecond),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to connect to 127.0.0.1:1 after")
+	require.WithinDuration(t, start.Add(300*time.Millisecond), time.Now(), 200*time.Millisecond)
+}
+
+func TestConnectWithoutRetries(t *testing.T) {
+	err := migration.LoadSchemaConfig(context.Background(), unreachableConfig(), t.TempDir(), migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "unable to connect to 127.0.0.1:1 after 1 attempts: "), err.Error())
+}
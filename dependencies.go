@@ -0,0 +1,140 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Dependent is implemented by migrations that require other migrations to
+// have been applied first, for those other than Definition which has a
+// DependsOn field.
+type Dependent interface {
+	DependsOn() []int
+}
+
+func migrationDependencies(migration Migration) []int {
+	switch dependent := migration.(type) {
+	case *Definition:
+		return dependent.DependsOn
+	case Dependent:
+		return dependent.DependsOn()
+	}
+	return nil
+}
+
+// validateDependencies fails when a migration depends on a version that isn't
+// one of migrations, or when migrations depend on each other in a cycle.
+func validateDependencies(migrations []Migration) error {
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version()] = migration
+	}
+
+	for _, migration := range migrations {
+		for _, dependency := range migrationDependencies(migration) {
+			if _, ok := byVersion[dependency]; !ok {
+				return errors.Errorf("migration %d depends on migration %d, which doesn't exist", migration.Version(), dependency)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[int]int, len(migrations))
+	var path []int
+
+	var visit func(version int) error
+	visit = func(version int) error {
+		switch state[version] {
+		case visited:
+			return nil
+		case visiting:
+			for i, v := range path {
+				if v == version {
+					return errors.Errorf("migrations depend on each other in a cycle: %s", formatChain(append(path[i:], version)))
+				}
+			}
+		}
+
+		state[version] = visiting
+		path = append(path, version)
+		for _, dependency := range migrationDependencies(byVersion[version]) {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[version] = visited
+		return nil
+	}
+
+	for _, migration := range migrations {
+		if err := visit(migration.Version()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDependencies fails when a pending migration would be executed before a
+// migration it depends on, naming the chain of dependencies that wouldn't have
+// been applied yet. migrations must be sorted and have valid dependencies.
+func checkDependencies(migrations []Migration, applied map[int]bool) error {
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version()] = migration
+	}
+
+	done := make(map[int]bool, len(applied)+len(migrations))
+	for version := range applied {
+		done[version] = true
+	}
+
+	for _, migration := range migrations {
+		if done[migration.Version()] {
+			continue
+		}
+
+		chain := []int{migration.Version()}
+		for next := migration; ; {
+			missing, ok := firstMissing(migrationDependencies(next), done)
+			if !ok {
+				break
+			}
+			chain = append(chain, missing)
+			next = byVersion[missing]
+		}
+		if len(chain) > 1 {
+			return errors.Errorf(
+				"migration %d would be executed before migration %d it depends on: %s, give it a version after the migrations it depends on",
+				migration.Version(), chain[1], formatChain(chain),
+			)
+		}
+
+		done[migration.Version()] = true
+	}
+	return nil
+}
+
+func firstMissing(versions []int, done map[int]bool) (int, bool) {
+	for _, version := range versions {
+		if !done[version] {
+			return version, true
+		}
+	}
+	return 0, false
+}
+
+// formatChain formats versions as "1 -> 2 -> 3".
+func formatChain(versions []int) string {
+	formatted := make([]string, len(versions))
+	for i, version := range versions {
+		formatted[i] = fmt.Sprintf("%d", version)
+	}
+	return strings.Join(formatted, " -> ")
+}
@@ -0,0 +1,121 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependsOnValidation(t *testing.T) {
+	dbname := "dependstest"
+
+	tests := []struct {
+		name       string
+		migrations []migration.Migration
+		err        string
+	}{
+		{
+			name: "unknown version",
+			migrations: []migration.Migration{
+				&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, DependsOn: []int{7}},
+			},
+			err: "migration 1 depends on migration 7, which doesn't exist",
+		},
+		{
+			name: "itself",
+			migrations: []migration.Migration{
+				&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, DependsOn: []int{1}},
+			},
+			err: "migrations depend on each other in a cycle: 1 -> 1",
+		},
+		{
+			name: "cycle",
+			migrations: []migration.Migration{
+				&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, DependsOn: []int{3}},
+				&migration.Definition{ID: 2, Up: `CREATE TABLE gralb ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, DependsOn: []int{1}},
+				&migration.Definition{ID: 3, Up: `CREATE TABLE bralg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, DependsOn: []int{2}},
+			},
+			err: "migrations depend on each other in a cycle: 1 -> 3 -> 2 -> 1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dropDB(dbname)
+			err := migration.Migrate(context.Background(), fullDSN(dbname), test.migrations, migration.WithLogger(migration.NopLogger{}))
+			require.EqualError(t, err, test.err)
+			require.Len(t, queryVersions(fullDSN(dbname)), 0)
+		})
+	}
+}
+
+func TestDependsOnRefusesToExecuteBeforeDependencies(t *testing.T) {
+	dbname := "dependstest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 20240101, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 20240105, Up: `ALTER TABLE gralb ADD COLUMN blarg_id INT NULL`, DependsOn: []int{20240101, 20240107}},
+		&migration.Definition{ID: 20240107, Up: `CREATE TABLE gralb ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, DependsOn: []int{20240109}},
+		&migration.Definition{ID: 20240109, Up: `CREATE TABLE bralg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "migration 20240105 would be executed before migration 20240107 it depends on: "+
+		"20240105 -> 20240107 -> 20240109, give it a version after the migrations it depends on")
+	require.Len(t, queryVersions(fullDSN(dbname)), 0)
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+}
+
+func TestDependsOnApplied(t *testing.T) {
+	dbname := "dependstest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE gralb ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+
+	migrations = append(migrations,
+		&migration.Definition{ID: 3, Up: `ALTER TABLE gralb ADD COLUMN blarg_id INT NULL`, DependsOn: []int{2, 1}},
+	)
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, 3, report.Applied[0].Version)
+}
+
+type dependentMigration struct {
+	version   int
+	dependsOn []int
+}
+
+func (d *dependentMigration) Version() int {
+	return d.version
+}
+
+func (d *dependentMigration) DependsOn() []int {
+	return d.dependsOn
+}
+
+func (d *dependentMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE bralg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`)
+	return err
+}
+
+func TestDependentMigration(t *testing.T) {
+	dbname := "dependstest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&dependentMigration{version: 1, dependsOn: []int{2}},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "migration 1 would be executed before migration 2 it depends on: 1 -> 2, give it a version after the migrations it depends on")
+}
@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// WithAllowDestructive lets Migrate execute statements that lose data, such
+// as DROP TABLE, TRUNCATE and DROP COLUMN, which it otherwise refuses to do
+// unless the Definition holding them is marked Destructive.
+func WithAllowDestructive() Option {
+	return func(m *Migrator) {
+		m.allowDestructive = true
+	}
+}
+
+// DestructiveError is returned by Migrate when pending migrations hold
+// statements that lose data, listing every one of them.
+type DestructiveError struct {
+	Statements []DestructiveStatement
+}
+
+type DestructiveStatement struct {
+	Version int
+	// Kind is what makes the statement destructive, e.g. DROP TABLE.
+	Kind string
+	SQL  string
+}
+
+func (e *DestructiveError) Error() string {
+	statements := make([]string, len(e.Statements))
+	for i, statement := range e.Statements {
+		statements[i] = fmt.Sprintf("migration %d %s (%s)", statement.Version, statement.Kind, dialect.Snippet(statement.SQL, 80))
+	}
+	return fmt.Sprintf(
+		"refusing to execute destructive statements, mark their Definitions Destructive or use WithAllowDestructive: %s",
+		strings.Join(statements, ", "),
+	)
+}
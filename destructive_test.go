@@ -0,0 +1,64 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestructiveStatementsRefused(t *testing.T) {
+	dbname := "destructivetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+				CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	migrations = append(migrations,
+		&migration.Definition{
+			ID: 2,
+			Up: `INSERT INTO blarg (id, name) VALUES (1, 'DROP TABLE blarg'); -- DROP TABLE gralb
+				ALTER TABLE blarg DROP COLUMN name`,
+		},
+		&migration.Definition{
+			ID: 3,
+			Up: `TRUNCATE gralb; DROP TABLE gralb`,
+		},
+	)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	var destructive *migration.DestructiveError
+	require.True(t, errors.As(err, &destructive), "got %v", err)
+	require.Equal(t, []migration.DestructiveStatement{
+		{Version: 2, Kind: "DROP COLUMN", SQL: "ALTER TABLE blarg DROP COLUMN name"},
+		{Version: 3, Kind: "TRUNCATE", SQL: "TRUNCATE gralb"},
+		{Version: 3, Kind: "DROP TABLE", SQL: "DROP TABLE gralb"},
+	}, destructive.Statements)
+	require.EqualError(t, err, "refusing to execute destructive statements, mark their Definitions Destructive or use WithAllowDestructive: "+
+		"migration 2 DROP COLUMN (ALTER TABLE blarg DROP COLUMN name), migration 3 TRUNCATE (TRUNCATE gralb), migration 3 DROP TABLE (DROP TABLE gralb)")
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+
+	migrations[1].(*migration.Definition).Destructive = true
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.True(t, errors.As(err, &destructive), "got %v", err)
+	require.Len(t, destructive.Statements, 2)
+	require.Equal(t, 3, destructive.Statements[0].Version)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithAllowDestructive(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 3)
+	require.False(t, tableExists(fullDSN(dbname), "gralb"))
+}
@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// ErrDirty matches a DirtyError with errors.Is.
+var ErrDirty = errors.New("dirty migration")
+
+// DirtyError is returned when a migration was started but never recorded as
+// finished, so the schema may be half changed. It's resolved by undoing what
+// the migration did and calling ResetVersion so it's executed again, or by
+// finishing it by hand and calling SetApplied.
+type DirtyError struct {
+	Version int
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf(
+		"migration %d was started but never finished, it may still be running or have failed part way, resolve it with ResetVersion or SetApplied",
+		e.Version,
+	)
+}
+
+func (e *DirtyError) Is(target error) bool {
+	return target == ErrDirty
+}
+
+// readVersions reads every version in the tracking table, and which of them
+// are dirty in ascending order.
+func (m *Migrator) readVersions(ctx context.Context, conn *sql.DB) (map[int]bool, []int, error) {
+	columns, err := m.migrationsTableColumns(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// tables that haven't been upgraded yet can't have dirty migrations
+	dirtyColumn := "0"
+	if columns["dirty"] {
+		dirtyColumn = "dirty"
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT id, %s FROM %s ORDER BY id", dirtyColumn, dialect.QuoteIdentifier(m.tableName)))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed reading applied migrations from %q", m.tableName)
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	dirty := []int{}
+	for rows.Next() {
+		var version int
+		var isDirty bool
+		if err := rows.Scan(&version, &isDirty); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed reading applied migrations from %q", m.tableName)
+		}
+		applied[version] = true
+		if isDirty {
+			dirty = append(dirty, version)
+		}
+	}
+
+	return applied, dirty, rows.Err()
+}
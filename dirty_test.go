@@ -0,0 +1,89 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailedMigrationLeavesDatabaseDirty(t *testing.T) {
+	dbname := "dirtytest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB; THIS IS NOT SQL`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.True(t, errors.Is(err, migration.ErrDirty), "got %v", err)
+	var dirty *migration.DirtyError
+	require.True(t, errors.As(err, &dirty))
+	require.Equal(t, 2, dirty.Version)
+
+	_, err = migration.Plan(context.Background(), fullDSN(dbname), migrations)
+	require.True(t, errors.Is(err, migration.ErrDirty), "got %v", err)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.False(t, applied[0].Dirty)
+	require.True(t, applied[1].Dirty)
+
+	upToDate, err := migration.IsUpToDate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.False(t, upToDate)
+
+	current, err := migration.CurrentVersion(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Equal(t, 1, current)
+
+	// undo what it managed to do, fix it and reset it so it runs again
+	execSQL(fullDSN(dbname), "DROP TABLE IF EXISTS gralb")
+	migrations[1].(*migration.Definition).Up = `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`
+	err = migration.ResetVersion(context.Background(), fullDSN(dbname), 2, migration.WithConfirmReset(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	upToDate, err = migration.IsUpToDate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.True(t, upToDate)
+}
+
+func TestSetAppliedResolvesDirtyMigration(t *testing.T) {
+	dbname := "dirtytest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB; THIS IS NOT SQL`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+
+	// finished by hand
+	err = migration.SetApplied(context.Background(), fullDSN(dbname), migrations, 1, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+}
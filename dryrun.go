@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// WithDryRun makes Migrate report the migrations it would execute without
+// executing them. The database and tracking table are read but never created,
+// no lock is taken and hooks, events and metrics aren't triggered. The
+// pending migrations are logged and returned in Report.Pending, including
+// those WithTags excludes, which are marked Filtered, along with an estimate
+// of how long they'll take in total.
+func WithDryRun() Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+func (m *Migrator) dryRunMigrations(ctx context.Context, migrations []Migration, report *Report) error {
+	pending, skipped, err := m.plan(ctx, migrations)
+	if err != nil {
+		return err
+	}
+
+	report.DryRun = true
+	report.Pending = pending
+	report.Skipped = append(report.Skipped, skipped...)
+
+	for _, planned := range pending {
+		if planned.Filtered {
+			report.Filtered = append(report.Filtered, planned.Version)
+			m.log(ctx, slog.LevelInfo,
+				fmt.Sprintf("dry run: would skip migration %d as none of its tags are enabled", planned.Version),
+				slog.Int("version", planned.Version),
+				slog.String("db", m.src.DBName),
+				slog.String("status", "filtered"),
+			)
+			continue
+		}
+
+		msg := fmt.Sprintf("dry run: would execute migration %d", planned.Version)
+		if planned.SQL != "" {
+			msg = fmt.Sprintf("%s: %s", msg, planned.SQL)
+		}
+		m.log(ctx, slog.LevelInfo, msg,
+			slog.Int("version", planned.Version),
+			slog.String("db", m.src.DBName),
+			slog.String("status", "pending"),
+			slog.String("sql", planned.SQL),
+			slog.String("estimate", planned.EstimatedDuration.String()),
+		)
+	}
+
+	total, unknown := EstimateTotal(pending)
+	msg := fmt.Sprintf("dry run: estimated to take %s", total)
+	if len(unknown) > 0 {
+		msg = fmt.Sprintf("%s, plus migrations %v whose duration is unknown", msg, unknown)
+	}
+	m.log(ctx, slog.LevelInfo, msg,
+		slog.String("db", m.src.DBName),
+		slog.Duration("estimate", total),
+		slog.Any("unknown", unknown),
+	)
+
+	return nil
+}
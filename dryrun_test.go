@@ -0,0 +1,76 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunDoesntCreateDatabase(t *testing.T) {
+	dbname := "dryruntest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	logger := &capturingLogger{}
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithDryRun(),
+		migration.WithLogger(logger),
+	)
+	require.NoError(t, err)
+
+	require.False(t, dbExists(dbname))
+	require.True(t, report.DryRun)
+	require.Empty(t, report.Applied)
+	require.Equal(t, []migration.PlannedMigration{
+		{
+			Version: 1,
+			SQL:     `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Tables:  []migration.PlannedTable{{Database: "migration_test_dryruntest", Table: "blarg", New: true}},
+		},
+	}, report.Pending)
+	require.Contains(t, logger.lines, "dry run: would execute migration 1: CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB")
+	require.Contains(t, logger.lines, "dry run: estimated to take 0s, plus migrations [1] whose duration is unknown")
+}
+
+func TestDryRunReportsPendingMigrations(t *testing.T) {
+	dbname := "dryruntest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 2,
+		Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(255)`,
+	})
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithDryRun(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, []int{1}, report.Skipped)
+	require.Len(t, report.Pending, 1)
+	require.Equal(t, 2, report.Pending[0].Version)
+	require.Equal(t, `ALTER TABLE blarg ADD COLUMN name VARCHAR(255)`, report.Pending[0].SQL)
+	require.Len(t, report.Pending[0].Tables, 1)
+	require.Equal(t, "blarg", report.Pending[0].Tables[0].Table)
+	require.False(t, report.Pending[0].Tables[0].New)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+	require.NotContains(t, showSchema(fullDSN(dbname), "blarg"), "name")
+}
@@ -0,0 +1,90 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// defaultDumpConcurrency is how many tables DumpSchema dumps at once without
+// WithDumpConcurrency.
+const defaultDumpConcurrency = 4
+
+// WithDumpConcurrency limits how many tables DumpSchema dumps at once, each
+// on a connection of its own. The files written are the same whatever it's
+// set to.
+func WithDumpConcurrency(tables int) Option {
+	return func(m *Migrator) {
+		m.dumpConcurrency = tables
+	}
+}
+
+// dumpTables writes the CREATE TABLE statement of each of tables to dir, up
+// to WithDumpConcurrency at a time. The first table that fails stops the
+// others, and its error is returned.
+func (m *Migrator) dumpTables(ctx context.Context, conn *sql.DB, dir SchemaWriter, tables []string) error {
+	concurrency := m.dumpConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var failed sync.Once
+	var firstErr error
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, table := range tables {
+		select {
+		case <-ctx.Done():
+		case slots <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(table string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := m.dumpTable(ctx, conn, dir, table); err != nil {
+				failed.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(table)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// dumpTable writes table's CREATE TABLE statement to dir.
+func (m *Migrator) dumpTable(ctx context.Context, conn *sql.DB, dir SchemaWriter, table string) error {
+	if !validFileName(table) {
+		return errors.Errorf("table %q can't be dumped as its name isn't a valid file name", table)
+	}
+
+	var tableName, createStatement string
+	err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+dialect.QuoteIdentifier(table)).Scan(&tableName, &createStatement)
+	if err != nil {
+		return errors.Wrapf(err, "failed showing create statement for table %q", table)
+	}
+	createStatement = m.normalizeTable(createStatement)
+
+	err = dir.WriteFile(table+".sql", []byte(createStatement))
+	if err != nil {
+		return errors.Wrapf(err, "failed writing out create table statement for table %q", table)
+	}
+	return nil
+}
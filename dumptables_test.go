@@ -0,0 +1,66 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+// manyTables creates count tables of differing shapes in a single migration.
+func manyTables(count int) []migration.Migration {
+	statements := []string{}
+	for i := 0; i < count; i++ {
+		statements = append(statements, fmt.Sprintf(
+			"CREATE TABLE t%03d ( id INT NOT NULL AUTO_INCREMENT, c%d VARCHAR(%d) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB", i, i, i+1,
+		))
+	}
+	return []migration.Migration{
+		&migration.Definition{ID: 1, UpStatements: statements},
+	}
+}
+
+func readDir(t require.TestingT, dir string) map[string]string {
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	contents := map[string]string{}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		require.NoError(t, err)
+		contents[file.Name()] = string(b)
+	}
+	return contents
+}
+
+func TestDumpSchemaConcurrently(t *testing.T) {
+	dbname := "concurrentdumptest"
+	dropDB(dbname)
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), manyTables(300), migration.WithLogger(migration.NopLogger{})))
+
+	sequential := t.TempDir()
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), sequential, migration.WithLogger(migration.NopLogger{}), migration.WithDumpConcurrency(1)))
+	concurrent := t.TempDir()
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), concurrent, migration.WithLogger(migration.NopLogger{}), migration.WithDumpConcurrency(16)))
+
+	files := readDir(t, sequential)
+	require.Len(t, files, 301)
+	require.Equal(t, files, readDir(t, concurrent))
+}
+
+func TestDumpSchemaConcurrentlyReportsFailingTable(t *testing.T) {
+	dbname := "concurrentdumptest_fail"
+	dropDB(dbname)
+
+	migrations := append(manyTables(100),
+		&migration.Definition{ID: 2, Up: "CREATE TABLE `t050/escape` ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB"},
+	)
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+
+	err := migration.DumpSchema(context.Background(), fullDSN(dbname), t.TempDir(), migration.WithLogger(migration.NopLogger{}), migration.WithDumpConcurrency(8))
+	require.EqualError(t, err, `table "t050/escape" can't be dumped as its name isn't a valid file name`)
+}
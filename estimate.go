@@ -0,0 +1,221 @@
+package migration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// EstimateSource says what a DurationEstimate is based on.
+type EstimateSource string
+
+const (
+	// EstimateUnknown means there was nothing to go on, so no estimate is
+	// made rather than guessing.
+	EstimateUnknown EstimateSource = ""
+	// EstimateExpected comes from the Definition's ExpectedDuration.
+	EstimateExpected EstimateSource = "expected"
+	// EstimateRecorded is how long the same version took elsewhere, see
+	// WithRecordedDurations and WithRecordedDurationsFrom.
+	EstimateRecorded EstimateSource = "recorded"
+	// EstimateInstant is for migrations whose statements are all ALTER
+	// TABLEs with ALGORITHM=INSTANT, which don't touch the table's rows.
+	EstimateInstant EstimateSource = "instant"
+)
+
+// DurationEstimate is roughly how long a pending migration will take.
+// Duration is only meaningful when the Source isn't EstimateUnknown.
+type DurationEstimate struct {
+	Duration time.Duration
+	Source   EstimateSource
+}
+
+// Known reports whether an estimate could be made.
+func (e DurationEstimate) Known() bool {
+	return e.Source != EstimateUnknown
+}
+
+func (e DurationEstimate) String() string {
+	if !e.Known() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s (%s)", e.Duration, e.Source)
+}
+
+// WithRecordedDurations gives Plan and WithDryRun how long each version took
+// when it was applied elsewhere, e.g. the Report.Applied durations of a
+// staging run, to estimate how long it'll take here. A Definition's
+// ExpectedDuration takes precedence.
+func WithRecordedDurations(durations map[int]time.Duration) Option {
+	return func(m *Migrator) {
+		m.recordedDurations = durations
+	}
+}
+
+// WithRecordedDurationsFrom reads how long each version took from the
+// history in a schema DumpSchema wrote elsewhere, e.g. staging's dump shipped
+// with the release, for Plan and WithDryRun to estimate how long it'll take
+// here. location is anything LoadSchema accepts. Durations given
+// WithRecordedDurations take precedence.
+func WithRecordedDurationsFrom(location string) Option {
+	return func(m *Migrator) {
+		m.durationsLocation = location
+	}
+}
+
+// durations combines the durations read WithRecordedDurationsFrom
+// with those given WithRecordedDurations.
+func (m *Migrator) durations() (map[int]time.Duration, error) {
+	if len(m.durationsLocation) == 0 {
+		return m.recordedDurations, nil
+	}
+
+	durations, err := m.readRecordedDurations(m.durationsLocation)
+	if err != nil {
+		return nil, err
+	}
+	for version, duration := range m.recordedDurations {
+		durations[version] = duration
+	}
+	return durations, nil
+}
+
+// readRecordedDurations reads the durations in the history file of the
+// schema dumped to location. A dump without a history file has none.
+func (m *Migrator) readRecordedDurations(location string) (map[int]time.Duration, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read recorded durations from %q", location)
+	}
+	if !info.IsDir() {
+		dir, err := unpackSchemaFile(location)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		location = dir
+	}
+
+	dir, err := decompressSchemaDir(location)
+	if err != nil {
+		return nil, err
+	}
+	if len(dir) > 0 {
+		defer os.RemoveAll(dir)
+		location = dir
+	}
+
+	historyFile := filepath.Join(location, m.tableName+".sql")
+	history, err := ioutil.ReadFile(historyFile)
+	if os.IsNotExist(err) {
+		return map[int]time.Duration{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %q", historyFile)
+	}
+
+	durations, err := parseRecordedDurations(string(history))
+	return durations, errors.Wrapf(err, "unable to read recorded durations from %q", historyFile)
+}
+
+var dumpedColumnsPattern = regexp.MustCompile(`\AINSERT INTO \S+ \(([^)]*)\) VALUES$`)
+
+// parseRecordedDurations reads the duration_ms column of a history file
+// written by dumpHistory, with one row per line. Rows without a duration,
+// and histories dumped before durations were recorded, are left out.
+func parseRecordedDurations(history string) (map[int]time.Duration, error) {
+	lines := strings.Split(strings.TrimSpace(history), "\n")
+	match := dumpedColumnsPattern.FindStringSubmatch(lines[0])
+	if match == nil {
+		return nil, errors.New("not a dumped migration history")
+	}
+
+	id, duration := -1, -1
+	columns := strings.Split(match[1], ", ")
+	for i, column := range columns {
+		switch column {
+		case "id":
+			id = i
+		case "duration_ms":
+			duration = i
+		}
+	}
+
+	durations := map[int]time.Duration{}
+	if id < 0 || duration < 0 {
+		return durations, nil
+	}
+
+	for _, line := range lines[1:] {
+		values := dialect.SplitValues(line)
+		if len(values) != len(columns) {
+			return nil, errors.Errorf("row %q doesn't have %d values", line, len(columns))
+		}
+		if values[duration] == "NULL" {
+			continue
+		}
+		version, err := strconv.Atoi(values[id])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid version in row %q", line)
+		}
+		milliseconds, err := strconv.ParseInt(values[duration], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid duration in row %q", line)
+		}
+		durations[version] = time.Duration(milliseconds) * time.Millisecond
+	}
+	return durations, nil
+}
+
+// EstimateTotal adds up the estimates of the planned migrations Migrate would
+// execute, leaving out those that are Filtered. The versions it couldn't
+// estimate are returned rather than counted as taking no time.
+func EstimateTotal(planned []PlannedMigration) (total time.Duration, unknown []int) {
+	unknown = []int{}
+	for _, migration := range planned {
+		if migration.Filtered {
+			continue
+		}
+		if !migration.EstimatedDuration.Known() {
+			unknown = append(unknown, migration.Version)
+			continue
+		}
+		total += migration.EstimatedDuration.Duration
+	}
+	return total, unknown
+}
+
+// estimateDuration estimates how long migration will take from, in order,
+// its ExpectedDuration, how long it took elsewhere, or its statements when
+// they're all instant. Statements in an UpFile aren't read.
+func estimateDuration(migration Migration, recorded map[int]time.Duration) DurationEstimate {
+	definition, isDefinition := migration.(*Definition)
+	if isDefinition && definition.ExpectedDuration > 0 {
+		return DurationEstimate{Duration: definition.ExpectedDuration, Source: EstimateExpected}
+	}
+	if duration, ok := recorded[migration.Version()]; ok {
+		return DurationEstimate{Duration: duration, Source: EstimateRecorded}
+	}
+	if !isDefinition || len(definition.UpFile) > 0 || definition.Strategy != nil {
+		return DurationEstimate{}
+	}
+
+	statements := definition.statements()
+	if len(statements) == 0 {
+		return DurationEstimate{}
+	}
+	for _, statement := range statements {
+		if !dialect.InstantDDL(statement) {
+			return DurationEstimate{}
+		}
+	}
+	return DurationEstimate{Source: EstimateInstant}
+}
@@ -0,0 +1,191 @@
+package migration_test
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateDuration(t *testing.T) {
+	recorded := map[int]time.Duration{1: time.Minute, 2: time.Minute, 5: 10 * time.Second}
+
+	cases := []struct {
+		name      string
+		migration migration.Migration
+		expected  migration.DurationEstimate
+	}{
+		{
+			name:      "expected takes precedence",
+			migration: &migration.Definition{ID: 1, Up: `SELECT 1`, ExpectedDuration: time.Hour},
+			expected:  migration.DurationEstimate{Duration: time.Hour, Source: migration.EstimateExpected},
+		},
+		{
+			name:      "recorded elsewhere",
+			migration: &migration.Definition{ID: 2, Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ALGORITHM=INSTANT`},
+			expected:  migration.DurationEstimate{Duration: time.Minute, Source: migration.EstimateRecorded},
+		},
+		{
+			name: "instant DDL",
+			migration: &migration.Definition{ID: 3, UpStatements: []string{
+				`ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ALGORITHM=INSTANT`,
+				`ALTER TABLE gralb RENAME COLUMN a TO b, ALGORITHM=INSTANT`,
+			}},
+			expected: migration.DurationEstimate{Source: migration.EstimateInstant},
+		},
+		{
+			name: "not every statement is instant",
+			migration: &migration.Definition{ID: 4, UpStatements: []string{
+				`ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ALGORITHM=INSTANT`,
+				`UPDATE blarg SET name = 'blarg'`,
+			}},
+			expected: migration.DurationEstimate{},
+		},
+		{
+			name:      "statements in a file aren't read",
+			migration: &migration.Definition{ID: 6, UpFile: "testdata/instant.sql"},
+			expected:  migration.DurationEstimate{},
+		},
+		{
+			name:      "other migrations only go by what was recorded",
+			migration: migration.Chunked(5, `DELETE FROM blarg WHERE id < 0`, 100),
+			expected:  migration.DurationEstimate{Duration: 10 * time.Second, Source: migration.EstimateRecorded},
+		},
+		{
+			name:      "nothing to go on",
+			migration: migration.Chunked(7, `DELETE FROM blarg WHERE id < 0`, 100),
+			expected:  migration.DurationEstimate{},
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expected, migration.EstimateDuration(c.migration, recorded), c.name)
+	}
+}
+
+func TestDurationEstimateString(t *testing.T) {
+	require.Equal(t, "unknown", migration.DurationEstimate{}.String())
+	require.Equal(t, "0s (instant)", migration.DurationEstimate{Source: migration.EstimateInstant}.String())
+	require.Equal(t, "1m30s (recorded)", migration.DurationEstimate{Duration: 90 * time.Second, Source: migration.EstimateRecorded}.String())
+}
+
+func TestParseRecordedDurations(t *testing.T) {
+	history, err := ioutil.ReadFile("testdata/durations/_migrations.sql")
+	require.NoError(t, err)
+
+	durations, err := migration.ParseRecordedDurations(string(history))
+	require.NoError(t, err)
+	require.Equal(t, map[int]time.Duration{1: 1500 * time.Millisecond, 3: 2 * time.Minute}, durations)
+
+	durations, err = migration.ParseRecordedDurations("INSERT INTO `_migrations` (id, created_at) VALUES\n(1, \"2019-01-01 00:00:00\")")
+	require.NoError(t, err)
+	require.Empty(t, durations)
+
+	_, err = migration.ParseRecordedDurations("CREATE TABLE blarg ( id INT NOT NULL )")
+	require.EqualError(t, err, "not a dumped migration history")
+
+	_, err = migration.ParseRecordedDurations("INSERT INTO `_migrations` (id, created_at, duration_ms) VALUES\n(1, \"2019-01-01 00:00:00\")")
+	require.EqualError(t, err, `row "(1, \"2019-01-01 00:00:00\")" doesn't have 3 values`)
+}
+
+func TestRecordedDurationsFrom(t *testing.T) {
+	m, err := migration.New(fullDSN("durationsfromtest"),
+		migration.WithRecordedDurationsFrom("testdata/durations"),
+		migration.WithRecordedDurations(map[int]time.Duration{3: time.Second}),
+	)
+	require.NoError(t, err)
+	durations, err := m.Durations()
+	require.NoError(t, err)
+	require.Equal(t, map[int]time.Duration{1: 1500 * time.Millisecond, 3: time.Second}, durations)
+
+	// dumps from databases without any migrations have no history file
+	m, err = migration.New(fullDSN("durationsfromtest"), migration.WithRecordedDurationsFrom("testdata/migrations"))
+	require.NoError(t, err)
+	durations, err = m.Durations()
+	require.NoError(t, err)
+	require.Empty(t, durations)
+
+	m, err = migration.New(fullDSN("durationsfromtest"), migration.WithRecordedDurationsFrom("testdata/missing"))
+	require.NoError(t, err)
+	_, err = m.Durations()
+	require.EqualError(t, err, `unable to read recorded durations from "testdata/missing": stat testdata/missing: no such file or directory`)
+}
+
+func TestPlanEstimatesDurationsRecordedInADump(t *testing.T) {
+	dbname := "durationdumptest"
+	dropDB(dbname)
+	dropDB("durationdumptest_copy")
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `DO SLEEP(0.2)`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+
+	planned, err := migration.Plan(context.Background(), fullDSN("durationdumptest_copy"), migrations, migration.WithRecordedDurationsFrom(dir))
+	require.NoError(t, err)
+	require.Len(t, planned, 2)
+	require.Equal(t, migration.EstimateRecorded, planned[1].EstimatedDuration.Source)
+	require.True(t, planned[1].EstimatedDuration.Duration >= 200*time.Millisecond)
+}
+
+func TestEstimateTotal(t *testing.T) {
+	total, unknown := migration.EstimateTotal([]migration.PlannedMigration{
+		{Version: 1, EstimatedDuration: migration.DurationEstimate{Duration: time.Minute, Source: migration.EstimateExpected}},
+		{Version: 2, EstimatedDuration: migration.DurationEstimate{Source: migration.EstimateInstant}},
+		{Version: 3},
+		{Version: 4, EstimatedDuration: migration.DurationEstimate{Duration: 30 * time.Second, Source: migration.EstimateRecorded}},
+		{Version: 5, Filtered: true, EstimatedDuration: migration.DurationEstimate{Duration: time.Hour, Source: migration.EstimateExpected}},
+		{Version: 6, Filtered: true},
+	})
+	require.Equal(t, 90*time.Second, total)
+	require.Equal(t, []int{3}, unknown)
+
+	total, unknown = migration.EstimateTotal(nil)
+	require.Zero(t, total)
+	require.Empty(t, unknown)
+}
+
+func TestPlanEstimatesDurations(t *testing.T) {
+	dbname := "planestimatetest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:               1,
+			Up:               `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			ExpectedDuration: time.Second,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ALGORITHM=INSTANT`,
+		},
+		&migration.Definition{
+			ID: 3,
+			Up: `INSERT INTO blarg (id) VALUES (1)`,
+		},
+		&migration.Definition{
+			ID: 4,
+			Up: `INSERT INTO blarg (id) VALUES (2)`,
+		},
+	}
+
+	planned, err := migration.Plan(context.Background(), fullDSN(dbname), migrations,
+		migration.WithRecordedDurations(map[int]time.Duration{1: time.Hour, 3: 2 * time.Second}),
+	)
+	require.NoError(t, err)
+	require.Len(t, planned, 4)
+	require.Equal(t, migration.DurationEstimate{Duration: time.Second, Source: migration.EstimateExpected}, planned[0].EstimatedDuration)
+	require.Equal(t, migration.DurationEstimate{Source: migration.EstimateInstant}, planned[1].EstimatedDuration)
+	require.Equal(t, migration.DurationEstimate{Duration: 2 * time.Second, Source: migration.EstimateRecorded}, planned[2].EstimatedDuration)
+	require.False(t, planned[3].EstimatedDuration.Known())
+
+	total, unknown := migration.EstimateTotal(planned)
+	require.Equal(t, 3*time.Second, total)
+	require.Equal(t, []int{4}, unknown)
+	require.False(t, dbExists(dbname))
+}
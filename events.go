@@ -0,0 +1,90 @@
+package migration
+
+import (
+	"context"
+	"time"
+)
+
+// Event is sent on the channel given to WithEvents. It is one of RunStarted,
+// MigrationStarted, MigrationFinished, RunFinished, SchemaLoadStarted,
+// SchemaFileStarted, SchemaFileFinished or SchemaLoadFinished.
+type Event interface {
+	event()
+}
+
+// RunStarted is sent once Migrate has worked out how many migrations it's
+// going to execute.
+type RunStarted struct {
+	Pending int
+}
+
+type MigrationStarted struct {
+	Version int
+}
+
+type MigrationFinished struct {
+	Version  int
+	Duration time.Duration
+	Err      error
+}
+
+// RunFinished is sent when Migrate stops executing migrations, with the error
+// that stopped it if any.
+type RunFinished struct {
+	Applied int
+	Err     error
+}
+
+// SchemaLoadStarted is sent once LoadSchema has found the files it's going to
+// load.
+type SchemaLoadStarted struct {
+	Files int
+}
+
+type SchemaFileStarted struct {
+	Name string
+}
+
+type SchemaFileFinished struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+type SchemaLoadFinished struct {
+	Loaded int
+	Err    error
+}
+
+func (RunStarted) event()         {}
+func (MigrationStarted) event()   {}
+func (MigrationFinished) event()  {}
+func (RunFinished) event()        {}
+func (SchemaLoadStarted) event()  {}
+func (SchemaFileStarted) event()  {}
+func (SchemaFileFinished) event() {}
+func (SchemaLoadFinished) event() {}
+
+// WithEvents sends progress events for Migrate and LoadSchema on events, e.g.
+// to drive a progress bar. Sends never block: when events isn't ready to
+// receive the event is dropped, so give it a buffer or a consumer that keeps
+// up. The channel is never closed.
+func WithEvents(events chan<- Event) Option {
+	return func(m *Migrator) {
+		m.events = events
+	}
+}
+
+func (m *Migrator) emit(ctx context.Context, event Event) {
+	if m.events == nil {
+		return
+	}
+
+	// sending on a closed channel panics
+	m.observe(ctx, "events channel", func() {
+		select {
+		case m.events <- event:
+		default:
+		}
+	})
+}
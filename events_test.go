@@ -0,0 +1,95 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEventsReportsMigrationProgress(t *testing.T) {
+	dbname := "eventstest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/eventstest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 2,
+		Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+	})
+
+	events := make(chan migration.Event, 100)
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithEvents(events))
+	require.NoError(t, err)
+
+	received := drainEvents(events)
+	require.Equal(t, 4, len(received))
+	require.Equal(t, migration.RunStarted{Pending: 1}, received[0])
+	require.Equal(t, migration.MigrationStarted{Version: 2}, received[1])
+	finished, ok := received[2].(migration.MigrationFinished)
+	require.True(t, ok)
+	require.Equal(t, 2, finished.Version)
+	require.NoError(t, finished.Err)
+	require.Equal(t, migration.RunFinished{Applied: 1}, received[3])
+
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir)
+	require.NoError(t, err)
+
+	dropDB(dbname)
+
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithEvents(events))
+	require.NoError(t, err)
+
+	received = drainEvents(events)
+	require.Equal(t, 8, len(received))
+	require.Equal(t, migration.SchemaLoadStarted{Files: 3}, received[0])
+	require.Equal(t, migration.SchemaFileStarted{Name: "_migrations.sql"}, received[1])
+	require.Equal(t, migration.SchemaLoadFinished{Loaded: 3}, received[7])
+}
+
+func TestWithEventsNeverBlocks(t *testing.T) {
+	dbname := "eventsblocktest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	events := make(chan migration.Event)
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithEvents(events))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(queryVersions(fullDSN(dbname))))
+}
+
+func drainEvents(events chan migration.Event) []migration.Event {
+	received := []migration.Event{}
+	for {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
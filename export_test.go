@@ -0,0 +1,19 @@
+package migration
+
+import "time"
+
+// Unexported helpers used by the tests in migration_test.
+
+func (m *Migrator) VerificationQuery(query string) string {
+	return m.verificationQuery(query)
+}
+
+var IsVerificationTimeout = isVerificationTimeout
+
+var EstimateDuration = estimateDuration
+
+var ParseRecordedDurations = parseRecordedDurations
+
+func (m *Migrator) Durations() (map[int]time.Duration, error) {
+	return m.durations()
+}
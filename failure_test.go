@@ -0,0 +1,78 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordsWhyMigrationFailed(t *testing.T) {
+	dbname := "failuretest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `THIS IS NOT SQL`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.Nil(t, applied[0].Failure)
+	require.NotNil(t, applied[1].Failure)
+	require.Contains(t, applied[1].Failure.Error, "Error 1064")
+	require.False(t, applied[1].Failure.FailedAt.IsZero())
+
+	migrations[1].(*migration.Definition).Up = `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`
+	err = migration.ResetVersion(context.Background(), fullDSN(dbname), 2, migration.WithConfirmReset(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	applied, err = migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.Nil(t, applied[1].Failure)
+}
+
+func TestTruncatesRecordedFailure(t *testing.T) {
+	dbname := "failuretest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		failingMigration(strings.Repeat("x", 5000)),
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, strings.Repeat("x", 4096), applied[0].Failure.Error)
+}
+
+type failingMigration string
+
+func (f failingMigration) Version() int {
+	return 1
+}
+
+func (f failingMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	return errors.New(string(f))
+}
@@ -0,0 +1,134 @@
+package migration
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const gzipSuffix = ".gz"
+
+// WithGzip makes DumpSchema compress the files it writes with gzip, adding
+// .gz to their names. LoadSchema decompresses any file ending in .gz whether
+// or not it's set.
+func WithGzip() Option {
+	return func(m *Migrator) {
+		m.gzip = true
+	}
+}
+
+// gzipWriter compresses the files it writes with gzip, adding .gz to their
+// names.
+type gzipWriter struct {
+	SchemaWriter
+}
+
+func (w gzipWriter) WriteFile(name string, contents []byte) error {
+	compressed, err := gzipContents(contents)
+	if err != nil {
+		return errors.Wrapf(err, "unable to compress %q", name)
+	}
+	if !strings.HasSuffix(name, gzipSuffix) {
+		name = name + gzipSuffix
+	}
+	return w.SchemaWriter.WriteFile(name, compressed)
+}
+
+// compressing wraps w in a gzipWriter with WithGzip.
+func (m *Migrator) compressing(w SchemaWriter) SchemaWriter {
+	if m.gzip {
+		return gzipWriter{w}
+	}
+	return w
+}
+
+func gzipContents(contents []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(contents); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// readSchemaFile reads the file at path, decompressing it when it ends in
+// .gz.
+func readSchemaFile(path string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil || !strings.HasSuffix(path, gzipSuffix) {
+		return contents, err
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(contents))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decompress")
+	}
+	defer r.Close()
+
+	contents, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decompress")
+	}
+	return contents, nil
+}
+
+// decompressSchemaDir copies the schema files in location to a temporary
+// directory, decompressing those ending in .sql.gz, when there are any, so
+// the rest of LoadSchema only sees .sql files. It returns an empty string
+// when there's nothing to decompress.
+func decompressSchemaDir(location string) (string, error) {
+	files, err := ioutil.ReadDir(location)
+	if err != nil {
+		// left to LoadSchema to report, or not
+		return "", nil
+	}
+
+	names := map[string]bool{}
+	compressed := false
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(name, ".sql"+gzipSuffix) {
+			compressed = true
+			names[name] = true
+		} else if strings.HasSuffix(name, ".sql") {
+			names[name] = true
+		}
+	}
+	if !compressed {
+		return "", nil
+	}
+
+	for name := range names {
+		if strings.HasSuffix(name, gzipSuffix) && names[strings.TrimSuffix(name, gzipSuffix)] {
+			return "", errors.Errorf("schema in %q has both %q and %q, remove one", location, strings.TrimSuffix(name, gzipSuffix), name)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "migration-schema-*")
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create temporary schema dir")
+	}
+	for name := range names {
+		contents, err := readSchemaFile(filepath.Join(location, name))
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", errors.Wrapf(err, "unable to read %q", name)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, strings.TrimSuffix(name, gzipSuffix)), contents, 0644); err != nil {
+			os.RemoveAll(dir)
+			return "", errors.Wrapf(err, "failed decompressing %q", name)
+		}
+	}
+	return dir, nil
+}
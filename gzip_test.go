@@ -0,0 +1,97 @@
+package migration_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func gunzipFile(t *testing.T, path string) string {
+	compressed, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	contents, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return string(contents)
+}
+
+func TestDumpAndLoadGzip(t *testing.T) {
+	dbname := "gziptest"
+	dropDB(dbname)
+	dropDB("gziptest_copy")
+	plain := t.TempDir()
+	compressed := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE parts ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE bins ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 3, Up: `CREATE VIEW part_ids AS SELECT id FROM parts`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), plain, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), compressed, migration.WithLogger(migration.NopLogger{}), migration.WithGzip()))
+
+	files, err := ioutil.ReadDir(compressed)
+	require.NoError(t, err)
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	require.Equal(t, []string{"_migrations.sql.gz", "bins.sql.gz", "part_ids.view.sql.gz", "parts.sql.gz"}, names)
+
+	for _, name := range []string{"_migrations.sql", "bins.sql", "part_ids.view.sql", "parts.sql"} {
+		contents, err := ioutil.ReadFile(filepath.Join(plain, name))
+		require.NoError(t, err)
+		require.Equal(t, string(contents), gunzipFile(t, filepath.Join(compressed, name+".gz")), name)
+	}
+
+	// some files compressed and some not
+	require.NoError(t, os.Remove(filepath.Join(compressed, "bins.sql.gz")))
+	contents, err := ioutil.ReadFile(filepath.Join(plain, "bins.sql"))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(compressed, "bins.sql"), contents, 0644))
+
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("gziptest_copy"), compressed, migration.WithLogger(migration.NopLogger{})))
+	require.Len(t, queryVersions(fullDSN("gziptest_copy")), 3)
+	for _, table := range []string{"parts", "bins", "part_ids"} {
+		require.True(t, tableExists(fullDSN("gziptest_copy"), table), table)
+	}
+}
+
+func TestDumpAndLoadGzipSingleFile(t *testing.T) {
+	dbname := "gzipfiletest"
+	dropDB(dbname)
+	dropDB("gzipfiletest_copy")
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE parts ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), filepath.Join(dir, "schema.sql"), migration.WithLogger(migration.NopLogger{}), migration.WithSingleFile(), migration.WithGzip()))
+
+	_, err := os.Stat(filepath.Join(dir, "schema.sql"))
+	require.True(t, os.IsNotExist(err))
+	require.Contains(t, gunzipFile(t, filepath.Join(dir, "schema.sql.gz")), "-- file: parts.sql\nCREATE TABLE `parts`")
+
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("gzipfiletest_copy"), filepath.Join(dir, "schema.sql.gz"), migration.WithLogger(migration.NopLogger{})))
+	require.True(t, tableExists(fullDSN("gzipfiletest_copy"), "parts"))
+	require.Len(t, queryVersions(fullDSN("gzipfiletest_copy")), 1)
+}
+
+func TestLoadSchemaRefusesCompressedAndPlainCopies(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "parts.sql"), []byte("CREATE TABLE parts (id INT)"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "parts.sql.gz"), []byte{}, 0644))
+
+	err := migration.LoadSchema(context.Background(), fullDSN("gzipbothtest"), dir, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, `schema in "`+dir+`" has both "parts.sql" and "parts.sql.gz", remove one`)
+}
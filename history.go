@@ -0,0 +1,142 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// createdAtFormat parses created_at with or without fractional seconds,
+// which tables created before created_at was DATETIME(6) don't have.
+const createdAtFormat = "2006-01-02 15:04:05"
+
+// createdAtWriteFormat is how created_at is written, always in UTC.
+const createdAtWriteFormat = "2006-01-02 15:04:05.000000"
+
+// placeholderCreatedAt is dumped in place of created_at values that couldn't
+// be read, so that schemas dumped from legacy databases still load.
+const placeholderCreatedAt = "1970-01-01 00:00:00"
+
+type historyRow struct {
+	ID int
+	// Name is empty for migrations recorded before names were.
+	Name string
+	// AppliedBy is empty for migrations recorded before it was.
+	AppliedBy string
+	// CreatedAt is the zero time when the stored value is a zero date or
+	// otherwise not a valid datetime.
+	CreatedAt time.Time
+	// Metadata is the JSON encoded metadata, NULL when none was recorded.
+	Metadata sql.NullString
+	// Binlog is the JSON encoded binlog delta, NULL when none was measured.
+	Binlog sql.NullString
+	// Dirty is set while the migration is executing, and left set if it
+	// failed.
+	Dirty bool
+	// Failure is why the migration failed, NULL unless it's dirty.
+	Failure sql.NullString
+	// FailedAt is when it failed, the zero time unless it has.
+	FailedAt time.Time
+	// Duration is how many milliseconds it took, NULL for migrations that
+	// weren't executed or were recorded before durations were.
+	Duration sql.NullInt64
+}
+
+func (m *Migrator) readHistory(ctx context.Context, conn *sql.DB) ([]historyRow, error) {
+	columns, err := m.migrationsTableColumns(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// tables that haven't been upgraded yet don't have the newer columns
+	metadata := "NULL"
+	if columns["metadata"] {
+		metadata = "metadata"
+	}
+	binlog := "NULL"
+	if columns["binlog_delta"] {
+		binlog = "binlog_delta"
+	}
+	name := "''"
+	if columns["name"] {
+		name = "name"
+	}
+	appliedBy := "''"
+	if columns["applied_by"] {
+		appliedBy = "applied_by"
+	}
+	dirty := "0"
+	if columns["dirty"] {
+		dirty = "dirty"
+	}
+	failure, failedAt := "NULL", "NULL"
+	if columns["error_text"] {
+		failure, failedAt = "error_text", "CAST(failed_at AS CHAR)"
+	}
+	duration := "NULL"
+	if columns["duration_ms"] {
+		duration = "duration_ms"
+	}
+
+	// created_at is read as text so zero dates like 0000-00-00 00:00:00 never
+	// reach the driver's time parsing, regardless of the parseTime setting
+	rows, err := conn.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			"SELECT id, %s, CAST(created_at AS CHAR), %s, %s, %s, %s, %s, %s, %s FROM %s ORDER BY id ASC",
+			name, appliedBy, metadata, binlog, dirty, failure, failedAt, duration, dialect.QuoteIdentifier(m.tableName),
+		),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to select from %s table", m.tableName)
+	}
+	defer rows.Close()
+
+	history := []historyRow{}
+	invalid := []int{}
+
+	for rows.Next() {
+		var id int
+		var name, appliedBy string
+		var createdAt, metadata, binlog, failure, failedAt sql.NullString
+		var dirty bool
+		var duration sql.NullInt64
+		if err := rows.Scan(&id, &name, &createdAt, &appliedBy, &metadata, &binlog, &dirty, &failure, &failedAt, &duration); err != nil {
+			return nil, errors.Wrapf(err, "unable to scan %s", m.tableName)
+		}
+
+		parsed, err := time.Parse(createdAtFormat, createdAt.String)
+		if err != nil {
+			if m.strictDates {
+				return nil, errors.Wrapf(err, "migration %d has an invalid created_at %q", id, createdAt.String)
+			}
+			invalid = append(invalid, id)
+			parsed = time.Time{}
+		}
+
+		row := historyRow{ID: id, Name: name, CreatedAt: parsed, AppliedBy: appliedBy, Metadata: metadata, Binlog: binlog, Dirty: dirty, Failure: failure, Duration: duration}
+		if failedAt.Valid {
+			// only ever written by this package, so always valid
+			row.FailedAt, _ = time.Parse(createdAtFormat, failedAt.String)
+		}
+		history = append(history, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", m.tableName)
+	}
+
+	if len(invalid) > 0 {
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("migrations %v have an invalid created_at, treating it as unknown", invalid),
+			slog.Any("versions", invalid),
+		)
+	}
+
+	return history, nil
+}
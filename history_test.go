@@ -0,0 +1,115 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpSchemaToleratesZeroDates(t *testing.T) {
+	dbname := "zerodatetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/zerodatetest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+
+	execSQL(laxDSN(fullDSN(dbname)), `UPDATE _migrations SET created_at = '0000-00-00 00:00:00' WHERE id = 2`)
+
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir)
+	require.NoError(t, err)
+
+	trackedMigrations, err := ioutil.ReadFile(dir + "/_migrations.sql")
+	require.NoError(t, err)
+	require.Regexp(t, `\(2, "1970-01-01 00:00:00", "[^"]+", \d+\)\z`, string(trackedMigrations))
+
+	dropDB(dbname)
+
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir)
+	require.NoError(t, err)
+
+	versions := queryVersions(fullDSN(dbname))
+	require.Equal(t, 2, len(versions))
+	require.Equal(t, 2, versions[1].ID)
+	require.Equal(t, 1970, versions[1].CreatedAt.Year())
+}
+
+// laxDSN disables the strict sql_mode so legacy values like zero dates can be
+// written.
+func laxDSN(dsn string) string {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["sql_mode"] = "''"
+	return cfg.FormatDSN()
+}
+
+func TestCreatedAtIsUTCWithMicroseconds(t *testing.T) {
+	dbname := "createdattest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	// a legacy table, with second precision and a zero date
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_"+dbname)
+	execSQL(fullDSN(dbname), `CREATE TABLE _migrations (
+		id INT NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB`)
+	execSQL(laxDSN(fullDSN(dbname)), `INSERT INTO _migrations (id, created_at) VALUES (1, '0000-00-00 00:00:00')`)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `DO 1`},
+		&migration.Definition{ID: 2, Up: `DO 2`},
+		&migration.Definition{ID: 3, Up: `DO 3`},
+	}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	require.Contains(t, showSchema(fullDSN(dbname), "_migrations"), "`created_at` datetime(6) NOT NULL")
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var second, third string
+	must(conn.QueryRow("SELECT CAST(created_at AS CHAR) FROM _migrations WHERE id = 2").Scan(&second))
+	must(conn.QueryRow("SELECT CAST(created_at AS CHAR) FROM _migrations WHERE id = 3").Scan(&third))
+	require.Regexp(t, `\.\d{6}\z`, second)
+	require.True(t, second < third, "%s should sort before %s", second, third)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.True(t, applied[0].CreatedAt.IsZero())
+	require.Equal(t, time.UTC, applied[1].CreatedAt.Location())
+	require.WithinDuration(t, time.Now(), applied[1].CreatedAt, 5*time.Second)
+}
@@ -0,0 +1,60 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BeforeHook is called before each pending migration executes. Returning an
+// error aborts the run without executing the migration.
+type BeforeHook func(ctx context.Context, migration Migration) error
+
+// AfterHook is called once each pending migration has executed, with the
+// error it failed with if any.
+type AfterHook func(ctx context.Context, migration Migration, duration time.Duration, err error)
+
+// WithBeforeHook adds a hook called before each migration that hasn't been
+// executed yet. Hooks run in the order they were added.
+func WithBeforeHook(hook BeforeHook) Option {
+	return func(m *Migrator) {
+		m.beforeHooks = append(m.beforeHooks, hook)
+	}
+}
+
+// WithAfterHook adds a hook called after each migration that hasn't been
+// executed yet, including when it fails. Hooks run in the order they were
+// added.
+func WithAfterHook(hook AfterHook) Option {
+	return func(m *Migrator) {
+		m.afterHooks = append(m.afterHooks, hook)
+	}
+}
+
+func (m *Migrator) runBeforeHooks(ctx context.Context, migration Migration) error {
+	for _, hook := range m.beforeHooks {
+		if err := hook(ctx, migration); err != nil {
+			return errors.Wrapf(err, "before hook aborted migration %d", migration.Version())
+		}
+	}
+	return nil
+}
+
+// migrationFinished reports a pending migration's outcome once it has been
+// recorded, to the metrics collector, then the after hooks, then the events
+// channel. Each is isolated from the others and the run, see observe.
+func (m *Migrator) migrationFinished(ctx context.Context, migration Migration, duration time.Duration, err error) {
+	if m.metrics != nil {
+		m.observe(ctx, "metrics collector", func() {
+			m.metrics.ObserveMigration(migration.Version(), duration, err)
+		})
+	}
+	for i, hook := range m.afterHooks {
+		m.observe(ctx, fmt.Sprintf("after hook %d", i+1), func() {
+			hook(ctx, migration, duration, err)
+		})
+	}
+	m.emit(ctx, MigrationFinished{Version: migration.Version(), Duration: duration, Err: err})
+}
@@ -0,0 +1,91 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooksRunAroundPendingMigrations(t *testing.T) {
+	dbname := "hookstest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	calls := []string{}
+	hooks := []migration.Option{
+		migration.WithBeforeHook(func(ctx context.Context, m migration.Migration) error {
+			calls = append(calls, fmt.Sprintf("before %d", m.Version()))
+			return nil
+		}),
+		migration.WithAfterHook(func(ctx context.Context, m migration.Migration, duration time.Duration, err error) {
+			calls = append(calls, fmt.Sprintf("after %d: %v", m.Version(), err))
+		}),
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, hooks...)
+	require.NoError(t, err)
+	require.Equal(t, []string{"before 1", "after 1: <nil>"}, calls)
+
+	migrations = append(migrations,
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 3,
+			Up: `THIS IS NOT SQL`,
+		},
+	)
+
+	calls = []string{}
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, hooks...)
+	require.Error(t, err)
+	require.Equal(t, 4, len(calls))
+	require.Equal(t, []string{"before 2", "after 2: <nil>", "before 3"}, calls[:3])
+	require.Contains(t, calls[3], "after 3: failed executing migration 3")
+}
+
+func TestBeforeHookErrorAbortsRun(t *testing.T) {
+	dbname := "beforehookabort"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	afterCalls := 0
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithBeforeHook(func(ctx context.Context, m migration.Migration) error {
+			if m.Version() == 2 {
+				return errors.New("change freeze")
+			}
+			return nil
+		}),
+		migration.WithAfterHook(func(ctx context.Context, m migration.Migration, duration time.Duration, err error) {
+			afterCalls++
+		}),
+	)
+	require.EqualError(t, err, "before hook aborted migration 2: change freeze")
+	require.Equal(t, 1, afterCalls)
+	require.Equal(t, 1, len(queryVersions(fullDSN(dbname))))
+}
@@ -0,0 +1,102 @@
+package dialect
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	dropTablePattern    = regexp.MustCompile(`(?i)^\s*DROP\s+(?:TEMPORARY\s+)?TABLES?\b`)
+	dropDatabasePattern = regexp.MustCompile(`(?i)^\s*DROP\s+(?:DATABASE|SCHEMA)\b`)
+	truncatePattern     = regexp.MustCompile(`(?i)^\s*TRUNCATE\b`)
+	alterTablePattern   = regexp.MustCompile(`(?i)^\s*ALTER\s+(?:ONLINE\s+|IGNORE\s+)*TABLE\b`)
+	alterDropPattern    = regexp.MustCompile(`(?i)\b(DROP|TRUNCATE)\s+(\w+)`)
+)
+
+// alterDropsKept are the words following DROP in an ALTER TABLE that don't
+// lose data.
+var alterDropsKept = map[string]bool{
+	"INDEX":      true,
+	"KEY":        true,
+	"PRIMARY":    true,
+	"FOREIGN":    true,
+	"CONSTRAINT": true,
+	"CHECK":      true,
+	"DEFAULT":    true,
+}
+
+// Destructive returns what makes statement lose data, e.g. DROP TABLE, or
+// empty when nothing does. It errs on the side of reporting a statement, and
+// ignores strings, quoted identifiers and comments that merely mention DROP.
+func Destructive(statement string) string {
+	code := stripLiterals(statement)
+
+	switch {
+	case dropTablePattern.MatchString(code):
+		return "DROP TABLE"
+	case dropDatabasePattern.MatchString(code):
+		return "DROP DATABASE"
+	case truncatePattern.MatchString(code):
+		return "TRUNCATE"
+	case alterTablePattern.MatchString(code):
+		for _, match := range alterDropPattern.FindAllStringSubmatch(code, -1) {
+			command, word := strings.ToUpper(match[1]), strings.ToUpper(match[2])
+			switch {
+			case word == "PARTITION":
+				return command + " PARTITION"
+			case command == "DROP" && !alterDropsKept[word]:
+				return "DROP COLUMN"
+			}
+		}
+	}
+	return ""
+}
+
+// stripLiterals replaces the strings in sql with empty ones, quoted
+// identifiers with a plain one and comments with a space, keeping the
+// contents of /*! */ comments since the server executes them.
+func stripLiterals(sql string) string {
+	var b strings.Builder
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuote(sql, i+1, c)
+			if end < 0 {
+				end = len(sql)
+			}
+			if c == '`' {
+				b.WriteString("identifier")
+			} else {
+				b.WriteString("''")
+			}
+			i = end
+		case lineComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			b.WriteByte(' ')
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*!"):
+			i += 2
+			for i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9' {
+				i++
+			}
+			b.WriteByte(' ')
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i
+			}
+			b.WriteByte(' ')
+			i += end + 3
+		case c == '*' && strings.HasPrefix(sql[i:], "*/"):
+			// the end of a /*! comment
+			b.WriteByte(' ')
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,285 @@
+package dialect
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteString(t *testing.T) {
+	require.Equal(t, `"it's a \"test\"\n\\"`, QuoteString("it's a \"test\"\n\\"))
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	require.Equal(t, "`tenant-west-2`", QuoteIdentifier("tenant-west-2"))
+	require.Equal(t, "`a``; DROP DATABASE b; -- `", QuoteIdentifier("a`; DROP DATABASE b; -- "))
+}
+
+func TestCheckComplete(t *testing.T) {
+	require.NoError(t, CheckComplete("CREATE TABLE `a)` (\n  id INT -- why (\n) COMMENT 'it''s';\n"))
+	require.EqualError(t, CheckComplete("INSERT INTO a VALUES ('abc"), "unterminated ' quoted string")
+	require.EqualError(t, CheckComplete("CREATE TABLE a (id INT /* cut"), "unterminated comment")
+	require.EqualError(t, CheckComplete("CREATE TABLE a (id INT"), "unbalanced parentheses")
+}
+
+func TestCountGTIDs(t *testing.T) {
+	require.Equal(t, int64(0), CountGTIDs(""))
+	require.Equal(t, int64(7), CountGTIDs("3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:11,\n4F11FA47-71CA-11E1-9E33-C80AA9429562:7"))
+}
+
+func TestStripAutoIncrement(t *testing.T) {
+	require.Equal(t,
+		"CREATE TABLE `a` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='AUTO_INCREMENT=5'",
+		StripAutoIncrement("CREATE TABLE `a` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=12345 DEFAULT CHARSET=utf8mb4 COMMENT='AUTO_INCREMENT=5'"),
+	)
+	require.Equal(t,
+		"CREATE TABLE `b` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB\n/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS 2 */",
+		StripAutoIncrement("CREATE TABLE `b` (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=7\n/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS 2 */"),
+	)
+	require.Equal(t, "CREATE TABLE `c` (\n  `id` int NOT NULL\n) ENGINE=InnoDB", StripAutoIncrement("CREATE TABLE `c` (\n  `id` int NOT NULL\n) ENGINE=InnoDB"))
+}
+
+func TestStripDefiner(t *testing.T) {
+	require.Equal(t,
+		"CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `v` AS select 1 AS `1`",
+		StripDefiner("CREATE ALGORITHM=UNDEFINED DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW `v` AS select 1 AS `1`"),
+	)
+	require.Equal(t,
+		"CREATE TRIGGER `t` BEFORE INSERT ON `a` FOR EACH ROW SET NEW.note = 'DEFINER=`x`@`y`'",
+		StripDefiner("CREATE DEFINER=`deploy`@`10.0.%` TRIGGER `t` BEFORE INSERT ON `a` FOR EACH ROW SET NEW.note = 'DEFINER=`x`@`y`'"),
+	)
+	require.Equal(t, "CREATE PROCEDURE `p`() SELECT 1", StripDefiner("CREATE DEFINER='it''s'@'localhost' PROCEDURE `p`() SELECT 1"))
+	require.Equal(t, "CREATE FUNCTION `f`() RETURNS int RETURN 1", StripDefiner("CREATE DEFINER=`odd``name`@localhost FUNCTION `f`() RETURNS int RETURN 1"))
+	require.Equal(t, "create or replace view v as select 1", StripDefiner("create or replace definer = current_user() view v as select 1"))
+	require.Equal(t, "CREATE VIEW `v` AS select 1", StripDefiner("CREATE DEFINER=`app_role` VIEW `v` AS select 1"))
+	require.Equal(t, "CREATE TABLE `a` (id INT)", StripDefiner("CREATE TABLE `a` (id INT)"))
+}
+
+func TestSplitValues(t *testing.T) {
+	require.Equal(t, []string{"1", `"2024-01-02 03:04:05.000000"`, "NULL"}, SplitValues(`(1, "2024-01-02 03:04:05.000000", NULL),`))
+	require.Equal(t, []string{"2", `"a, \"b\")"`, `'c''d'`, "120"}, SplitValues(`(2, "a, \"b\")", 'c''d', 120)`))
+}
+
+func TestSplitStatements(t *testing.T) {
+	require.Equal(t, []string{"SELECT 1"}, SplitStatements("SELECT 1"))
+	require.Equal(t, []string{"SELECT 1"}, SplitStatements("  SELECT 1;\n\n"))
+	require.Equal(t, []string{"SELECT 1", "SELECT 2"}, SplitStatements("SELECT 1; SELECT 2;"))
+	require.Equal(t,
+		[]string{"INSERT INTO a VALUES ('x;y', \"it\\\"s;\", 'it''s;')", "SELECT `odd;name` FROM a"},
+		SplitStatements("INSERT INTO a VALUES ('x;y', \"it\\\"s;\", 'it''s;');\nSELECT `odd;name` FROM a;"),
+	)
+	require.Equal(t,
+		[]string{"-- first; still a comment\nSELECT 1", "/* a; b */ SELECT 2", "SELECT 3--1"},
+		SplitStatements("-- first; still a comment\nSELECT 1;\n/* a; b */ SELECT 2;\nSELECT 3--1;\n# trailing; comment\n"),
+	)
+	require.Equal(t, []string{"/*!40101 SET NAMES utf8 */"}, SplitStatements("/*!40101 SET NAMES utf8 */;"))
+	require.Empty(t, SplitStatements(" ;\n-- nothing\n;"))
+}
+
+func TestSnippet(t *testing.T) {
+	require.Equal(t, "SELECT 1 FROM a", Snippet("SELECT 1\n  FROM a", 20))
+	require.Equal(t, "SELECT...", Snippet("SELECT 1 FROM a", 6))
+}
+
+func TestSplitStatementsWithDelimiter(t *testing.T) {
+	sql := `CREATE TABLE a (id INT);
+
+DELIMITER $$
+CREATE TRIGGER a_insert BEFORE INSERT ON a FOR EACH ROW
+BEGIN
+  SET NEW.id = NEW.id + 1; -- ends in $$ not ;
+  SET @last = ';$$';
+END$$
+delimiter ;
+INSERT INTO a VALUES (1);
+`
+	require.Equal(t, []string{
+		"CREATE TABLE a (id INT)",
+		"CREATE TRIGGER a_insert BEFORE INSERT ON a FOR EACH ROW\nBEGIN\n  SET NEW.id = NEW.id + 1; -- ends in $$ not ;\n  SET @last = ';$$';\nEND",
+		"INSERT INTO a VALUES (1)",
+	}, SplitStatements(sql))
+
+	// only a command at the start of a statement
+	require.Equal(t, []string{"SELECT delimiter FROM a"}, SplitStatements("SELECT delimiter FROM a;"))
+	require.Equal(t, []string{"SELECT 1\nDELIMITER //"}, SplitStatements("SELECT 1\nDELIMITER //"))
+}
+
+func TestScannerReadingOneByteAtATime(t *testing.T) {
+	for _, sql := range []string{
+		"  SELECT 1;\n\n",
+		"INSERT INTO a VALUES ('x;y', \"it\\\"s;\", 'it''s;');\nSELECT `odd;name` FROM a;",
+		"-- first; still a comment\nSELECT 1;\n/* a; b */ SELECT 2;\nSELECT 3--1;\n# trailing; comment\n",
+		"/*!40101 SET NAMES utf8 */;",
+		"SELECT 1;\nDELIMITER $$\nCREATE PROCEDURE p() BEGIN SELECT 1; END$$\ndelimiter ;\nSELECT 2; -- done",
+		"SELECT 1\nDELIMITER //",
+	} {
+		scanner := NewScanner(iotest.OneByteReader(strings.NewReader(sql)))
+		statements := []string{}
+		for scanner.Scan() {
+			statements = append(statements, scanner.Statement())
+		}
+		require.NoError(t, scanner.Err())
+		require.Equal(t, SplitStatements(sql), statements, sql)
+	}
+}
+
+func TestScannerReadError(t *testing.T) {
+	scanner := NewScanner(iotest.TimeoutReader(strings.NewReader("SELECT 1; SELECT")))
+	require.True(t, scanner.Scan())
+	require.Equal(t, "SELECT 1", scanner.Statement())
+	require.False(t, scanner.Scan())
+	require.Equal(t, iotest.ErrTimeout, scanner.Err())
+}
+
+func TestDestructive(t *testing.T) {
+	cases := map[string]string{
+		"DROP TABLE blarg":                                    "DROP TABLE",
+		"drop temporary table if exists blarg":                "DROP TABLE",
+		"/* cleanup */ DROP TABLE `blarg`":                    "DROP TABLE",
+		"DROP DATABASE blarg":                                 "DROP DATABASE",
+		"DROP SCHEMA IF EXISTS blarg":                         "DROP DATABASE",
+		"TRUNCATE TABLE blarg":                                "TRUNCATE",
+		"truncate blarg":                                      "TRUNCATE",
+		"ALTER TABLE blarg DROP COLUMN name":                  "DROP COLUMN",
+		"ALTER TABLE blarg ADD COLUMN a INT, DROP `name`":     "DROP COLUMN",
+		"ALTER TABLE blarg DROP name":                         "DROP COLUMN",
+		"ALTER TABLE blarg DROP PARTITION p1":                 "DROP PARTITION",
+		"ALTER TABLE blarg TRUNCATE PARTITION p1":             "TRUNCATE PARTITION",
+		"/*!50001 DROP TABLE blarg */":                        "DROP TABLE",
+		"ALTER TABLE blarg DROP INDEX name, DROP PRIMARY KEY": "",
+		"ALTER TABLE blarg DROP FOREIGN KEY fk, DROP CHECK c": "",
+		"ALTER TABLE blarg ALTER COLUMN name DROP DEFAULT":    "",
+		"ALTER TABLE blarg ADD COLUMN `drop` INT":             "",
+		"ALTER TABLE blarg COMMENT 'DROP COLUMN name later'":  "",
+		"ALTER TABLE blarg ADD COLUMN a INT -- DROP COLUMN b": "",
+		"INSERT INTO log (note) VALUES ('DROP TABLE blarg')":  "",
+		"CREATE TABLE blarg (id INT) /* DROP TABLE blarg */":  "",
+		"DROP INDEX name ON blarg":                            "",
+		"DROP PROCEDURE IF EXISTS add_blarg":                  "",
+		"CREATE TABLE `drop table` (id INT)":                  "",
+		"UPDATE blarg SET note = \"it's; DROP TABLE x\"":      "",
+		"SELECT 1 -- TRUNCATE blarg":                          "",
+	}
+
+	for statement, expected := range cases {
+		require.Equal(t, expected, Destructive(statement), statement)
+	}
+}
+
+func TestTargetTable(t *testing.T) {
+	cases := map[string][2]string{
+		"ALTER TABLE blarg ADD COLUMN name VARCHAR(64)":        {"", "blarg"},
+		"alter online table `my table` engine=InnoDB":          {"", "my table"},
+		"-- widen\n/* it */ ALTER TABLE db.blarg DROP INDEX x": {"db", "blarg"},
+		"ALTER TABLE `db` . `blarg` FORCE":                     {"db", "blarg"},
+		"CREATE UNIQUE INDEX name ON blarg (name)":             {"", "blarg"},
+		"CREATE INDEX name USING BTREE ON `blarg` (name)":      {"", "blarg"},
+		"DROP INDEX name ON blarg":                             {"", "blarg"},
+		"OPTIMIZE TABLE blarg":                                 {"", "blarg"},
+	}
+	for statement, expected := range cases {
+		database, table, ok := TargetTable(statement)
+		require.True(t, ok, statement)
+		require.Equal(t, expected, [2]string{database, table}, statement)
+	}
+
+	for _, statement := range []string{
+		"CREATE TABLE blarg (id INT)",
+		"INSERT INTO blarg VALUES (1)",
+		"-- ALTER TABLE blarg FORCE",
+		"SELECT 'ALTER TABLE blarg FORCE'",
+	} {
+		_, _, ok := TargetTable(statement)
+		require.False(t, ok, statement)
+	}
+}
+
+func TestCreatedTable(t *testing.T) {
+	cases := map[string][2]string{
+		"CREATE TABLE blarg (id INT)":                         {"", "blarg"},
+		"create table if not exists `db`.`my table` (id INT)": {"db", "my table"},
+		"/* new */ CREATE TABLE gralb LIKE blarg":             {"", "gralb"},
+	}
+	for statement, expected := range cases {
+		database, table, ok := CreatedTable(statement)
+		require.True(t, ok, statement)
+		require.Equal(t, expected, [2]string{database, table}, statement)
+	}
+
+	for _, statement := range []string{
+		"CREATE TEMPORARY TABLE blarg (id INT)",
+		"ALTER TABLE blarg FORCE",
+		"-- CREATE TABLE blarg (id INT)",
+	} {
+		_, _, ok := CreatedTable(statement)
+		require.False(t, ok, statement)
+	}
+}
+
+func TestEnforceOnlineDDL(t *testing.T) {
+	enforced, ok := EnforceOnlineDDL("ALTER TABLE blarg ADD COLUMN name VARCHAR(64) -- for display")
+	require.True(t, ok)
+	require.Equal(t, "ALTER TABLE blarg ADD COLUMN name VARCHAR(64) -- for display\n, ALGORITHM=INPLACE, LOCK=NONE", enforced)
+
+	for _, statement := range []string{
+		"ALTER TABLE blarg ADD INDEX name (name), ALGORITHM=INSTANT",
+		"ALTER TABLE blarg ADD INDEX name (name), LOCK = SHARED",
+		"ALTER TABLE blarg PARTITION BY HASH(id) PARTITIONS 4",
+		"CREATE TABLE blarg (id INT)",
+		"INSERT INTO blarg (note) VALUES ('ALTER TABLE blarg FORCE')",
+	} {
+		unchanged, ok := EnforceOnlineDDL(statement)
+		require.False(t, ok, statement)
+		require.Equal(t, statement, unchanged)
+	}
+
+	// columns and indexes named after the clauses don't choose anything
+	for _, statement := range []string{
+		"ALTER TABLE users ADD COLUMN locked_at DATETIME",
+		"ALTER TABLE users ADD COLUMN lock_version INT",
+		"ALTER TABLE users ADD COLUMN `lock` INT",
+		"ALTER TABLE users ADD INDEX (algorithm_id)",
+		"ALTER TABLE users ADD COLUMN algorithm VARCHAR(64)",
+	} {
+		enforced, ok := EnforceOnlineDDL(statement)
+		require.True(t, ok, statement)
+		require.Equal(t, statement+"\n, ALGORITHM=INPLACE, LOCK=NONE", enforced)
+	}
+
+	unchanged, ok := EnforceOnlineDDL("ALTER TABLE blarg ADD COLUMN lock_version INT, ALGORITHM COPY")
+	require.False(t, ok)
+	require.Equal(t, "ALTER TABLE blarg ADD COLUMN lock_version INT, ALGORITHM COPY", unchanged)
+
+	enforced, ok = EnforceOnlineDDL("ALTER TABLE blarg COMMENT 'no LOCK=NONE here'")
+	require.True(t, ok)
+	require.Equal(t, "ALTER TABLE blarg COMMENT 'no LOCK=NONE here'\n, ALGORITHM=INPLACE, LOCK=NONE", enforced)
+}
+
+func TestInstantDDL(t *testing.T) {
+	for _, statement := range []string{
+		"ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ALGORITHM=INSTANT",
+		"/* fast */ alter table blarg add column name varchar(64), algorithm = instant",
+		"ALTER TABLE blarg RENAME COLUMN a TO b, ALGORITHM INSTANT",
+	} {
+		require.True(t, InstantDDL(statement), statement)
+	}
+
+	for _, statement := range []string{
+		"ALTER TABLE blarg ADD COLUMN name VARCHAR(64)",
+		"ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ALGORITHM=INPLACE",
+		"ALTER TABLE blarg COMMENT 'ALGORITHM=INSTANT'",
+		"CREATE TABLE blarg (id INT) COMMENT 'ALGORITHM=INSTANT'",
+	} {
+		require.False(t, InstantDDL(statement), statement)
+	}
+}
+
+func TestMaxExecutionTime(t *testing.T) {
+	require.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(250) */ TABLE_ROWS FROM information_schema.TABLES", MaxExecutionTime("SELECT TABLE_ROWS FROM information_schema.TABLES", 250))
+	require.Equal(t, "  select /*+ MAX_EXECUTION_TIME(1) */ 1", MaxExecutionTime("  select 1", 1))
+	require.Equal(t, "SELECT 1", MaxExecutionTime("SELECT 1", 0))
+	require.Equal(t, "SHOW TABLES", MaxExecutionTime("SHOW TABLES", 250))
+	require.Equal(t, "SELECTED", MaxExecutionTime("SELECTED", 250))
+}
@@ -0,0 +1,75 @@
+package dialect
+
+import (
+	"regexp"
+	"strings"
+)
+
+var autoIncrementOption = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+// StripAutoIncrement removes the AUTO_INCREMENT=N table option, the table's
+// next counter value, from SHOW CREATE TABLE's output. Column definitions,
+// including their AUTO_INCREMENT attribute, are left alone as the options
+// are only looked for on the line closing them.
+func StripAutoIncrement(createTable string) string {
+	start := strings.Index(createTable, "\n)")
+	if start < 0 {
+		return createTable
+	}
+	end := strings.IndexByte(createTable[start+1:], '\n')
+	if end < 0 {
+		end = len(createTable)
+	} else {
+		end += start + 1
+	}
+
+	options := createTable[start:end]
+	if loc := autoIncrementOption.FindStringIndex(options); loc != nil {
+		options = options[:loc[0]] + options[loc[1]:]
+	}
+	return createTable[:start] + options + createTable[end:]
+}
+
+// account matches a user or host name, quoted or not.
+const account = "(?:`(?:[^`]|``)*`|'(?:[^'\\\\]|''|\\\\.)*'|\"(?:[^\"\\\\]|\"\"|\\\\.)*\"|[\\w.$%-]+)"
+
+// definerClause matches the DEFINER clause in the header of a CREATE VIEW,
+// TRIGGER, PROCEDURE, FUNCTION or EVENT statement, in any of the quoting
+// forms MySQL and MariaDB accept, and with or without a host as roles have
+// none.
+var definerClause = regexp.MustCompile(
+	"(?is)^(\\s*CREATE\\s+(?:OR\\s+REPLACE\\s+)?(?:ALGORITHM\\s*=\\s*\\w+\\s+)?)" +
+		"DEFINER\\s*=\\s*(?:CURRENT_USER(?:\\s*\\(\\s*\\))?|" + account + "(?:\\s*@\\s*" + account + ")?)\\s+",
+)
+
+// StripDefiner removes the DEFINER clause from statement, so the object is
+// created with the user executing it as its definer.
+func StripDefiner(statement string) string {
+	return definerClause.ReplaceAllString(statement, "$1")
+}
+
+// SplitValues splits the values of one row of a dumped INSERT, e.g.
+// (1, "a, b", NULL), into its trimmed literals. Commas in quoted strings don't
+// split them, and the literals are left quoted.
+func SplitValues(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimSuffix(row, ",")
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "("), ")")
+
+	values := []string{}
+	start := 0
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '"', '\'':
+			if end := closingQuote(row, i+1, row[i]); end >= 0 {
+				i = end
+			} else {
+				i = len(row)
+			}
+		case ',':
+			values = append(values, strings.TrimSpace(row[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, strings.TrimSpace(row[start:]))
+}
@@ -0,0 +1,31 @@
+package dialect
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CountGTIDs counts the transactions in a GTID set such as
+// "3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5:11,4F...:7".
+func CountGTIDs(set string) int64 {
+	count := int64(0)
+	for _, member := range strings.Split(set, ",") {
+		intervals := strings.Split(strings.TrimSpace(member), ":")
+		for _, interval := range intervals[1:] {
+			bounds := strings.SplitN(interval, "-", 2)
+			start, err := strconv.ParseInt(bounds[0], 10, 64)
+			if err != nil {
+				// tagged GTIDs (MySQL 8.3+) put the tag between the uuid and intervals
+				continue
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil {
+					continue
+				}
+			}
+			count += end - start + 1
+		}
+	}
+	return count
+}
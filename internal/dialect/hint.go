@@ -0,0 +1,20 @@
+package dialect
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var selectPattern = regexp.MustCompile(`(?i)\A\s*SELECT\b`)
+
+// MaxExecutionTime adds a MAX_EXECUTION_TIME optimizer hint to a SELECT, so
+// MySQL 5.7.8 and later abort it with error 3024 after milliseconds. Servers
+// without optimizer hints read it as a comment. Other statements are returned
+// as they are.
+func MaxExecutionTime(query string, milliseconds int64) string {
+	loc := selectPattern.FindStringIndex(query)
+	if loc == nil || milliseconds <= 0 {
+		return query
+	}
+	return query[:loc[1]] + fmt.Sprintf(" /*+ MAX_EXECUTION_TIME(%d) */", milliseconds) + query[loc[1]:]
+}
@@ -0,0 +1,34 @@
+package dialect
+
+import "regexp"
+
+// OnlineDDLClause is appended to ALTER TABLE statements by EnforceOnlineDDL.
+const OnlineDDLClause = "ALGORITHM=INPLACE, LOCK=NONE"
+
+var (
+	algorithmOrLockPattern = regexp.MustCompile(`(?i)\bALGORITHM\s*=?\s*(?:DEFAULT|INPLACE|COPY|INSTANT)\b|\bLOCK\s*=?\s*(?:DEFAULT|NONE|SHARED|EXCLUSIVE)\b`)
+	partitionPattern       = regexp.MustCompile(`(?i)\bPARTITION(?:S|ING)?\b`)
+	instantPattern         = regexp.MustCompile(`(?i)\bALGORITHM\s*=?\s*INSTANT\b`)
+)
+
+// EnforceOnlineDDL appends OnlineDDLClause to an ALTER TABLE statement that
+// doesn't already choose its algorithm or lock, so that the server rejects it
+// rather than copying or locking the table. It reports whether it did.
+// Statements with partitioning clauses, which have to come last, are left as
+// they are.
+func EnforceOnlineDDL(statement string) (string, bool) {
+	code := stripLiterals(statement)
+	if !alterTablePattern.MatchString(code) || algorithmOrLockPattern.MatchString(code) || partitionPattern.MatchString(code) {
+		return statement, false
+	}
+	// on a line of its own, in case the statement ends in a -- comment
+	return statement + "\n, " + OnlineDDLClause, true
+}
+
+// InstantDDL reports whether statement is an ALTER TABLE that asks for
+// ALGORITHM=INSTANT, which the server either does without touching the
+// table's rows or rejects.
+func InstantDDL(statement string) bool {
+	code := stripLiterals(statement)
+	return alterTablePattern.MatchString(code) && instantPattern.MatchString(code)
+}
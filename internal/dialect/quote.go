@@ -0,0 +1,36 @@
+// Package dialect holds the parts of the package that deal with MySQL's SQL
+// text and server formats rather than a connection.
+package dialect
+
+import "strings"
+
+// QuoteString formats s as a double quoted MySQL string literal.
+func QuoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\x1a':
+			b.WriteString(`\Z`)
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// QuoteIdentifier formats name as a backtick quoted MySQL identifier, so names
+// with hyphens or that are reserved words can be interpolated safely.
+func QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
@@ -0,0 +1,287 @@
+package dialect
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CheckComplete reports SQL that ends inside a string, quoted
+// identifier or comment, or with unbalanced parentheses, which is what a
+// truncated file looks like.
+func CheckComplete(sql string) error {
+	depth := 0
+
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := closingQuote(sql, i+1, c)
+			if end < 0 {
+				return errors.Errorf("unterminated %c quoted string", c)
+			}
+			i = end
+		case lineComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return nil
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return errors.New("unterminated comment")
+			}
+			i += end + 3
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return errors.New("unbalanced parentheses")
+			}
+		}
+	}
+
+	if depth != 0 {
+		return errors.New("unbalanced parentheses")
+	}
+	return nil
+}
+
+// closingQuote returns the index of the quote closing the string started
+// before start, or -1. Quotes are escaped by doubling them and, other than in
+// identifiers, with a backslash.
+func closingQuote(sql string, start int, quote byte) int {
+	for i := start; i < len(sql); i++ {
+		switch {
+		case sql[i] == '\\' && quote != '`':
+			i++
+		case sql[i] == quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// lineComment reports whether a # or -- comment starts at sql[i].
+func lineComment(sql string, i int) bool {
+	switch {
+	case sql[i] == '#':
+		return true
+	case sql[i] == '-' && strings.HasPrefix(sql[i:], "--"):
+		// -- only starts a comment when followed by whitespace
+		return i+2 == len(sql) || strings.IndexByte(" \t\r\n", sql[i+2]) >= 0
+	}
+	return false
+}
+
+// SplitStatements splits sql into statements on the delimiters ending them,
+// skipping those in strings, quoted identifiers and comments, the way the
+// mysql client does. The delimiter is a semicolon until changed by a
+// DELIMITER line, e.g. so a CREATE TRIGGER with semicolons in its body is one
+// statement. Statements are trimmed, and those that are empty or only
+// comments are dropped.
+func SplitStatements(sql string) []string {
+	s := &Scanner{buf: sql, delimiter: ";", eof: true}
+
+	statements := []string{}
+	for s.Scan() {
+		statements = append(statements, s.Statement())
+	}
+	return statements
+}
+
+// scanChunkSize is how much a Scanner reads at a time.
+const scanChunkSize = 64 * 1024
+
+// Scanner splits SQL read from a reader into statements the way
+// SplitStatements does, holding no more than the statement being read in
+// memory, for files too large to read at once.
+type Scanner struct {
+	r         io.Reader
+	buf       string
+	delimiter string
+	statement string
+	err       error
+	eof       bool
+	chunk     []byte
+
+	// pos is where scanning buf resumes after reading more, and code is
+	// whether anything other than whitespace and comments precedes it.
+	pos  int
+	code bool
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: r, delimiter: ";"}
+}
+
+// Scan advances to the next statement, returning false at the end of the
+// input or on an error reading it.
+func (s *Scanner) Scan() bool {
+	for {
+		if statement, ok := s.next(); ok {
+			s.statement = statement
+			return true
+		}
+		if s.eof {
+			return false
+		}
+
+		if s.chunk == nil {
+			s.chunk = make([]byte, scanChunkSize)
+		}
+		n, err := s.r.Read(s.chunk)
+		s.buf += string(s.chunk[:n])
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			s.err = err
+			return false
+		}
+	}
+}
+
+func (s *Scanner) Statement() string {
+	return s.statement
+}
+
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// next cuts the next statement from buf. Until the end of the input it
+// returns false when buf ends before the statement does, or anywhere what
+// follows could change how it's read, e.g. between the quotes of a doubled
+// quote.
+func (s *Scanner) next() (string, bool) {
+	sql := s.buf
+	more := !s.eof
+
+	for i := s.pos; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case (c == 'D' || c == 'd') && !s.code && more && strings.IndexByte(sql[i:], '\n') < 0:
+			s.pos = i
+			return "", false
+		case (c == 'D' || c == 'd') && !s.code && isDelimiterCommand(sql[i:]):
+			line := sql[i:]
+			if end := strings.IndexByte(line, '\n'); end >= 0 {
+				line = line[:end]
+			}
+			if fields := strings.Fields(line); len(fields) > 1 {
+				s.delimiter = fields[1]
+			}
+			sql, i = sql[i+len(line):], -1
+			s.buf = sql
+		case c == '\'' || c == '"' || c == '`':
+			s.code = true
+			end := closingQuote(sql, i+1, c)
+			if more && (end < 0 || end == len(sql)-1) {
+				s.pos = i
+				return "", false
+			}
+			if end < 0 {
+				end = len(sql)
+			}
+			i = end
+		case more && (c == '-' || c == '/') && i+2 >= len(sql):
+			s.pos = i
+			return "", false
+		case lineComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 && more {
+				s.pos = i
+				return "", false
+			}
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			s.code = s.code || strings.HasPrefix(sql[i:], "/*!")
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 && more {
+				s.pos = i
+				return "", false
+			}
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end + 3
+		case more && len(sql)-i < len(s.delimiter) && strings.HasPrefix(s.delimiter, sql[i:]):
+			s.pos = i
+			return "", false
+		case strings.HasPrefix(sql[i:], s.delimiter):
+			statement := strings.TrimSpace(sql[:i])
+			sql, i = sql[i+len(s.delimiter):], -1
+			s.buf, s.pos, s.code = sql, 0, false
+			if !onlyComments(statement) {
+				return statement, true
+			}
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			s.code = true
+		}
+	}
+
+	if more {
+		s.pos = len(sql)
+		return "", false
+	}
+
+	statement := strings.TrimSpace(sql)
+	s.buf, s.pos, s.code = "", 0, false
+	if onlyComments(statement) {
+		return "", false
+	}
+	return statement, true
+}
+
+// isDelimiterCommand reports whether sql starts with the mysql client's
+// DELIMITER command.
+func isDelimiterCommand(sql string) bool {
+	const command = "DELIMITER"
+	return len(sql) > len(command) &&
+		strings.EqualFold(sql[:len(command)], command) &&
+		(sql[len(command)] == ' ' || sql[len(command)] == '\t')
+}
+
+// onlyComments reports whether sql is nothing but whitespace and comments.
+func onlyComments(sql string) bool {
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		case lineComment(sql, i):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return true
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*") && !strings.HasPrefix(sql[i:], "/*!"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return true
+			}
+			i += end + 3
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Snippet shortens sql to around n bytes on a single line, for errors.
+func Snippet(sql string, n int) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) <= n {
+		return sql
+	}
+	for n > 0 && sql[n]&0xC0 == 0x80 {
+		n--
+	}
+	return sql[:n] + "..."
+}
@@ -0,0 +1,76 @@
+package dialect
+
+import (
+	"regexp"
+	"strings"
+)
+
+// qualifiedTableName matches a table name, optionally qualified by its
+// database, either of which can be quoted.
+const qualifiedTableName = "(?:`([^`]+)`|([\\w$]+))(?:\\s*\\.\\s*(?:`([^`]+)`|([\\w$]+)))?"
+
+var targetTablePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?is)^ALTER\s+(?:ONLINE\s+|IGNORE\s+)*TABLE\s+` + qualifiedTableName),
+	regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+|FULLTEXT\s+|SPATIAL\s+)?INDEX\s+\S+\s+(?:USING\s+\w+\s+)?ON\s+` + qualifiedTableName),
+	regexp.MustCompile(`(?is)^DROP\s+INDEX\s+\S+\s+ON\s+` + qualifiedTableName),
+	regexp.MustCompile(`(?is)^OPTIMIZE\s+(?:NO_WRITE_TO_BINLOG\s+|LOCAL\s+)?TABLE\s+` + qualifiedTableName),
+}
+
+var createdTablePattern = regexp.MustCompile(`(?is)^CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + qualifiedTableName)
+
+// TargetTable returns the existing table statement changes the structure of,
+// for ALTER TABLE, CREATE INDEX, DROP INDEX and OPTIMIZE TABLE. database is
+// empty unless the table name is qualified with it. It's best effort, and ok
+// is false for anything else.
+func TargetTable(statement string) (database string, table string, ok bool) {
+	statement = skipComments(statement)
+	for _, pattern := range targetTablePatterns {
+		match := pattern.FindStringSubmatch(statement)
+		if match == nil {
+			continue
+		}
+		first, second := match[1]+match[2], match[3]+match[4]
+		if len(second) == 0 {
+			return "", first, true
+		}
+		return first, second, true
+	}
+	return "", "", false
+}
+
+// CreatedTable returns the table a CREATE TABLE statement creates, like
+// TargetTable. Temporary tables aren't included.
+func CreatedTable(statement string) (database string, table string, ok bool) {
+	match := createdTablePattern.FindStringSubmatch(skipComments(statement))
+	if match == nil {
+		return "", "", false
+	}
+	first, second := match[1]+match[2], match[3]+match[4]
+	if len(second) == 0 {
+		return "", first, true
+	}
+	return first, second, true
+}
+
+// skipComments drops the whitespace and comments sql starts with.
+func skipComments(sql string) string {
+	for {
+		sql = strings.TrimLeft(sql, " \t\r\n")
+		switch {
+		case len(sql) > 0 && lineComment(sql, 0):
+			end := strings.IndexByte(sql, '\n')
+			if end < 0 {
+				return ""
+			}
+			sql = sql[end:]
+		case strings.HasPrefix(sql, "/*") && !strings.HasPrefix(sql, "/*!"):
+			end := strings.Index(sql[2:], "*/")
+			if end < 0 {
+				return ""
+			}
+			sql = sql[end+4:]
+		default:
+			return sql
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package migration
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// migrationFilePattern matches migration file names like
+// 0001_create_users.sql or 0001_create_users.up.sql, capturing the version,
+// name and direction.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)(?:_(.+?))?(?:\.(up|down))?\.sql$`)
+
+type migrationFile struct {
+	file    string
+	version int
+	name    string
+	// direction is up or down for files following the .up.sql/.down.sql
+	// convention, and empty otherwise.
+	direction string
+}
+
+// LoadOption changes how FromFS loads migrations.
+type LoadOption func(*loader)
+
+type loader struct {
+	unlistedLogger Logger
+}
+
+// WithUnlistedWarning makes FromFS log .sql files missing from the manifest
+// to logger and skip them, rather than failing.
+func WithUnlistedWarning(logger Logger) LoadOption {
+	return func(l *loader) {
+		l.unlistedLogger = logger
+	}
+}
+
+// FromFS reads the migrations in dir of fsys, e.g. an embed.FS, one per .sql
+// file named like 0001_create_users.sql. The leading number is the version,
+// the rest is the name, and the contents are Up. They're returned in version
+// order. Files that don't end in .sql are ignored.
+//
+// Directories can instead follow the .up.sql/.down.sql convention, with files
+// like 0001_create_users.up.sql and an optional 0001_create_users.down.sql
+// that's used as Down. The two conventions can't be mixed.
+//
+// When dir has a manifest, see manifestFile, only the files it lists are
+// loaded and every other .sql file is an error.
+func FromFS(fsys fs.FS, dir string, opts ...LoadOption) ([]Migration, error) {
+	l := &loader{}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading dir %q", dir)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && path.Ext(entry.Name()) == ".sql" {
+			names = append(names, entry.Name())
+		}
+	}
+
+	listed, err := readManifest(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	if listed != nil {
+		names, err = l.applyManifest(names, listed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plain := map[int]migrationFile{}
+	ups := map[int]migrationFile{}
+	downs := map[int]migrationFile{}
+	for _, name := range names {
+		file, err := parseMigrationFileName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		files := plain
+		switch file.direction {
+		case "up":
+			files = ups
+		case "down":
+			files = downs
+		}
+		if existing, ok := files[file.version]; ok {
+			return nil, errors.Errorf("%q and %q are both migration %d", existing.file, file.file, file.version)
+		}
+		files[file.version] = file
+	}
+
+	if len(plain) > 0 && len(ups)+len(downs) > 0 {
+		return nil, errors.Errorf(
+			"dir %q mixes files like %q with the .up.sql/.down.sql convention of files like %q, use one or the other",
+			dir, firstMigrationFile(plain).file, firstMigrationFile(ups, downs).file,
+		)
+	}
+
+	for version, down := range downs {
+		up, ok := ups[version]
+		if !ok {
+			return nil, errors.Errorf("%q has no matching up file", down.file)
+		}
+		if up.name != down.name {
+			return nil, errors.Errorf("%q and %q are both migration %d but are named differently", up.file, down.file, version)
+		}
+	}
+	for version, file := range ups {
+		plain[version] = file
+	}
+
+	migrations := []Migration{}
+	for version, file := range plain {
+		up, err := readMigrationFile(fsys, path.Join(dir, file.file))
+		if err != nil {
+			return nil, err
+		}
+
+		definition := &Definition{ID: version, Name: file.name, Up: up}
+		if down, ok := downs[version]; ok {
+			definition.Down, err = readMigrationFile(fsys, path.Join(dir, down.file))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		migrations = append(migrations, definition)
+	}
+
+	return sortMigrations(migrations), nil
+}
+
+func parseMigrationFileName(file string) (migrationFile, error) {
+	match := migrationFilePattern.FindStringSubmatch(file)
+	if match == nil {
+		return migrationFile{}, errors.Errorf("%q isn't named like a migration, e.g. 0001_create_users.sql", file)
+	}
+
+	version, err := strconv.Atoi(match[1])
+	if err != nil {
+		return migrationFile{}, errors.Wrapf(err, "%q has an invalid version", file)
+	}
+
+	return migrationFile{file: file, version: version, name: match[2], direction: match[3]}, nil
+}
+
+// firstMigrationFile returns the file with the lowest version in the first of
+// sets that isn't empty, for error messages.
+func firstMigrationFile(sets ...map[int]migrationFile) migrationFile {
+	for _, files := range sets {
+		var first *migrationFile
+		for _, file := range files {
+			if first == nil || file.version < first.version {
+				file := file
+				first = &file
+			}
+		}
+		if first != nil {
+			return *first
+		}
+	}
+	return migrationFile{}
+}
+
+func readMigrationFile(fsys fs.FS, file string) (string, error) {
+	contents, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed reading %q", file)
+	}
+	if len(strings.TrimSpace(string(contents))) == 0 {
+		return "", errors.Errorf("%q is empty", file)
+	}
+	return string(contents), nil
+}
@@ -0,0 +1,217 @@
+package migration_test
+
+import (
+	"context"
+	"embed"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/migrations
+var embeddedMigrations embed.FS
+
+func TestFromFS(t *testing.T) {
+	expected := []migration.Migration{
+		&migration.Definition{
+			ID:   1,
+			Name: "create_blarg",
+			Up:   "CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB\n",
+		},
+		&migration.Definition{
+			ID:   2,
+			Name: "create_gralb",
+			Up:   "CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB\n",
+		},
+	}
+
+	embedded, err := migration.FromFS(embeddedMigrations, "testdata/migrations")
+	require.NoError(t, err)
+	require.Equal(t, expected, embedded)
+
+	dir, err := migration.FromFS(os.DirFS("testdata"), "migrations")
+	require.NoError(t, err)
+	require.Equal(t, expected, dir)
+
+	dbname := "fromfstest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), embedded, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.True(t, tableExists(fullDSN(dbname), "gralb"))
+}
+
+func TestFromFSSortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/10_later.sql": {Data: []byte("SELECT 10")},
+		"m/9.sql":        {Data: []byte("SELECT 9")},
+	}
+
+	migrations, err := migration.FromFS(fsys, "m")
+	require.NoError(t, err)
+	require.Equal(t, []migration.Migration{
+		&migration.Definition{ID: 9, Up: "SELECT 9"},
+		&migration.Definition{ID: 10, Name: "later", Up: "SELECT 10"},
+	}, migrations)
+}
+
+func TestFromFSRejectsInvalidDirectories(t *testing.T) {
+	cases := map[string]struct {
+		fsys fstest.MapFS
+		err  string
+	}{
+		"duplicate version": {
+			fsys: fstest.MapFS{
+				"m/0001_one.sql": {Data: []byte("SELECT 1")},
+				"m/1_uno.sql":    {Data: []byte("SELECT 1")},
+			},
+			err: `"0001_one.sql" and "1_uno.sql" are both migration 1`,
+		},
+		"unparseable name": {
+			fsys: fstest.MapFS{
+				"m/create_users.sql": {Data: []byte("SELECT 1")},
+			},
+			err: `"create_users.sql" isn't named like a migration, e.g. 0001_create_users.sql`,
+		},
+		"empty file": {
+			fsys: fstest.MapFS{
+				"m/0001_one.sql": {Data: []byte(" \n")},
+			},
+			err: `"m/0001_one.sql" is empty`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := migration.FromFS(c.fsys, "m")
+			require.EqualError(t, err, c.err)
+		})
+	}
+}
+
+func TestFromFSUpDownConvention(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_create_blarg.up.sql":   {Data: []byte("CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB")},
+		"m/0001_create_blarg.down.sql": {Data: []byte("DROP TABLE blarg")},
+		"m/0002_seed_blarg.up.sql":     {Data: []byte("INSERT INTO blarg (id) VALUES (1)")},
+	}
+
+	migrations, err := migration.FromFS(fsys, "m")
+	require.NoError(t, err)
+	require.Equal(t, []migration.Migration{
+		&migration.Definition{
+			ID:   1,
+			Name: "create_blarg",
+			Up:   "CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB",
+			Down: "DROP TABLE blarg",
+		},
+		&migration.Definition{ID: 2, Name: "seed_blarg", Up: "INSERT INTO blarg (id) VALUES (1)"},
+	}, migrations)
+}
+
+func TestFromFSRejectsInvalidUpDownDirectories(t *testing.T) {
+	cases := map[string]struct {
+		fsys fstest.MapFS
+		err  string
+	}{
+		"mixed conventions": {
+			fsys: fstest.MapFS{
+				"m/0001_create_blarg.sql":      {Data: []byte("SELECT 1")},
+				"m/0002_create_gralb.up.sql":   {Data: []byte("SELECT 2")},
+				"m/0002_create_gralb.down.sql": {Data: []byte("SELECT 2")},
+			},
+			err: `dir "m" mixes files like "0001_create_blarg.sql" with the .up.sql/.down.sql convention of files like "0002_create_gralb.up.sql", use one or the other`,
+		},
+		"down without up": {
+			fsys: fstest.MapFS{
+				"m/0001_create_blarg.up.sql":   {Data: []byte("SELECT 1")},
+				"m/0002_create_gralb.down.sql": {Data: []byte("SELECT 2")},
+			},
+			err: `"0002_create_gralb.down.sql" has no matching up file`,
+		},
+		"names differ": {
+			fsys: fstest.MapFS{
+				"m/0001_create_blarg.up.sql": {Data: []byte("SELECT 1")},
+				"m/0001_drop_blarg.down.sql": {Data: []byte("SELECT 1")},
+			},
+			err: `"0001_create_blarg.up.sql" and "0001_drop_blarg.down.sql" are both migration 1 but are named differently`,
+		},
+		"empty down file": {
+			fsys: fstest.MapFS{
+				"m/0001_create_blarg.up.sql":   {Data: []byte("SELECT 1")},
+				"m/0001_create_blarg.down.sql": {Data: []byte("")},
+			},
+			err: `"m/0001_create_blarg.down.sql" is empty`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := migration.FromFS(c.fsys, "m")
+			require.EqualError(t, err, c.err)
+		})
+	}
+}
+
+func TestFromFSManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/migrations.list":            {Data: []byte("# in version order\n0001_create_blarg.up.sql\n\n0002_create_gralb.up.sql\n")},
+		"m/0001_create_blarg.up.sql":   {Data: []byte("SELECT 1")},
+		"m/0001_create_blarg.down.sql": {Data: []byte("SELECT -1")},
+		"m/0002_create_gralb.up.sql":   {Data: []byte("SELECT 2")},
+	}
+
+	migrations, err := migration.FromFS(fsys, "m")
+	require.NoError(t, err)
+	require.Equal(t, []migration.Migration{
+		&migration.Definition{ID: 1, Name: "create_blarg", Up: "SELECT 1", Down: "SELECT -1"},
+		&migration.Definition{ID: 2, Name: "create_gralb", Up: "SELECT 2"},
+	}, migrations)
+
+	fsys["m/0003_stray.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 3")}
+
+	_, err = migration.FromFS(fsys, "m")
+	require.EqualError(t, err, `"0003_stray.up.sql" isn't listed in migrations.list, add it or delete it`)
+
+	logger := &capturingLogger{}
+	migrations, err = migration.FromFS(fsys, "m", migration.WithUnlistedWarning(logger))
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, []string{`"0003_stray.up.sql" isn't listed in migrations.list, skipping it`}, logger.lines)
+}
+
+func TestFromFSRejectsInvalidManifests(t *testing.T) {
+	cases := map[string]struct {
+		manifest string
+		err      string
+	}{
+		"missing file": {
+			manifest: "0001_one.sql\n0002_two.sql\n0003_three.sql\n",
+			err:      `migrations.list lists "0003_three.sql" which doesn't exist`,
+		},
+		"out of order": {
+			manifest: "0002_two.sql\n0001_one.sql\n",
+			err:      `migrations.list lists "0001_one.sql" after "0002_two.sql", list them in version order`,
+		},
+		"listed twice": {
+			manifest: "0001_one.sql\n0001_one.sql\n0002_two.sql\n",
+			err:      `migrations.list lists "0001_one.sql" more than once`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"m/migrations.list": {Data: []byte(c.manifest)},
+				"m/0001_one.sql":    {Data: []byte("SELECT 1")},
+				"m/0002_two.sql":    {Data: []byte("SELECT 2")},
+			}
+			_, err := migration.FromFS(fsys, "m")
+			require.EqualError(t, err, c.err)
+		})
+	}
+}
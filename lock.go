@@ -0,0 +1,61 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultLockTimeout is how long operations that always lock, such as Redo,
+// wait for the lock when WithLock doesn't say.
+const defaultLockTimeout = time.Minute
+
+// lockName identifies the advisory lock for this database and tracking table.
+// MySQL limits lock names to 64 characters, so long names are hashed.
+func (m *Migrator) lockName() string {
+	name := fmt.Sprintf("migration:%s.%s", m.src.DBName, m.tableName)
+	if len(name) > 64 {
+		name = fmt.Sprintf("migration:%x", sha1.Sum([]byte(name)))
+	}
+	return name
+}
+
+// acquireLock takes the advisory lock on a dedicated connection, since
+// GET_LOCK is held by the session that took it. The returned func releases
+// the lock and the connection.
+func (m *Migrator) acquireLock(ctx context.Context, db *sql.DB) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open connection for lock")
+	}
+
+	timeout := -1
+	if m.lockTimeout >= 0 {
+		timeout = int(math.Ceil(m.lockTimeout.Seconds()))
+	}
+
+	name := m.lockName()
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, timeout).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrapf(err, "failed acquiring lock %q", name)
+	}
+
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return nil, errors.Errorf("timed out after %s waiting for lock %q", m.lockTimeout, name)
+	}
+
+	return func() {
+		// the caller's ctx may already be cancelled, the lock still needs releasing
+		conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", name)
+		conn.Close()
+	}, nil
+}
@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+)
+
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Log is the logger used when no WithLogger option is given.
+//
+// Deprecated: pass WithLogger instead, changing Log affects every caller in
+// the process and isn't safe to do while migrations are running.
+var Log Logger = log.New(os.Stdout, "", log.LstdFlags)
+
+// NopLogger discards everything logged to it.
+type NopLogger struct{}
+
+func (NopLogger) Printf(format string, v ...interface{}) {}
+
+// WithLogger sends everything the Migrator logs to logger instead of Log.
+func WithLogger(logger Logger) Option {
+	return func(m *Migrator) {
+		m.logger = logger
+	}
+}
+
+// WithSlog logs through a *slog.Logger with levels and structured attributes
+// such as the migration version and duration. It takes precedence over
+// WithLogger.
+func WithSlog(logger *slog.Logger) Option {
+	return func(m *Migrator) {
+		m.slog = logger
+	}
+}
+
+// WithLogLevel drops log lines below level. The default, slog.LevelDebug,
+// logs everything including a "skipping migration" line for every migration
+// that has already been executed; slog.LevelInfo keeps applied migrations,
+// warnings and errors.
+func WithLogLevel(level slog.Level) Option {
+	return func(m *Migrator) {
+		m.logLevel = level
+	}
+}
+
+// log writes msg at level. Printf style loggers only receive msg, so it
+// should read on its own without the attributes.
+func (m *Migrator) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if level < m.logLevel {
+		return
+	}
+	if len(m.logPrefix) > 0 {
+		msg = "[" + m.logPrefix + "] " + msg
+	}
+
+	if m.slog != nil {
+		m.slog.LogAttrs(ctx, level, msg, attrs...)
+		return
+	}
+
+	logger := m.logger
+	if logger == nil {
+		logger = Log
+	}
+	logger.Printf("%s", msg)
+}
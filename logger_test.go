@@ -0,0 +1,168 @@
+package migration_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+type capturingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *capturingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestWithLoggerReceivesMigrationLines(t *testing.T) {
+	dbname := "loggertest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	logger := &capturingLogger{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(logger))
+	require.NoError(t, err)
+
+	require.Regexp(t, `\Aconnected to (MySQL|MariaDB) \S+ at `, logger.lines[0])
+	require.Equal(t, `db "migration_test_loggertest" doesn't exist`, logger.lines[1])
+	require.Equal(t, `created db "migration_test_loggertest"`, logger.lines[2])
+	require.Equal(t, "table _migrations doesn't exist", logger.lines[3])
+	require.Equal(t, "created _migrations table", logger.lines[4])
+	require.Regexp(t, `\Aexecuted migration 1 in \S+\z`, logger.lines[5])
+	require.Equal(t, 6, len(logger.lines))
+
+	logger = &capturingLogger{}
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(logger))
+	require.NoError(t, err)
+	require.Len(t, logger.lines, 2)
+	require.Equal(t, "skipping migration 1 as it has already been executed", logger.lines[1])
+}
+
+func TestWithSlogEmitsStructuredRecords(t *testing.T) {
+	dbname := "slogtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithSlog(logger))
+	require.NoError(t, err)
+
+	executed := findRecord(t, &buf, "executed migration 1 in ")
+	require.Equal(t, "INFO", executed["level"])
+	require.Equal(t, float64(1), executed["version"])
+	require.Equal(t, "applied", executed["status"])
+	require.Equal(t, "migration_test_slogtest", executed["db"])
+	require.Contains(t, executed, "duration")
+
+	buf.Reset()
+	migrations = append(migrations, &migration.Definition{
+		ID: 2,
+		Up: `NOT VALID SQL`,
+	})
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithSlog(logger))
+	require.Error(t, err)
+
+	skipped := findRecord(t, &buf, "skipping migration 1 ")
+	require.Equal(t, "DEBUG", skipped["level"])
+	require.Equal(t, "skipped", skipped["status"])
+
+	failed := findRecord(t, &buf, "failed executing migration 2")
+	require.Equal(t, "ERROR", failed["level"])
+	require.Equal(t, float64(2), failed["version"])
+}
+
+func findRecord(t *testing.T, buf *bytes.Buffer, prefix string) map[string]interface{} {
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		record := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if msg, _ := record["msg"].(string); strings.HasPrefix(msg, prefix) {
+			return record
+		}
+	}
+	t.Fatalf("no log record starting with %q in:\n%s", prefix, buf.String())
+	return nil
+}
+
+func TestWithLogLevelSuppressesSkippedMigrations(t *testing.T) {
+	dbname := "logleveltest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 2,
+		Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+	})
+
+	logger := &capturingLogger{}
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLogger(logger),
+		migration.WithLogLevel(slog.LevelInfo),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(logger.lines))
+	require.Regexp(t, `\Aconnected to (MySQL|MariaDB) \S+ at `, logger.lines[0])
+	require.Regexp(t, `\Aexecuted migration 2 in \S+\z`, logger.lines[1])
+}
+
+func TestNopLoggerProducesNoOutput(t *testing.T) {
+	dbname := "noploggertest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	global := &capturingLogger{}
+	previous := migration.Log
+	migration.Log = global
+	defer func() { migration.Log = previous }()
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	require.Empty(t, global.lines)
+}
@@ -0,0 +1,91 @@
+package migration
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// manifestFile pins which migrations FromFS loads from a directory. It lists
+// one file name per line in version order, ignoring blank lines and lines
+// starting with #. Down files don't need listing.
+const manifestFile = "migrations.list"
+
+// readManifest reads the files listed in dir's manifest, or nil when it
+// doesn't have one.
+func readManifest(fsys fs.FS, dir string) ([]string, error) {
+	contents, err := fs.ReadFile(fsys, path.Join(dir, manifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading %q", manifestFile)
+	}
+
+	listed := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		listed = append(listed, line)
+	}
+
+	return listed, scanner.Err()
+}
+
+// applyManifest narrows names, the .sql files in a directory, to those
+// listed and the down files that go with them.
+func (l *loader) applyManifest(names []string, listed []string) ([]string, error) {
+	present := map[string]bool{}
+	for _, name := range names {
+		present[name] = true
+	}
+
+	included := map[string]bool{}
+	previous := migrationFile{}
+	for _, name := range listed {
+		if included[name] {
+			return nil, errors.Errorf("%s lists %q more than once", manifestFile, name)
+		}
+		if !present[name] {
+			return nil, errors.Errorf("%s lists %q which doesn't exist", manifestFile, name)
+		}
+
+		file, err := parseMigrationFileName(name)
+		if err != nil {
+			return nil, err
+		}
+		if file.direction == "down" {
+			return nil, errors.Errorf("%s lists down file %q, only list up files", manifestFile, name)
+		}
+		if len(previous.file) > 0 && file.version <= previous.version {
+			return nil, errors.Errorf("%s lists %q after %q, list them in version order", manifestFile, name, previous.file)
+		}
+		previous = file
+
+		included[name] = true
+		if file.direction == "up" {
+			included[strings.TrimSuffix(name, ".up.sql")+".down.sql"] = true
+		}
+	}
+
+	loaded := []string{}
+	for _, name := range names {
+		switch {
+		case included[name]:
+			loaded = append(loaded, name)
+		case l.unlistedLogger != nil:
+			l.unlistedLogger.Printf("%q isn't listed in %s, skipping it", name, manifestFile)
+		default:
+			return nil, errors.Errorf("%q isn't listed in %s, add it or delete it", name, manifestFile)
+		}
+	}
+
+	return loaded, nil
+}
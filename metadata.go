@@ -0,0 +1,99 @@
+package migration
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// maxMetadataSize bounds the JSON encoded metadata stored for each migration.
+const maxMetadataSize = 4096
+
+// WithRunMetadata attaches metadata, such as a git SHA or deploy ID, to every
+// migration applied during the run. Keys in a Definition's own Metadata take
+// precedence.
+func WithRunMetadata(metadata map[string]string) Option {
+	return func(m *Migrator) {
+		m.runMetadata = make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			m.runMetadata[k] = v
+		}
+	}
+}
+
+func (m *Migrator) migrationMetadata(migration Migration) map[string]string {
+	metadata := map[string]string{}
+	for k, v := range m.runMetadata {
+		metadata[k] = v
+	}
+	if definition, ok := migration.(*Definition); ok {
+		for k, v := range definition.Metadata {
+			metadata[k] = v
+		}
+	}
+	if _, ok := m.overrides[migration.Version()]; ok {
+		metadata["override"] = "true"
+	}
+	return metadata
+}
+
+// validateMetadata checks metadata up front so an oversized or malformed
+// entry fails the run before anything executes rather than on INSERT.
+func (m *Migrator) validateMetadata(migrations []Migration) error {
+	if err := validateMetadataKeys(m.runMetadata); err != nil {
+		return errors.Wrap(err, "invalid run metadata")
+	}
+
+	for _, migration := range migrations {
+		if definition, ok := migration.(*Definition); ok {
+			if err := validateMetadataKeys(definition.Metadata); err != nil {
+				return errors.Wrapf(err, "invalid metadata for migration %d", migration.Version())
+			}
+		}
+
+		encoded, err := encodeMetadata(m.migrationMetadata(migration))
+		if err != nil {
+			return errors.Wrapf(err, "invalid metadata for migration %d", migration.Version())
+		}
+
+		if len(encoded.String) > maxMetadataSize {
+			return errors.Errorf(
+				"metadata for migration %d is %d bytes, the limit is %d",
+				migration.Version(), len(encoded.String), maxMetadataSize,
+			)
+		}
+	}
+
+	return nil
+}
+
+func validateMetadataKeys(metadata map[string]string) error {
+	for key := range metadata {
+		if len(key) == 0 {
+			return errors.New("metadata keys must not be empty")
+		}
+		if strings.IndexFunc(key, unicode.IsControl) >= 0 {
+			return errors.Errorf("metadata key %q contains control characters", key)
+		}
+	}
+	return nil
+}
+
+func encodeMetadata(metadata map[string]string) (sql.NullString, error) {
+	if len(metadata) == 0 {
+		return sql.NullString{}, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(metadata); err != nil {
+		return sql.NullString{}, errors.Wrap(err, "unable to encode metadata")
+	}
+
+	return sql.NullString{String: strings.TrimSuffix(buf.String(), "\n"), Valid: true}, nil
+}
@@ -0,0 +1,155 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoresMetadata(t *testing.T) {
+	dbname := "metadatatest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/metadatatest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Metadata: map[string]string{
+				"ticket":  "OPS-123",
+				"git_sha": "overridden",
+			},
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithRunMetadata(map[string]string{"git_sha": "abc123", "note": `"quoted" \ <é>`}),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t,
+		map[string]string{"ticket": "OPS-123", "git_sha": "overridden", "note": `"quoted" \ <é>`},
+		queryMetadata(fullDSN(dbname), 1),
+	)
+	require.Equal(t,
+		map[string]string{"git_sha": "abc123", "note": `"quoted" \ <é>`},
+		queryMetadata(fullDSN(dbname), 2),
+	)
+
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir)
+	require.NoError(t, err)
+
+	trackedMigrations, err := ioutil.ReadFile(dir + "/_migrations.sql")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(trackedMigrations), "INSERT INTO `_migrations` (id, created_at, applied_by, metadata, duration_ms) VALUES\n"))
+
+	dropDB(dbname)
+
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir)
+	require.NoError(t, err)
+
+	require.Equal(t,
+		map[string]string{"git_sha": "abc123", "note": `"quoted" \ <é>`},
+		queryMetadata(fullDSN(dbname), 2),
+	)
+}
+
+func TestRejectsInvalidMetadataBeforeRunning(t *testing.T) {
+	dbname := "invalidmetadatatest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID:       2,
+			Up:       `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+			Metadata: map[string]string{"bad\nkey": "value"},
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.EqualError(t, err, `invalid metadata for migration 2: metadata key "bad\nkey" contains control characters`)
+	require.Equal(t, 0, len(queryVersions(fullDSN(dbname))))
+
+	migrations[1].(*migration.Definition).Metadata = map[string]string{"big": strings.Repeat("x", 5000)}
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.EqualError(t, err, "metadata for migration 2 is 5010 bytes, the limit is 4096")
+	require.Equal(t, 0, len(queryVersions(fullDSN(dbname))))
+}
+
+func TestUpgradesMigrationsTableWithoutMetadata(t *testing.T) {
+	dbname := "upgrademetadatatest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_"+dbname)
+	execSQL(fullDSN(dbname), `CREATE TABLE _migrations (
+		id INT NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (id)
+	) ENGINE=InnoDB`)
+	execSQL(fullDSN(dbname), `INSERT INTO _migrations (id, created_at) VALUES (1, NOW())`)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID:       2,
+			Up:       `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+			Metadata: map[string]string{"ticket": "OPS-1"},
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+
+	require.Nil(t, queryMetadata(fullDSN(dbname), 1))
+	require.Equal(t, map[string]string{"ticket": "OPS-1"}, queryMetadata(fullDSN(dbname), 2))
+}
+
+func queryMetadata(dsn string, id int) map[string]string {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var raw sql.NullString
+	if err := conn.QueryRow("SELECT metadata FROM _migrations WHERE id = ?", id).Scan(&raw); err != nil {
+		panic(err)
+	}
+
+	if !raw.Valid {
+		return nil
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(raw.String), &metadata); err != nil {
+		panic(err)
+	}
+	return metadata
+}
@@ -0,0 +1,21 @@
+package migration
+
+import "time"
+
+// MetricsCollector receives measurements from Migrate, see the migrationprom
+// package for a Prometheus implementation.
+type MetricsCollector interface {
+	// ObserveMigration is called for each migration executed, with the error
+	// it failed with if any.
+	ObserveMigration(version int, duration time.Duration, err error)
+	// ObserveRun is called at the end of each Migrate call with the number of
+	// migrations applied and the error the run failed with if any.
+	ObserveRun(applied int, duration time.Duration, err error)
+}
+
+// WithMetrics reports each migration and run to collector.
+func WithMetrics(collector MetricsCollector) Option {
+	return func(m *Migrator) {
+		m.metrics = collector
+	}
+}
@@ -0,0 +1,66 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingCollector struct {
+	migrations []int
+	failed     []int
+	runs       []int
+	runErrors  int
+}
+
+func (c *recordingCollector) ObserveMigration(version int, duration time.Duration, err error) {
+	if err != nil {
+		c.failed = append(c.failed, version)
+		return
+	}
+	c.migrations = append(c.migrations, version)
+}
+
+func (c *recordingCollector) ObserveRun(applied int, duration time.Duration, err error) {
+	c.runs = append(c.runs, applied)
+	if err != nil {
+		c.runErrors++
+	}
+}
+
+func TestWithMetricsObservesMigrationsAndRuns(t *testing.T) {
+	dbname := "metricstest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	collector := &recordingCollector{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithMetrics(collector))
+	require.NoError(t, err)
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 3,
+		Up: `THIS IS NOT SQL`,
+	})
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithMetrics(collector))
+	require.Error(t, err)
+
+	require.Equal(t, []int{1, 2}, collector.migrations)
+	require.Equal(t, []int{3}, collector.failed)
+	require.Equal(t, []int{2, 0}, collector.runs)
+	require.Equal(t, 1, collector.runErrors)
+}
@@ -5,20 +5,18 @@ import (
 	"database/sql"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
 )
 
-type Logger interface {
-	Printf(format string, v ...interface{})
-}
-
-var Log Logger = log.New(os.Stdout, "", log.LstdFlags)
-
 type Migration interface {
 	Version() int
 	Migrate(ctx context.Context, conn *sql.DB) error
@@ -26,70 +24,458 @@ type Migration interface {
 
 type Definition struct {
 	ID int
-	Up string
+	// Name describes the migration in the logs and its history row, e.g.
+	// add_index_on_orders_email.
+	Name string
+	Up   string
+	// UpStatements is executed in order in place of Up, for migrations made
+	// up of several steps. Only one of the two can be set.
+	UpStatements []string
+	// UpFile is a file of statements executed in place of Up, for migrations
+	// too large to keep in memory. It's only read when the migration is
+	// pending, and its statements are executed as they're read.
+	UpFile string
+	// Down undoes Up, for Redo. Migrate never executes it.
+	Down string
+	// DownFile is a file read in place of Down.
+	DownFile string
+	// Tags limits the migration to runs WithTags including one of them, e.g.
+	// staging. Untagged migrations always run.
+	Tags []string
+	// DependsOn lists the versions that have to be applied before this one,
+	// whatever their version, so Migrate fails rather than executing it
+	// first.
+	DependsOn []int
+	// Destructive marks Up as meant to lose data, e.g. with DROP TABLE, which
+	// Migrate otherwise refuses to execute, see WithAllowDestructive.
+	Destructive bool
+	// Strategy executes Up's statements in place of executing them over the
+	// migration's connection, e.g. OnlineSchemaChange.
+	Strategy ExecutionStrategy
+	// SkipSizeCheck exempts the migration from WithLargeTableWarning and
+	// WithMaxTableRows, for large tables it's known to be safe to alter.
+	SkipSizeCheck bool
+	// AllowTableCopy exempts the migration from WithEnforceOnlineDDL, for
+	// ALTERs where copying or locking the table is acceptable.
+	AllowTableCopy bool
+	// Idempotent marks Up as safe to execute again after failing part way,
+	// see WithRetry.
+	Idempotent bool
+	// Timeout limits how long Up can run for, in place of
+	// WithMigrationTimeout.
+	Timeout time.Duration
+	// ExpectedDuration is roughly how long Up takes, e.g. as measured against
+	// a copy of production, for Plan to report. It isn't enforced.
+	ExpectedDuration time.Duration
+	// Metadata is stored alongside the migration's history row, e.g. a ticket
+	// or change request number.
+	Metadata map[string]string
 }
 
 func (s *Definition) Version() int {
 	return s.ID
 }
 
+// Migrate executes Up one statement at a time, so it can hold several
+// separated by semicolons without multiStatements being set on the DSN.
 func (s *Definition) Migrate(ctx context.Context, conn *sql.DB) error {
-	if _, err := conn.ExecContext(ctx, s.Up); err != nil {
-		return err
+	if len(s.UpFile) > 0 {
+		return execFile(ctx, conn, s.UpFile)
+	}
+	return execStatements(ctx, conn, s.statements())
+}
+
+func (s *Definition) statements() []string {
+	if len(s.UpStatements) > 0 {
+		return s.UpStatements
+	}
+	return dialect.SplitStatements(s.Up)
+}
+
+// validate checks that the Definition sets its SQL in one way only, and that
+// its statements and files can be executed, before anything runs.
+func (s *Definition) validate() error {
+	switch {
+	case len(s.Up) > 0 && len(s.UpStatements) > 0:
+		return errors.New("both Up and UpStatements are set")
+	case len(s.UpFile) > 0 && len(s.Up)+len(s.UpStatements) > 0:
+		return errors.New("UpFile is set along with Up or UpStatements")
+	case len(s.DownFile) > 0 && len(s.Down) > 0:
+		return errors.New("both Down and DownFile are set")
+	}
+
+	for i, statement := range s.UpStatements {
+		if len(strings.TrimSpace(statement)) == 0 {
+			return errors.Errorf("statement %d of %d is empty", i+1, len(s.UpStatements))
+		}
+	}
+	for _, file := range []string{s.UpFile, s.DownFile} {
+		if len(file) == 0 {
+			continue
+		}
+		if err := checkStatementFile(file); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func MustMigrate(ctx context.Context, dsn string, migrations []Migration) {
-	if err := Migrate(ctx, dsn, migrations); err != nil {
+// execStatements executes statements in order, saying which failed when
+// there's more than one.
+func execStatements(ctx context.Context, conn *sql.DB, statements []string) error {
+	rewritten := make([]string, len(statements))
+	for i, statement := range statements {
+		var err error
+		if rewritten[i], err = rewriteStatement(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	for i, statement := range rewritten {
+		if err := execStatement(ctx, conn, statement); err != nil {
+			if len(statements) == 1 {
+				return err
+			}
+			return errors.Wrapf(err, "statement %d of %d failed (%s)", i+1, len(statements), dialect.Snippet(statement, 80))
+		}
+	}
+	return nil
+}
+
+// MustMigrate is Migrate, panicking with the error it returns. The panic
+// happens once the run is over and its lock and connections are released, and
+// the value is the error itself so recover sites can still inspect it with
+// errors.As.
+func MustMigrate(ctx context.Context, dsn string, migrations []Migration, opts ...Option) {
+	if err := Migrate(ctx, dsn, migrations, opts...); err != nil {
 		panic(err)
 	}
 }
 
-func Migrate(ctx context.Context, dsn string, migrations []Migration) error {
-	if err := createDBIfNotExists(ctx, dsn); err != nil {
+func Migrate(ctx context.Context, dsn string, migrations []Migration, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
 		return err
 	}
+	return m.Migrate(ctx, migrations)
+}
 
-	conn, err := connect(dsn)
+func MustMigrateWithReport(ctx context.Context, dsn string, migrations []Migration, opts ...Option) Report {
+	report, err := MigrateWithReport(ctx, dsn, migrations, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return report
+}
+
+// MigrateWithReport is Migrate, also returning a Report of what ran.
+func MigrateWithReport(ctx context.Context, dsn string, migrations []Migration, opts ...Option) (Report, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return Report{}, err
+	}
+	return m.MigrateWithReport(ctx, migrations)
+}
+
+func MustMigrateConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) {
+	if err := MigrateConfig(ctx, cfg, migrations, opts...); err != nil {
+		panic(err)
+	}
+}
+
+func MigrateConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
 	if err != nil {
 		return err
 	}
+	return m.Migrate(ctx, migrations)
+}
+
+func MustMigrateWithReportConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) Report {
+	report, err := MigrateWithReportConfig(ctx, cfg, migrations, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return report
+}
+
+// MigrateWithReportConfig is MigrateConfig, also returning a Report of what ran.
+func MigrateWithReportConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) (Report, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return Report{}, err
+	}
+	return m.MigrateWithReport(ctx, migrations)
+}
 
-	if err := createMigrationsTableIfNotExists(ctx, conn); err != nil {
+func MustMigrateSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) {
+	if err := MigrateSource(ctx, src, migrations, opts...); err != nil {
+		panic(err)
+	}
+}
+
+func MigrateSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
 		return err
 	}
+	return m.Migrate(ctx, migrations)
+}
+
+func MustMigrateWithReportSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) Report {
+	report, err := MigrateWithReportSource(ctx, src, migrations, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return report
+}
+
+// MigrateWithReportSource is MigrateSource, also returning a Report of what ran.
+func MigrateWithReportSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) (Report, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return Report{}, err
+	}
+	return m.MigrateWithReport(ctx, migrations)
+}
+
+func (m *Migrator) Migrate(ctx context.Context, migrations []Migration) error {
+	_, err := m.MigrateWithReport(ctx, migrations)
+	return err
+}
+
+// MigrateWithReport runs migrations like Migrate and reports which were
+// applied and skipped. When a migration fails the report still covers those
+// that ran before it.
+func (m *Migrator) MigrateWithReport(ctx context.Context, migrations []Migration) (report Report, err error) {
+	report = Report{Applied: []AppliedMigration{}, Skipped: []int{}, Filtered: []int{}, Repeated: []string{}, Seeded: []string{}}
+
+	ctx, end := m.startSpan(ctx, "migration.run", slog.String("db.name", m.src.DBName))
+	defer func() { end(err) }()
+
+	start := time.Now()
+	started := false
+	defer func() {
+		report.TotalDuration = time.Now().Sub(start)
+		if m.metrics != nil && !m.dryRun {
+			m.observe(ctx, "metrics collector", func() {
+				m.metrics.ObserveRun(len(report.Applied), report.TotalDuration, err)
+			})
+		}
+		if started {
+			m.emit(ctx, RunFinished{Applied: len(report.Applied), Err: err})
+		}
+	}()
+
+	if err := validateRepeatables(m.repeatables); err != nil {
+		return report, err
+	}
+	if err := m.checkSeedable(m.seeds); err != nil {
+		return report, err
+	}
+
+	if err := m.waitForServer(ctx); err != nil {
+		return report, err
+	}
+	if err := m.checkServerVersion(ctx); err != nil {
+		return report, err
+	}
+
+	if m.dryRun {
+		return report, m.dryRunMigrations(ctx, migrations, &report)
+	}
+
+	started, err = m.migrateWithFailoverRetry(ctx, migrations, &report)
+	return report, err
+}
+
+// migrateOnce connects and runs the pending migrations, repeatables and seeds.
+// failedOverSince is when the run began if it's trying again after a
+// failover, and zero otherwise.
+func (m *Migrator) migrateOnce(ctx context.Context, migrations []Migration, report *Report, failedOverSince time.Time) (started bool, err error) {
+	if err := m.checkWritable(ctx); err != nil {
+		return started, err
+	}
+
+	if m.createDatabase {
+		if err := m.createDBIfNotExists(ctx); err != nil {
+			return started, err
+		}
+	}
 
-	if err := runMigrations(ctx, conn, migrations); err != nil {
+	conn, err := m.openDatabase(ctx)
+	if err != nil {
+		return started, err
+	}
+	defer conn.Close()
+
+	if !m.createDatabase {
+		if err := m.checkDBExists(ctx, conn); err != nil {
+			return started, err
+		}
+	}
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return started, err
+		}
+		defer unlock()
+	}
+
+	if err := m.checkTracked(ctx, conn); err != nil {
+		return started, err
+	}
+
+	if err := m.createMigrationsTableIfNotExists(ctx, conn); err != nil {
+		return started, err
+	}
+
+	if !failedOverSince.IsZero() {
+		if err := m.clearFailedOver(ctx, conn, migrations, failedOverSince); err != nil {
+			return started, err
+		}
+	}
+
+	started, err = m.runMigrations(ctx, conn, migrations, report)
+	if err != nil {
+		return started, err
+	}
+
+	if err := m.runRepeatables(ctx, conn, report); err != nil {
+		return started, err
+	}
+
+	report.Seeded, err = m.runSeeds(ctx, conn, m.seeds)
+	if err != nil {
+		return started, err
+	}
+
+	return started, nil
+}
+
+func MustLoadSchema(ctx context.Context, dsn string, location string, opts ...Option) {
+	if err := LoadSchema(ctx, dsn, location, opts...); err != nil {
+		panic(err)
+	}
+}
+
+func LoadSchema(ctx context.Context, dsn string, location string, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
 		return err
 	}
+	return m.LoadSchema(ctx, location)
+}
 
-	return nil
+func MustLoadSchemaConfig(ctx context.Context, cfg *mysql.Config, location string, opts ...Option) {
+	if err := LoadSchemaConfig(ctx, cfg, location, opts...); err != nil {
+		panic(err)
+	}
+}
+
+func LoadSchemaConfig(ctx context.Context, cfg *mysql.Config, location string, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return err
+	}
+	return m.LoadSchema(ctx, location)
 }
 
-func MustLoadSchema(ctx context.Context, dsn string, location string) {
-	if err := LoadSchema(ctx, dsn, location); err != nil {
+func MustLoadSchemaSource(ctx context.Context, src Source, location string, opts ...Option) {
+	if err := LoadSchemaSource(ctx, src, location, opts...); err != nil {
 		panic(err)
 	}
 }
 
-func LoadSchema(ctx context.Context, dsn string, location string) error {
-	if err := createDBIfNotExists(ctx, dsn); err != nil {
+func LoadSchemaSource(ctx context.Context, src Source, location string, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
 		return err
 	}
+	return m.LoadSchema(ctx, location)
+}
 
-	conn, err := connect(dsn)
+func (m *Migrator) LoadSchema(ctx context.Context, location string) error {
+	if info, err := os.Stat(location); err == nil && !info.IsDir() {
+		dir, err := unpackSchemaFile(location)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		location = dir
+	}
+
+	dir, err := decompressSchemaDir(location)
 	if err != nil {
 		return err
 	}
+	if len(dir) > 0 {
+		defer os.RemoveAll(dir)
+		location = dir
+	}
 
-	if err := createMigrationsTableIfNotExists(ctx, conn); err != nil {
+	historyFile := filepath.Join(location, m.tableName+".sql")
+
+	// nothing is loaded without the migrations table's file, so there's
+	// nothing to check either
+	if _, err := os.Stat(historyFile); err == nil || m.schemaOnly {
+		if err := m.checkSchemaDir(location); err != nil {
+			return err
+		}
+	}
+
+	if err := m.waitForServer(ctx); err != nil {
 		return err
 	}
 
-	// load the migrations table with necessary version information
-	if _, err := os.Stat(location + "/_migrations.sql"); os.IsNotExist(err) {
-		return nil
+	if m.createDatabase {
+		if err := m.createDBIfNotExists(ctx); err != nil {
+			return err
+		}
+	}
+
+	conn, err := m.openDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if !m.createDatabase {
+		if err := m.checkDBExists(ctx, conn); err != nil {
+			return err
+		}
+	}
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	if !m.schemaOnly {
+		if err := m.createMigrationsTableIfNotExists(ctx, conn); err != nil {
+			return err
+		}
+
+		// load the migrations table with necessary version information
+		if _, err := os.Stat(historyFile); os.IsNotExist(err) {
+			return nil
+		}
+
+		// other namespaces' history is loaded into their own tracking tables
+		for _, table := range m.trackingTables()[1:] {
+			if _, err := os.Stat(filepath.Join(location, table+".sql")); os.IsNotExist(err) {
+				continue
+			}
+			if err := m.forTrackingTable(table).createMigrationsTableIfNotExists(ctx, conn); err != nil {
+				return err
+			}
+		}
+	} else if err := m.markSchemaOnly(ctx, conn); err != nil {
+		return err
 	}
 
 	files, err := ioutil.ReadDir(location)
@@ -97,136 +483,557 @@ func LoadSchema(ctx context.Context, dsn string, location string) error {
 		return errors.Wrapf(err, "failed reading dir %q", location)
 	}
 
+	names := []string{}
 	for _, file := range files {
 		name := file.Name()
+		if m.schemaOnly && m.isTrackingTable(strings.TrimSuffix(name, ".sql")) {
+			continue
+		}
+		if strings.HasSuffix(name, ".sql") {
+			names = append(names, name)
+		}
+	}
+	names, err = orderSchemaFiles(location, names)
+	if err != nil {
+		return err
+	}
 
-		if name[len(name)-4:] == ".sql" {
-			schema, err := ioutil.ReadFile(fmt.Sprintf("%s/%s", location, name))
-			if err != nil {
-				return errors.Wrapf(err, "unable to read %q", name)
-			}
-			if _, err := conn.ExecContext(ctx, string(schema)); err != nil {
-				return errors.Wrapf(err, "failed loading %q", name)
-			}
+	session, err := m.openKillable(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if m.skipBinlog {
+		restore, err := m.disableBinlog(ctx, session.db)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+
+	m.emit(ctx, SchemaLoadStarted{Files: len(names)})
+
+	loaded := []string{}
+
+	for _, name := range names {
+		m.emit(ctx, SchemaFileStarted{Name: name})
+		start := time.Now()
+		err := m.loadSchemaFile(ctx, conn, session, location, name)
+		m.emit(ctx, SchemaFileFinished{Name: name, Duration: time.Now().Sub(start), Err: err})
+		if err != nil {
+			m.emit(ctx, SchemaLoadFinished{Loaded: len(loaded), Err: err})
+			return err
 		}
+		loaded = append(loaded, name)
 	}
 
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("loaded %d schema files into db %q", len(loaded), m.src.DBName),
+		slog.String("db", m.src.DBName),
+		slog.Any("files", loaded),
+	)
+	m.emit(ctx, SchemaLoadFinished{Loaded: len(loaded)})
+
 	return nil
 }
 
-func MustDumpSchema(ctx context.Context, dsn string, location string) {
-	if err := DumpSchema(ctx, dsn, location); err != nil {
+func (m *Migrator) loadSchemaFile(ctx context.Context, conn *sql.DB, session *killableConn, location string, name string) error {
+	schema, err := ioutil.ReadFile(filepath.Join(location, name))
+	if err != nil {
+		return errors.Wrapf(err, "unable to read %q", name)
+	}
+	stopWatching := m.watch(ctx,
+		fmt.Sprintf("loading %q still running", name),
+		slog.String("db", m.src.DBName),
+		slog.String("file", name),
+	)
+	stopKilling := m.killOnDone(ctx, conn, session)
+	statements := dialect.SplitStatements(string(schema))
+	for i := range statements {
+		statements[i] = m.normalizeDefinition(statements[i])
+	}
+	err = execStatements(m.withExecution(ctx, &execution{}), session.db, statements)
+	if stopKilling() && err != nil {
+		err = &InterruptedError{File: name, Err: err}
+	}
+	stopWatching()
+	if err != nil {
+		m.log(ctx, slog.LevelError,
+			fmt.Sprintf("failed loading %q: %s", name, err),
+			slog.String("db", m.src.DBName),
+			slog.String("file", name),
+			slog.Any("error", err),
+		)
+		return errors.Wrapf(err, "failed loading %q", name)
+	}
+	return nil
+}
+
+func MustDumpSchema(ctx context.Context, dsn string, location string, opts ...Option) {
+	if err := DumpSchema(ctx, dsn, location, opts...); err != nil {
+		panic(err)
+	}
+}
+
+func DumpSchema(ctx context.Context, dsn string, location string, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return errors.Wrap(err, "unable to dump schema")
+	}
+	return m.DumpSchema(ctx, location)
+}
+
+func MustDumpSchemaConfig(ctx context.Context, cfg *mysql.Config, location string, opts ...Option) {
+	if err := DumpSchemaConfig(ctx, cfg, location, opts...); err != nil {
 		panic(err)
 	}
 }
 
-func DumpSchema(ctx context.Context, dsn string, location string) error {
-	conn, err := connect(dsn)
+func DumpSchemaConfig(ctx context.Context, cfg *mysql.Config, location string, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
 	if err != nil {
 		return errors.Wrap(err, "unable to dump schema")
 	}
+	return m.DumpSchema(ctx, location)
+}
 
-	rows, err := conn.QueryContext(ctx, "SHOW TABLES")
+func MustDumpSchemaSource(ctx context.Context, src Source, location string, opts ...Option) {
+	if err := DumpSchemaSource(ctx, src, location, opts...); err != nil {
+		panic(err)
+	}
+}
+
+func DumpSchemaSource(ctx context.Context, src Source, location string, opts ...Option) error {
+	m, err := NewSource(src, opts...)
 	if err != nil {
-		return errors.Wrap(err, "unable to show tables")
+		return errors.Wrap(err, "unable to dump schema")
 	}
-	defer rows.Close()
+	return m.DumpSchema(ctx, location)
+}
 
-	tables := []string{}
+func (m *Migrator) DumpSchema(ctx context.Context, location string) error {
+	if m.singleFile {
+		if info, err := os.Stat(location); err == nil && info.IsDir() {
+			return errors.Errorf("schema location %q is a directory", location)
+		}
+		target, err := newSchemaDir(filepath.Dir(location))
+		if err != nil {
+			return err
+		}
+		return m.dumpSchemaFile(ctx, m.compressing(target), filepath.Base(location), fmt.Sprintf("%q", location))
+	}
 
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return errors.Wrap(err, "unable to scan table name")
+	dir, err := newSchemaDir(location)
+	if err != nil {
+		return err
+	}
+	if err := m.dumpSchema(ctx, m.compressing(dir), fmt.Sprintf("%q", location)); err != nil {
+		return err
+	}
+
+	removed, err := dir.removeStale()
+	if err != nil {
+		return err
+	}
+	for _, name := range removed {
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("removed stale %q from %q", name, location),
+			slog.String("db", m.src.DBName),
+			slog.String("file", name),
+		)
+	}
+	return nil
+}
+
+// dumpSchema writes the schema's files to dir, logging that it was dumped to
+// destination.
+func (m *Migrator) dumpSchema(ctx context.Context, dir SchemaWriter, destination string) error {
+	if err := m.waitForServer(ctx); err != nil {
+		return errors.Wrap(err, "unable to dump schema")
+	}
+
+	conn, err := m.openDatabase(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to dump schema")
+	}
+	defer conn.Close()
+
+	tables, views, err := m.schemaObjects(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if err := m.dumpTables(ctx, conn, dir, tables); err != nil {
+		return err
+	}
+
+	routines, err := m.routines(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, r := range routines {
+		if err := m.dumpRoutine(ctx, conn, dir, r); err != nil {
+			return err
 		}
+	}
 
-		if tableName != "_migrations" {
-			tables = append(tables, tableName)
+	for _, view := range views {
+		if err := m.dumpView(ctx, conn, dir, view); err != nil {
+			return err
 		}
 	}
 
-	for _, table := range tables {
-		var tableName, createStatement string
-		err := conn.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE TABLE %s", table)).Scan(&tableName, &createStatement)
-		if err != nil {
-			return errors.Wrapf(err, "failed showing create statement for table %q", table)
+	triggers, err := m.triggers(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, t := range triggers {
+		if err := m.dumpTrigger(ctx, conn, dir, t); err != nil {
+			return err
+		}
+	}
+
+	versions := 0
+	for _, table := range m.trackingTables() {
+		tracker := m.forTrackingTable(table)
+		if table != m.tableName {
+			exists, err := tracker.migrationsTableExists(ctx, conn)
+			if err != nil {
+				return errors.Wrapf(err, "failed checking if table %q exists", table)
+			}
+			if !exists {
+				continue
+			}
 		}
 
-		err = ioutil.WriteFile(fmt.Sprintf("%s/%s.sql", location, table), []byte(createStatement), 0644)
+		dumped, err := tracker.dumpHistory(ctx, conn, dir)
 		if err != nil {
-			return errors.Wrapf(err, "failed writing out create table statement for table %q", table)
+			return err
 		}
+		versions += dumped
 	}
 
-	rowsVersions, err := conn.QueryContext(ctx, "SELECT id, created_at FROM _migrations ORDER BY id ASC")
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("dumped %d tables, %d views, %d triggers and %d routines from db %q to %s", len(tables), len(views), len(triggers), len(routines), m.src.DBName, destination),
+		slog.String("db", m.src.DBName),
+		slog.Int("tables", len(tables)),
+		slog.Int("routines", len(routines)),
+		slog.Int("views", len(views)),
+		slog.Int("triggers", len(triggers)),
+		slog.Int("versions", versions),
+	)
+
+	return nil
+}
+
+// dumpHistory writes the tracking table's rows to dir as INSERTs, returning
+// how many there were.
+func (m *Migrator) dumpHistory(ctx context.Context, conn *sql.DB, dir SchemaWriter) (int, error) {
+	history, err := m.readHistory(ctx, conn)
 	if err != nil {
-		return errors.Wrap(err, "unable to select from _migrations table")
+		return 0, err
+	}
+
+	// metadata is only dumped when it's in use, so dumps from databases that
+	// never record any stay the same
+	withMetadata, withName, withAppliedBy, withDirty, withDuration := false, false, false, false, false
+	for _, row := range history {
+		withMetadata = withMetadata || row.Metadata.Valid
+		withName = withName || len(row.Name) > 0
+		withAppliedBy = withAppliedBy || len(row.AppliedBy) > 0
+		withDirty = withDirty || row.Dirty
+		withDuration = withDuration || row.Duration.Valid
 	}
-	defer rowsVersions.Close()
 
 	versions := ""
-	for rowsVersions.Next() {
-		var id int
-		var createdAt time.Time
-		if err := rowsVersions.Scan(&id, &createdAt); err != nil {
-			return errors.Wrap(err, "unable to scan _migrations")
+	for _, row := range history {
+		createdAt := placeholderCreatedAt
+		if !row.CreatedAt.IsZero() {
+			createdAt = row.CreatedAt.Format(createdAtWriteFormat)
+		}
+
+		values := fmt.Sprintf("%d, %q", row.ID, createdAt)
+		if withName {
+			values = values + ", " + dialect.QuoteString(row.Name)
+		}
+		if withAppliedBy {
+			values = values + ", " + dialect.QuoteString(row.AppliedBy)
+		}
+		if withMetadata {
+			metadata := "NULL"
+			if row.Metadata.Valid {
+				metadata = dialect.QuoteString(row.Metadata.String)
+			}
+			values = values + ", " + metadata
+		}
+		if withDirty {
+			dirty := "0"
+			if row.Dirty {
+				dirty = "1"
+			}
+			values = values + ", " + dirty
+		}
+		if withDuration {
+			duration := "NULL"
+			if row.Duration.Valid {
+				duration = strconv.FormatInt(row.Duration.Int64, 10)
+			}
+			values = values + ", " + duration
 		}
 
-		versions = versions + fmt.Sprintf("(%d, %q),\n", id, createdAt.Format("2006-01-02 15:04:05"))
+		versions = versions + fmt.Sprintf("(%s),\n", values)
 	}
 
 	if len(versions) > 0 {
-		migrations := fmt.Sprintf("INSERT INTO _migrations (id, created_at) VALUES\n%s", versions[:len(versions)-2])
-		if err := ioutil.WriteFile(fmt.Sprintf("%s/_migrations.sql", location), []byte(migrations), 0644); err != nil {
-			return errors.Wrap(err, "failed writing out create table statement for _migrations")
+		columns := "id, created_at"
+		if withName {
+			columns = columns + ", name"
+		}
+		if withAppliedBy {
+			columns = columns + ", applied_by"
+		}
+		if withMetadata {
+			columns = columns + ", metadata"
+		}
+		if withDirty {
+			columns = columns + ", dirty"
+		}
+		if withDuration {
+			columns = columns + ", duration_ms"
+		}
+
+		migrations := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n%s", dialect.QuoteIdentifier(m.tableName), columns, versions[:len(versions)-2])
+		if err := dir.WriteFile(m.tableName+".sql", []byte(migrations)); err != nil {
+			return 0, errors.Wrapf(err, "failed writing out create table statement for %s", m.tableName)
 		}
 	}
 
-	return nil
+	return len(history), nil
 }
 
-func runMigrations(ctx context.Context, conn *sql.DB, migrations []Migration) error {
+// runMigrations executes the pending migrations, reporting whether it sent
+// RunStarted so that the caller sends RunFinished.
+func (m *Migrator) runMigrations(ctx context.Context, conn *sql.DB, migrations []Migration, report *Report) (started bool, err error) {
+	migrations = sortMigrations(migrations)
 	if err := validateMigrations(migrations); err != nil {
-		return err
+		return started, err
 	}
 
-	for _, migration := range migrations {
-		alreadyExecuted, err := migrationAlreadyExecuted(ctx, conn, migration.Version())
-		if err != nil {
-			return err
+	recorded, err := m.readApplied(ctx, conn)
+	if err != nil {
+		return started, err
+	}
+	applied := make(map[int]bool, len(recorded))
+	for version := range recorded {
+		applied[version] = true
+	}
+
+	migrations, err = m.applyOverrides(ctx, migrations, func(version int) (bool, error) {
+		return applied[version], nil
+	})
+	if err != nil {
+		return started, err
+	}
+
+	if err := m.validateMetadata(migrations); err != nil {
+		return started, err
+	}
+
+	if err := m.validateStatements(migrations); err != nil {
+		return started, err
+	}
+
+	if err := m.verifyChecksums(ctx, conn, migrations, recorded); err != nil {
+		return started, err
+	}
+
+	all := migrations
+	migrations, filtered := m.filterByTags(migrations, applied)
+	for _, version := range filtered {
+		report.Filtered = append(report.Filtered, version)
+		m.log(ctx, slog.LevelDebug,
+			fmt.Sprintf("skipping migration %d as none of its tags are enabled", version),
+			slog.Int("version", version),
+			slog.String("db", m.src.DBName),
+			slog.String("status", "filtered"),
+		)
+	}
+
+	if err := m.checkOrder(migrations, applied); err != nil {
+		return started, err
+	}
+	if err := m.checkContiguous(all, applied); err != nil {
+		return started, err
+	}
+	if err := checkDependencies(migrations, applied); err != nil {
+		return started, err
+	}
+	if err := m.checkPendingStatements(ctx, migrations, applied); err != nil {
+		return started, err
+	}
+	if err := m.checkTableSizes(ctx, conn, migrations, applied); err != nil {
+		return started, err
+	}
+
+	if m.events != nil {
+		pending := 0
+		for _, migration := range migrations {
+			if !applied[migration.Version()] {
+				pending++
+			}
 		}
+		m.emit(ctx, RunStarted{Pending: pending})
+		started = true
+	}
 
-		if !alreadyExecuted {
-			start := time.Now()
-			err := migration.Migrate(ctx, conn)
-			if err != nil {
-				return errors.Wrapf(err, "failed executing migration %d", migration.Version())
+	binlog := m.newBinlogTracker(conn)
+
+	for _, migration := range migrations {
+		if !applied[migration.Version()] {
+			if err := m.runBeforeHooks(ctx, migration); err != nil {
+				return started, err
 			}
-			timeTaken := time.Now().Sub(start)
-			if err := markMigrationSuccessful(ctx, conn, migration.Version()); err != nil {
-				return err
+			m.emit(ctx, MigrationStarted{Version: migration.Version()})
+
+			applied, err := m.executeMigration(ctx, conn, migration, binlog)
+			timeTaken := applied.Duration
+			if err != nil {
+				m.migrationFinished(ctx, migration, timeTaken, err)
+				return started, err
 			}
-			log.Printf("executed migration %d in %s", migration.Version(), timeTaken)
+			report.add(applied)
+			m.log(ctx, slog.LevelInfo,
+				fmt.Sprintf("executed migration %s in %s", describeMigration(migration), timeTaken),
+				slog.Int("version", migration.Version()),
+				slog.String("name", migrationName(migration)),
+				slog.String("db", m.src.DBName),
+				slog.String("status", "applied"),
+				slog.Duration("duration", timeTaken),
+			)
+			m.migrationFinished(ctx, migration, timeTaken, nil)
 		} else {
-			log.Printf("skipping migration %d as it has already been executed", migration.Version())
+			report.Skipped = append(report.Skipped, migration.Version())
+			m.log(ctx, slog.LevelDebug,
+				fmt.Sprintf("skipping migration %d as it has already been executed", migration.Version()),
+				slog.Int("version", migration.Version()),
+				slog.String("db", m.src.DBName),
+				slog.String("status", "skipped"),
+			)
 		}
 	}
-	return nil
+	return started, nil
+}
+
+// executeMigration runs a single migration and records it as applied.
+func (m *Migrator) executeMigration(ctx context.Context, conn *sql.DB, migration Migration, binlog *binlogTracker) (applied AppliedMigration, err error) {
+	ctx, end := m.startSpan(ctx, "migration.migrate",
+		slog.Int("migration.version", migration.Version()),
+		slog.String("db.name", m.src.DBName),
+	)
+	defer func() { end(err) }()
+
+	session, err := m.openKillable(ctx)
+	if err != nil {
+		return AppliedMigration{Version: migration.Version()}, err
+	}
+	defer session.Close()
+
+	if m.skipsBinlog(migration) {
+		restore, err := m.disableBinlog(ctx, session.db)
+		if err != nil {
+			return AppliedMigration{Version: migration.Version()}, err
+		}
+		defer restore()
+	}
+
+	// a resumable migration's progress records how far it got, so it's only
+	// recorded once it has finished
+	_, resumable := migration.(*ResumableMigration)
+	if resumable {
+		if err := m.clearInterrupted(ctx, conn, migration); err != nil {
+			return AppliedMigration{Version: migration.Version()}, err
+		}
+	} else if err := m.markMigrationStarted(ctx, conn, migration); err != nil {
+		return AppliedMigration{Version: migration.Version()}, errors.Wrapf(err, "failed recording migration %d as started", migration.Version())
+	}
+
+	// measured after recording the migration as started, so the INSERT isn't
+	// counted
+	before, tracked := binlog.position(ctx)
+
+	stopWatching := m.watch(ctx,
+		fmt.Sprintf("migration %d still running", migration.Version()),
+		slog.Int("version", migration.Version()),
+		slog.String("db", m.src.DBName),
+	)
+	timeout := m.migrationTimeoutFor(migration)
+	migrateCtx, cancel := withOptionalTimeout(ctx, timeout)
+	stopKilling := m.killOnDone(migrateCtx, conn, session)
+	applied = AppliedMigration{Version: migration.Version(), StartedAt: time.Now(), Metadata: m.migrationMetadata(migration)}
+	execution := m.executionFor(migration)
+	err = m.migrateWithRetry(m.withExecution(migrateCtx, &execution), session.db, migration)
+	applied.Duration = time.Now().Sub(applied.StartedAt)
+	applied.RowsAffected = execution.rowsAffected
+	if stopKilling() && err != nil {
+		err = &InterruptedError{Version: migration.Version(), Err: err}
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(migrateCtx.Err(), context.DeadlineExceeded) {
+		err = errors.Wrapf(err, "timed out after %s", timeout)
+	}
+	cancel()
+	stopWatching()
+	if err != nil {
+		m.log(ctx, slog.LevelError,
+			fmt.Sprintf("failed executing migration %d: %s", migration.Version(), err),
+			slog.Int("version", migration.Version()),
+			slog.String("db", m.src.DBName),
+			slog.Any("error", err),
+		)
+		if !resumable {
+			m.markMigrationFailed(ctx, conn, migration, err)
+		}
+		return applied, errors.Wrapf(err, "failed executing migration %d", migration.Version())
+	}
+
+	// measured before recording the migration as finished, so the UPDATE
+	// isn't counted
+	if tracked {
+		if after, ok := binlog.position(ctx); ok {
+			applied.Binlog, _ = binlog.delta(ctx, before, after)
+		}
+	}
+
+	if resumable {
+		if err := m.recordMigration(ctx, conn, migration, m.appliedBy, false); err != nil {
+			return applied, errors.Wrapf(err, "failed recording migration %d", migration.Version())
+		}
+	}
+
+	return applied, m.markMigrationSuccessful(ctx, conn, migration, applied.Duration, applied.Binlog)
 }
 
 func validateMigrations(migrations []Migration) error {
-	versions := make([]int, len(migrations))
+	versions := make(map[int]bool, len(migrations))
+
+	for _, migration := range migrations {
+		if versions[migration.Version()] {
+			return errors.Errorf("duplicate migration version %d", migration.Version())
+		}
+		versions[migration.Version()] = true
+
+		if len(migrationName(migration)) > maxNameLength {
+			return errors.Errorf("name of migration %d is longer than %d characters", migration.Version(), maxNameLength)
+		}
 
-	for i, migration := range migrations {
-		for _, version := range versions {
-			if version == migration.Version() {
-				return errors.Errorf("duplicate migration version %d", version)
+		if definition, ok := migration.(*Definition); ok {
+			if err := definition.validate(); err != nil {
+				return errors.Wrapf(err, "invalid migration %d", migration.Version())
 			}
 		}
-		versions[i] = migration.Version()
 	}
 
-	return nil
+	return validateDependencies(migrations)
 }
 
 func oneExists(ctx context.Context, conn *sql.DB, query string, args ...interface{}) (bool, error) {
@@ -244,58 +1051,289 @@ func oneExists(ctx context.Context, conn *sql.DB, query string, args ...interfac
 	}
 }
 
-func migrationAlreadyExecuted(ctx context.Context, conn *sql.DB, version int) (bool, error) {
-	return oneExists(ctx, conn, "SELECT id FROM _migrations WHERE id = ?", version)
+// readApplied reads every applied version along with its recorded checksum,
+// in one query however many migrations there are.
+func (m *Migrator) readApplied(ctx context.Context, conn *sql.DB) (recorded map[int]sql.NullString, err error) {
+	ctx, end := m.startSpan(ctx, "migration.read_applied", slog.String("db.sql.table", m.tableName))
+	defer func() { end(err) }()
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT id, checksum, dirty FROM %s ORDER BY id", dialect.QuoteIdentifier(m.tableName)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading applied migrations from %q", m.tableName)
+	}
+	defer rows.Close()
+
+	recorded = map[int]sql.NullString{}
+	dirty := []int{}
+	for rows.Next() {
+		var version int
+		var checksum sql.NullString
+		var isDirty bool
+		if err := rows.Scan(&version, &checksum, &isDirty); err != nil {
+			return nil, errors.Wrapf(err, "failed reading applied migrations from %q", m.tableName)
+		}
+		if isDirty {
+			dirty = append(dirty, version)
+			continue
+		}
+		recorded[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed reading applied migrations from %q", m.tableName)
+	}
+
+	if err := m.checkDirty(ctx, conn, dirty); err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
+
+// markMigrationStarted records migration as dirty before it's executed, so
+// that if it fails part way through DDL that can't be rolled back the next run
+// refuses to execute it again on top of what it changed.
+func (m *Migrator) markMigrationStarted(ctx context.Context, conn *sql.DB, migration Migration) error {
+	return m.recordMigration(ctx, conn, migration, m.appliedBy, true)
 }
 
-func markMigrationSuccessful(ctx context.Context, conn *sql.DB, version int) error {
-	_, err := conn.ExecContext(ctx, "INSERT INTO _migrations (id, created_at) VALUES(?, ?)", version, time.Now())
+func (m *Migrator) markMigrationSuccessful(ctx context.Context, conn *sql.DB, migration Migration, duration time.Duration, binlog *BinlogDelta) (err error) {
+	ctx, end := m.startSpan(ctx, "migration.record_applied", slog.Int("migration.version", migration.Version()))
+	defer func() { end(err) }()
+
+	binlogDelta, err := encodeBinlogDelta(binlog)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE %s SET dirty = 0, created_at = ?, duration_ms = ?, binlog_delta = ?, error_text = NULL, failed_at = NULL WHERE id = ?", dialect.QuoteIdentifier(m.tableName)),
+		time.Now().UTC().Format(createdAtWriteFormat), duration.Milliseconds(), binlogDelta, migration.Version(),
+	)
 	return err
 }
 
-func createMigrationsTableIfNotExists(ctx context.Context, conn *sql.DB) error {
-	exists, err := migrationsTableExists(ctx, conn)
+// markMigrationFailed stores why migration failed against its dirty record,
+// so it outlives the logs of whichever instance ran it.
+func (m *Migrator) markMigrationFailed(ctx context.Context, conn *sql.DB, migration Migration, failure error) {
+	// recorded even when the migration failed because ctx was cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	_, err := conn.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE %s SET error_text = ?, failed_at = ? WHERE id = ?", dialect.QuoteIdentifier(m.tableName)),
+		truncate(failure.Error(), maxErrorTextLength), time.Now().UTC().Format(createdAtWriteFormat), migration.Version(),
+	)
+	if err != nil {
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("failed recording why migration %d failed: %s", migration.Version(), err),
+			slog.Int("version", migration.Version()),
+			slog.String("db", m.src.DBName),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// recordMigration records migration as applied without executing it.
+func (m *Migrator) recordMigration(ctx context.Context, conn *sql.DB, migration Migration, appliedBy string, dirty bool) (err error) {
+	ctx, end := m.startSpan(ctx, "migration.record_applied", slog.Int("migration.version", migration.Version()))
+	defer func() { end(err) }()
+
+	metadata, err := encodeMetadata(m.migrationMetadata(migration))
+	if err != nil {
+		return err
+	}
+
+	checksummed := migration
+	if listed, ok := m.overridden[migration.Version()]; ok {
+		checksummed = listed
+	}
+	checksum, err := migrationChecksum(checksummed)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(
+		ctx,
+		fmt.Sprintf("INSERT INTO %s (id, name, created_at, applied_by, metadata, checksum, dirty) VALUES(?, ?, ?, ?, ?, ?, ?)", dialect.QuoteIdentifier(m.tableName)),
+		// formatted here so the driver's loc setting can't shift it out of UTC
+		migration.Version(), migrationName(migration), time.Now().UTC().Format(createdAtWriteFormat), appliedBy, metadata, checksum, dirty,
+	)
+	return err
+}
+
+// trackingColumns are the columns added to the tracking table since its
+// original (id, created_at) layout. Tables created by older versions of the
+// package are upgraded to include them.
+var trackingColumns = []struct {
+	name       string
+	definition string
+}{
+	{"metadata", "JSON NULL"},
+	{"binlog_delta", "JSON NULL"},
+	{"checksum", "CHAR(64) NULL"},
+	{"name", "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{"applied_by", "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{"dirty", "TINYINT(1) NOT NULL DEFAULT 0"},
+	{"error_text", "TEXT NULL"},
+	{"failed_at", "DATETIME(6) NULL"},
+	{"duration_ms", "BIGINT NULL"},
+}
+
+// maxErrorTextLength is how much of a failed migration's error is stored.
+const maxErrorTextLength = 4096
+
+func (m *Migrator) createMigrationsTableIfNotExists(ctx context.Context, conn *sql.DB) (err error) {
+	ctx, end := m.startSpan(ctx, "migration.prepare_table", slog.String("db.sql.table", m.tableName))
+	defer func() { end(err) }()
+
+	exists, err := m.migrationsTableExists(ctx, conn)
 	if err != nil {
-		return errors.Wrapf(err, "failed checking if table %q exists", "_migrations")
+		return errors.Wrapf(err, "failed checking if table %q exists", m.tableName)
 	}
 
-	if !exists {
-		log.Printf("table _migrations doesn't exist")
-		_, err := conn.ExecContext(
-			ctx,
-			`CREATE TABLE _migrations (
+	if exists {
+		return m.upgradeMigrationsTable(ctx, conn)
+	}
+
+	m.log(ctx, slog.LevelInfo, fmt.Sprintf("table %s doesn't exist", m.tableName), slog.String("table", m.tableName))
+
+	columns := ""
+	for _, column := range trackingColumns {
+		columns = columns + fmt.Sprintf("%s %s,\n", column.name, column.definition)
+	}
+
+	_, err = conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`CREATE TABLE %s (
 				id INT NOT NULL,
-				created_at DATETIME NOT NULL,
+				created_at DATETIME(6) NOT NULL,
+				%s
 				PRIMARY KEY (id)
-			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci`,
-		)
+			) ENGINE=InnoDB DEFAULT CHARSET=%s COLLATE=%s`,
+			dialect.QuoteIdentifier(m.tableName),
+			columns,
+			m.dbCharset,
+			m.dbCollation,
+		),
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed creating table %q", m.tableName)
+	}
+	m.log(ctx, slog.LevelInfo, fmt.Sprintf("created %s table", m.tableName), slog.String("table", m.tableName))
+	return nil
+}
+
+func (m *Migrator) upgradeMigrationsTable(ctx context.Context, conn *sql.DB) error {
+	existing, err := m.migrationsTableColumns(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if err := m.upgradeCreatedAt(ctx, conn); err != nil {
+		return err
+	}
+
+	for _, column := range trackingColumns {
+		if existing[column.name] {
+			continue
+		}
+
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", dialect.QuoteIdentifier(m.tableName), column.name, column.definition))
 		if err != nil {
-			return errors.Wrapf(err, "failed creating table %q", "_migrations")
+			return errors.Wrapf(err, "failed adding column %q to table %q", column.name, m.tableName)
 		}
-		log.Printf("created _migrations table")
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("added column %s to %s table", column.name, m.tableName),
+			slog.String("table", m.tableName),
+			slog.String("column", column.name),
+		)
 	}
+
 	return nil
 }
 
-func migrationsTableExists(ctx context.Context, conn *sql.DB) (bool, error) {
-	return oneExists(ctx, conn, `SHOW TABLES LIKE "_migrations"`)
-}
+// upgradeCreatedAt gives created_at microsecond precision on tables created
+// when it was a plain DATETIME. Legacy tables can hold zero dates, which the
+// ALTER would reject under a strict sql_mode, so it runs in a lax session.
+func (m *Migrator) upgradeCreatedAt(ctx context.Context, db *sql.DB) error {
+	var precision sql.NullInt64
+	err := db.QueryRowContext(
+		ctx,
+		"SELECT DATETIME_PRECISION FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'created_at'",
+		m.tableName,
+	).Scan(&precision)
+	if err != nil {
+		return errors.Wrapf(err, "failed checking created_at column of table %q", m.tableName)
+	}
+	if precision.Int64 >= 6 {
+		return nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to open connection to upgrade created_at")
+	}
+	defer conn.Close()
+
+	// the connection goes back to the pool, so its sql_mode is put back
+	var sqlMode string
+	if err := conn.QueryRowContext(ctx, "SELECT @@SESSION.sql_mode").Scan(&sqlMode); err != nil {
+		return errors.Wrap(err, "failed reading sql_mode to upgrade created_at")
+	}
+	if _, err := conn.ExecContext(ctx, "SET SESSION sql_mode = ''"); err != nil {
+		return errors.Wrap(err, "failed relaxing sql_mode to upgrade created_at")
+	}
+	defer conn.ExecContext(context.Background(), "SET SESSION sql_mode = ?", sqlMode)
 
-func createDBIfNotExists(ctx context.Context, dsn string) error {
-	parsed, err := mysql.ParseDSN(dsn)
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s MODIFY created_at DATETIME(6) NOT NULL", dialect.QuoteIdentifier(m.tableName)))
 	if err != nil {
-		return errors.Wrap(err, "unable to parse dsn")
+		return errors.Wrapf(err, "failed upgrading created_at column of table %q", m.tableName)
 	}
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("upgraded created_at column of %s table to DATETIME(6)", m.tableName),
+		slog.String("table", m.tableName),
+	)
 
-	dbname := parsed.DBName
+	return nil
+}
+
+func (m *Migrator) migrationsTableColumns(ctx context.Context, conn *sql.DB) (map[string]bool, error) {
+	rows, err := conn.QueryContext(
+		ctx,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+		m.tableName,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed listing columns of table %q", m.tableName)
+	}
+	defer rows.Close()
 
-	if len(dbname) == 0 {
-		return errors.Errorf("dsn missing database name")
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.Wrapf(err, "failed listing columns of table %q", m.tableName)
+		}
+		columns[name] = true
 	}
 
-	parsed.DBName = ""
+	return columns, rows.Err()
+}
+
+// migrationsTableExists looks the tracking table up by its exact name, as
+// SHOW TABLES LIKE would treat its underscores as wildcards.
+func (m *Migrator) migrationsTableExists(ctx context.Context, conn *sql.DB) (bool, error) {
+	return oneExists(ctx, conn, "SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", m.tableName)
+}
+
+func (m *Migrator) createDBIfNotExists(ctx context.Context) (err error) {
+	ctx, end := m.startSpan(ctx, "migration.create_database", slog.String("db.name", m.src.DBName))
+	defer func() { end(err) }()
 
-	conn, err := connect(parsed.FormatDSN())
+	dbname := m.src.DBName
+
+	conn, err := m.src.Server(ctx)
 	if err != nil {
 		return err
 	}
@@ -307,28 +1345,55 @@ func createDBIfNotExists(ctx context.Context, dsn string) error {
 	}
 
 	if !dbExists {
-		log.Printf("db %q doesn't exist", dbname)
-		if err := createDB(ctx, conn, dbname); err != nil {
+		m.log(ctx, slog.LevelInfo, fmt.Sprintf("db %q doesn't exist", dbname), slog.String("db", dbname))
+		if m.dbCharset != defaultCharset || m.dbCollation != defaultCollation {
+			if err := m.checkDatabaseCharset(ctx, conn); err != nil {
+				return err
+			}
+		}
+		if err := createDB(ctx, conn, dbname, m.dbCharset, m.dbCollation); err != nil {
 			return errors.Wrapf(err, "failed creating db %q", dbname)
 		}
-		log.Printf("created db %q", dbname)
+		m.log(ctx, slog.LevelInfo, fmt.Sprintf("created db %q", dbname), slog.String("db", dbname))
 	}
 
 	return nil
 }
 
+// checkDBExists connects to the database, failing clearly when it doesn't
+// exist as WithCreateDatabase(false) means it won't be created. It doesn't
+// need any privileges beyond those on the database itself.
+func (m *Migrator) checkDBExists(ctx context.Context, conn *sql.DB) error {
+	err := conn.PingContext(ctx)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errBadDB {
+		return errors.Errorf("database %q does not exist and creation is disabled", m.src.DBName)
+	}
+	return errors.Wrapf(err, "failed connecting to db %q", m.src.DBName)
+}
+
+// validFileName reports whether a table's dump can be named after it without
+// the file ending up outside the schema directory.
+func validFileName(table string) bool {
+	return table != "." && table != ".." && !strings.ContainsAny(table, `/\`)
+}
+
+// dbExists looks the database up by its exact name, as SHOW DATABASES LIKE
+// would treat underscores in it as wildcards.
 func dbExists(ctx context.Context, conn *sql.DB, dbname string) (bool, error) {
-	return oneExists(ctx, conn, fmt.Sprintf(`SHOW DATABASES LIKE %q`, dbname))
+	return oneExists(ctx, conn, "SELECT 1 FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", dbname)
 }
 
-func createDB(ctx context.Context, conn *sql.DB, dbname string) error {
+func createDB(ctx context.Context, conn *sql.DB, dbname, charset, collation string) error {
 	_, err := conn.ExecContext(
 		ctx,
 		fmt.Sprintf(
 			`CREATE DATABASE %s
-			DEFAULT CHARACTER SET = utf8mb4
-			DEFAULT COLLATE = utf8mb4_unicode_520_ci`,
-			dbname,
+			DEFAULT CHARACTER SET = %s
+			DEFAULT COLLATE = %s`,
+			dialect.QuoteIdentifier(dbname),
+			charset,
+			collation,
 		),
 	)
 	if err != nil {
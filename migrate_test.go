@@ -32,6 +32,88 @@ func TestCreatesDatabaseIfNoneExists(t *testing.T) {
 	require.True(t, dbExists(dbname))
 }
 
+func TestCreatesDatabaseWhenSimilarNameExists(t *testing.T) {
+	dbname := "foo"
+	dropDB(dbname)
+	execSQL(partialDSN(), "DROP DATABASE IF EXISTS migration_testXfoo")
+	execSQL(partialDSN(), "CREATE DATABASE migration_testXfoo")
+	defer execSQL(partialDSN(), "DROP DATABASE migration_testXfoo")
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.True(t, dbExists(dbname))
+	require.True(t, tableExists(fullDSN(dbname), "blarg"))
+}
+
+func TestCreatesMigrationsTableWhenSimilarTableExists(t *testing.T) {
+	dbname := "decoytest"
+	dropDB(dbname)
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_decoytest")
+	execSQL(fullDSN(dbname), "CREATE TABLE xmigrations ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB")
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.True(t, tableExists(fullDSN(dbname), "_migrations"))
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+}
+
+// countConnections counts the server's client connections, including the
+// one it's counted over.
+func countConnections() int {
+	conn, err := sql.Open("mysql", partialDSN())
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var count int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM information_schema.PROCESSLIST").Scan(&count); err != nil {
+		panic(err)
+	}
+	return count
+}
+
+func TestClosesConnections(t *testing.T) {
+	dbname := "connectionstest"
+	dropDB(dbname)
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	before := countConnections()
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLock(time.Second), migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir))
+	dropDB(dbname)
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+	require.Error(t, migration.Migrate(context.Background(), fullDSN(dbname), []migration.Migration{
+		&migration.Definition{ID: 2, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}, migration.WithLogger(migration.NopLogger{})))
+
+	// the server drops a thread shortly after its client disconnects
+	after := countConnections()
+	for deadline := time.Now().Add(5 * time.Second); after > before && time.Now().Before(deadline); {
+		time.Sleep(100 * time.Millisecond)
+		after = countConnections()
+	}
+	require.True(t, after <= before, "%d connections left open after %d before", after, before)
+}
+
 func TestRunsMigrationSuccesfully(t *testing.T) {
 	dbname := "runmigrationtest"
 	dropDB(dbname)
@@ -123,7 +205,7 @@ func TestDumpSchema(t *testing.T) {
 	trackedMigrations, err := ioutil.ReadFile(dir + "/_migrations.sql")
 	require.NoError(t, err)
 	require.Regexp(t,
-		regexp.MustCompile(`\AINSERT INTO _migrations \(id, created_at\) VALUES\n\(1, "\d\d\d\d-\d\d-\d\d \d\d:\d\d:\d\d"\),\n\(2, "\d\d\d\d-\d\d-\d\d \d\d:\d\d:\d\d"\),\n\(3, "\d\d\d\d-\d\d-\d\d \d\d:\d\d:\d\d"\)\z`),
+		regexp.MustCompile(`\AINSERT INTO \x60_migrations\x60 \(id, created_at, applied_by, duration_ms\) VALUES\n\(1, "\d\d\d\d-\d\d-\d\d \d\d:\d\d:\d\d\.\d{6}", "[^"]+", \d+\),\n\(2, "\d\d\d\d-\d\d-\d\d \d\d:\d\d:\d\d\.\d{6}", "[^"]+", \d+\),\n\(3, "\d\d\d\d-\d\d-\d\d \d\d:\d\d:\d\d\.\d{6}", "[^"]+", \d+\)\z`),
 		string(trackedMigrations),
 	)
 
@@ -149,6 +231,38 @@ func TestDumpSchema(t *testing.T) {
 	)
 }
 
+func TestDumpSchemaReservedWordTable(t *testing.T) {
+	dbname := "dumpschematest"
+	dropDB(dbname)
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: "CREATE TABLE `order` ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB"},
+	}
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir))
+	require.FileExists(t, dir+"/order.sql")
+
+	dropDB(dbname)
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN(dbname), dir))
+	require.Contains(t, showSchema(fullDSN(dbname), "order"), "CREATE TABLE `order`")
+}
+
+func TestDumpSchemaRefusesTableNamesWithPathSeparators(t *testing.T) {
+	dbname := "dumpschematest"
+	dropDB(dbname)
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: "CREATE TABLE `../escape` ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB"},
+	}
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	err := migration.DumpSchema(context.Background(), fullDSN(dbname), dir)
+	require.EqualError(t, err, `table "../escape" can't be dumped as its name isn't a valid file name`)
+}
+
 func TestLoadSchema(t *testing.T) {
 	dbname := "loadschematest"
 	dropDB(dbname)
@@ -182,6 +296,9 @@ func TestLoadSchema(t *testing.T) {
 	dropDB(dbname)
 	require.False(t, dbExists(dbname))
 
+	// files that aren't part of the schema are ignored, however short
+	must(ioutil.WriteFile(dir+"/a", []byte("not sql"), 0644))
+
 	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir)
 	require.NoError(t, err)
 
@@ -212,8 +329,9 @@ func TestLoadSchema(t *testing.T) {
 		gralb)
 
 	migrations = append(migrations, &migration.Definition{
-		ID: 4,
-		Up: `ALTER TABLE blarg DROP COLUMN something`,
+		ID:          4,
+		Up:          `ALTER TABLE blarg DROP COLUMN something`,
+		Destructive: true,
 	})
 
 	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations)
@@ -245,6 +363,10 @@ func must(err error) {
 }
 
 func queryVersions(dsn string) []version {
+	return queryTableVersions(dsn, "_migrations")
+}
+
+func queryTableVersions(dsn string, table string) []version {
 	conn, err := sql.Open("mysql", dsn)
 	if err != nil {
 		panic(err)
@@ -253,7 +375,7 @@ func queryVersions(dsn string) []version {
 
 	var versions []version
 
-	rows, err := conn.Query("SELECT * FROM _migrations ORDER BY id ASC")
+	rows, err := conn.Query(fmt.Sprintf("SELECT id, created_at FROM %s ORDER BY id ASC", table))
 	if err != nil {
 		panic(err)
 	}
@@ -278,7 +400,7 @@ func showSchema(dsn string, table string) string {
 	defer conn.Close()
 
 	var tableName, createStatement string
-	err = conn.QueryRow(fmt.Sprintf("SHOW CREATE TABLE %s", table)).Scan(&tableName, &createStatement)
+	err = conn.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table)).Scan(&tableName, &createStatement)
 	if err != nil {
 		panic(fmt.Errorf("failed checking if table %q exists: %s", table, err))
 	}
@@ -307,7 +429,7 @@ func dropDB(db string) {
 	}
 	defer conn.Close()
 
-	if _, err := conn.Exec(fmt.Sprintf(`DROP DATABASE IF EXISTS migration_test_%s`, db)); err != nil {
+	if _, err := conn.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS `migration_test_%s`", db)); err != nil {
 		panic(err)
 	}
 }
@@ -319,7 +441,7 @@ func dbExists(db string) bool {
 	}
 	defer conn.Close()
 
-	return oneExists(conn, fmt.Sprintf(`SHOW DATABASES LIKE %q`, "migration_test_"+db))
+	return oneExists(conn, "SELECT 1 FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", "migration_test_"+db)
 }
 
 func oneExists(conn *sql.DB, query string, args ...interface{}) bool {
@@ -336,3 +458,15 @@ func oneExists(conn *sql.DB, query string, args ...interface{}) bool {
 		return true
 	}
 }
+
+func execSQL(dsn string, query string, args ...interface{}) {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(query, args...); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package migrationfake is an in-memory migration.Runner for unit testing
+// code that drives migrations, such as deploy tooling, without MySQL:
+//
+//	fake := migrationfake.New(1, 2)
+//	fake.FailAt(4, errors.New("lock wait timeout"))
+//	err := deploy(ctx, fake, migrations)
+//
+// It only tracks versions, nothing is executed.
+package migrationfake
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration"
+)
+
+// Call is a call made to the Fake, for asserting on. Versions lists the
+// versions of the migrations it was given, and Version is the one passed to
+// Redo.
+type Call struct {
+	Method   string
+	Versions []int
+	Version  int
+}
+
+// Fake tracks which versions have been applied, applying pending ones in
+// order like a Migrator, and fails at the versions given to FailAt. It's safe
+// for concurrent use.
+type Fake struct {
+	mu       sync.Mutex
+	applied  map[int]time.Time
+	failures map[int]error
+	calls    []Call
+}
+
+var _ migration.Runner = (*Fake)(nil)
+
+// New returns a Fake with versions already applied.
+func New(versions ...int) *Fake {
+	f := &Fake{
+		applied:  map[int]time.Time{},
+		failures: map[int]error{},
+	}
+	for _, version := range versions {
+		f.applied[version] = time.Now()
+	}
+	return f
+}
+
+// FailAt makes applying or redoing version fail with err, leaving it and
+// everything after it pending. A nil err clears the failure.
+func (f *Fake) FailAt(version int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.failures, version)
+		return
+	}
+	f.failures[version] = err
+}
+
+// Applied returns the applied versions in ascending order.
+func (f *Fake) Applied() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.appliedVersions()
+}
+
+// Calls returns the calls made so far, in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+func (f *Fake) Migrate(ctx context.Context, migrations []migration.Migration) error {
+	_, err := f.migrate(ctx, "Migrate", migrations)
+	return err
+}
+
+func (f *Fake) MigrateWithReport(ctx context.Context, migrations []migration.Migration) (migration.Report, error) {
+	return f.migrate(ctx, "MigrateWithReport", migrations)
+}
+
+func (f *Fake) migrate(ctx context.Context, method string, migrations []migration.Migration) (migration.Report, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sorted := sortMigrations(migrations)
+	f.record(method, sorted, 0)
+
+	start := time.Now()
+	// initialised like the Migrator's, so reports compare equal
+	report := migration.Report{Applied: []migration.AppliedMigration{}, Skipped: []int{}, Filtered: []int{}, Repeated: []string{}, Seeded: []string{}}
+	defer func() { report.TotalDuration = time.Now().Sub(start) }()
+
+	for _, m := range sorted {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		version := m.Version()
+		if _, ok := f.applied[version]; ok {
+			report.Skipped = append(report.Skipped, version)
+			continue
+		}
+		if err, ok := f.failures[version]; ok {
+			return report, errors.Wrapf(err, "failed executing migration %d", version)
+		}
+		f.applied[version] = time.Now()
+		report.Applied = append(report.Applied, migration.AppliedMigration{Version: version, StartedAt: f.applied[version]})
+	}
+	return report, nil
+}
+
+func (f *Fake) Plan(ctx context.Context, migrations []migration.Migration) ([]migration.PlannedMigration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sorted := sortMigrations(migrations)
+	f.record("Plan", sorted, 0)
+
+	pending := []migration.PlannedMigration{}
+	for _, m := range sorted {
+		if _, ok := f.applied[m.Version()]; !ok {
+			pending = append(pending, migration.PlannedMigration{Version: m.Version(), SQL: migrationSQL(m)})
+		}
+	}
+	return pending, nil
+}
+
+func (f *Fake) AppliedVersions(ctx context.Context) ([]migration.Applied, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.record("AppliedVersions", nil, 0)
+
+	applied := []migration.Applied{}
+	for _, version := range f.appliedVersions() {
+		applied = append(applied, migration.Applied{Version: version, CreatedAt: f.applied[version]})
+	}
+	return applied, nil
+}
+
+func (f *Fake) Redo(ctx context.Context, migrations []migration.Migration, version int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sorted := sortMigrations(migrations)
+	f.record("Redo", sorted, version)
+
+	if _, ok := f.applied[version]; !ok {
+		return &migration.NotRecordedError{Version: version}
+	}
+	if err, ok := f.failures[version]; ok {
+		delete(f.applied, version)
+		return errors.Wrapf(err, "failed executing migration %d", version)
+	}
+	f.applied[version] = time.Now()
+	return nil
+}
+
+func (f *Fake) record(method string, migrations []migration.Migration, version int) {
+	call := Call{Method: method, Version: version}
+	for _, m := range migrations {
+		call.Versions = append(call.Versions, m.Version())
+	}
+	f.calls = append(f.calls, call)
+}
+
+func (f *Fake) appliedVersions() []int {
+	versions := make([]int, 0, len(f.applied))
+	for version := range f.applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+func sortMigrations(migrations []migration.Migration) []migration.Migration {
+	sorted := append([]migration.Migration(nil), migrations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version() < sorted[j].Version()
+	})
+	return sorted
+}
+
+// migrationSQL is the SQL a Definition runs, like migration.Plan reports.
+func migrationSQL(m migration.Migration) string {
+	if definition, ok := m.(*migration.Definition); ok {
+		if len(definition.UpStatements) > 0 {
+			return strings.Join(definition.UpStatements, ";\n")
+		}
+		return definition.Up
+	}
+	return ""
+}
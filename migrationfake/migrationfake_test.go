@@ -0,0 +1,79 @@
+package migrationfake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/rbone/migration/migrationfake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFake(t *testing.T) {
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 3, Up: "CREATE TABLE c ( id INT NOT NULL )"},
+		&migration.Definition{ID: 1, Up: "CREATE TABLE a ( id INT NOT NULL )"},
+		&migration.Definition{ID: 2, Up: "CREATE TABLE b ( id INT NOT NULL )"},
+	}
+
+	fake := migrationfake.New(1)
+	boom := errors.New("lock wait timeout")
+	fake.FailAt(3, boom)
+
+	planned, err := fake.Plan(context.Background(), migrations)
+	require.NoError(t, err)
+	require.Equal(t, []migration.PlannedMigration{
+		{Version: 2, SQL: "CREATE TABLE b ( id INT NOT NULL )"},
+		{Version: 3, SQL: "CREATE TABLE c ( id INT NOT NULL )"},
+	}, planned)
+
+	report, err := fake.MigrateWithReport(context.Background(), migrations)
+	require.EqualError(t, err, "failed executing migration 3: lock wait timeout")
+	require.True(t, errors.Is(err, boom))
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, 2, report.Applied[0].Version)
+	require.Equal(t, []int{1}, report.Skipped)
+	require.Equal(t, []int{1, 2}, fake.Applied())
+
+	fake.FailAt(3, nil)
+	require.NoError(t, fake.Migrate(context.Background(), migrations))
+	require.Equal(t, []int{1, 2, 3}, fake.Applied())
+
+	applied, err := fake.AppliedVersions(context.Background())
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+	require.Equal(t, 3, applied[2].Version)
+
+	require.NoError(t, fake.Redo(context.Background(), migrations, 3))
+	err = fake.Redo(context.Background(), migrations, 4)
+	var notRecorded *migration.NotRecordedError
+	require.True(t, errors.As(err, &notRecorded), "got %v", err)
+
+	require.Equal(t, []migrationfake.Call{
+		{Method: "Plan", Versions: []int{1, 2, 3}},
+		{Method: "MigrateWithReport", Versions: []int{1, 2, 3}},
+		{Method: "Migrate", Versions: []int{1, 2, 3}},
+		{Method: "AppliedVersions"},
+		{Method: "Redo", Versions: []int{1, 2, 3}, Version: 3},
+		{Method: "Redo", Versions: []int{1, 2, 3}, Version: 4},
+	}, fake.Calls())
+}
+
+func TestFakeReportMatchesMigrator(t *testing.T) {
+	fake := migrationfake.New()
+
+	report, err := fake.MigrateWithReport(context.Background(), nil)
+	require.NoError(t, err)
+	report.TotalDuration = 0
+	require.Equal(t, migration.Report{Applied: []migration.AppliedMigration{}, Skipped: []int{}, Filtered: []int{}, Repeated: []string{}, Seeded: []string{}}, report)
+}
+
+func TestFakeRedoFailureLeavesVersionPending(t *testing.T) {
+	fake := migrationfake.New(1, 2)
+	fake.FailAt(2, errors.New("boom"))
+
+	err := fake.Redo(context.Background(), nil, 2)
+	require.EqualError(t, err, "failed executing migration 2: boom")
+	require.Equal(t, []int{1}, fake.Applied())
+}
@@ -0,0 +1,85 @@
+// Package migrationprom reports migration runs to Prometheus. It lives apart
+// from the migration package so only users who want Prometheus depend on it:
+//
+//	collector := migrationprom.NewCollector()
+//	prometheus.MustRegister(collector)
+//	migration.MustMigrate(ctx, dsn, migrations, migration.WithMetrics(collector))
+package migrationprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rbone/migration"
+)
+
+// Collector implements both migration.MetricsCollector and
+// prometheus.Collector, exposing:
+//
+//	migration_applied_total          migrations applied successfully
+//	migration_duration_seconds       time taken by each migration, by outcome
+//	migration_runs_total             Migrate calls
+//	migration_run_errors_total       Migrate calls that failed
+type Collector struct {
+	applied   prometheus.Counter
+	duration  *prometheus.HistogramVec
+	runs      prometheus.Counter
+	runErrors prometheus.Counter
+}
+
+var _ migration.MetricsCollector = (*Collector)(nil)
+var _ prometheus.Collector = (*Collector)(nil)
+
+func NewCollector() *Collector {
+	return &Collector{
+		applied: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "migration_applied_total",
+			Help: "Number of migrations applied successfully.",
+		}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "migration_duration_seconds",
+			Help: "Time taken executing each migration.",
+			// migrations range from instant to hours long table rebuilds
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, []string{"outcome"}),
+		runs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "migration_runs_total",
+			Help: "Number of times migrations were run.",
+		}),
+		runErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "migration_run_errors_total",
+			Help: "Number of migration runs that failed.",
+		}),
+	}
+}
+
+func (c *Collector) ObserveMigration(version int, duration time.Duration, err error) {
+	outcome := "applied"
+	if err != nil {
+		outcome = "failed"
+	} else {
+		c.applied.Inc()
+	}
+	c.duration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+func (c *Collector) ObserveRun(applied int, duration time.Duration, err error) {
+	c.runs.Inc()
+	if err != nil {
+		c.runErrors.Inc()
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.applied.Describe(ch)
+	c.duration.Describe(ch)
+	c.runs.Describe(ch)
+	c.runErrors.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.applied.Collect(ch)
+	c.duration.Collect(ch)
+	c.runs.Collect(ch)
+	c.runErrors.Collect(ch)
+}
@@ -0,0 +1,47 @@
+package migrationprom_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rbone/migration/migrationprom"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	collector := migrationprom.NewCollector()
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	collector.ObserveMigration(1, time.Second, nil)
+	collector.ObserveMigration(2, 2*time.Second, nil)
+	collector.ObserveMigration(3, time.Second, errors.New("boom"))
+	collector.ObserveRun(2, 4*time.Second, errors.New("boom"))
+	collector.ObserveRun(0, time.Second, nil)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetCounter() != nil:
+				values[family.GetName()] = metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				values[family.GetName()+"{"+metric.GetLabel()[0].GetValue()+"}"] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	require.Equal(t, map[string]float64{
+		"migration_applied_total":             2,
+		"migration_duration_seconds{applied}": 2,
+		"migration_duration_seconds{failed}":  1,
+		"migration_runs_total":                2,
+		"migration_run_errors_total":          1,
+	}, values)
+}
@@ -0,0 +1,204 @@
+package migration
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+const defaultTableName = "_migrations"
+
+var tableNamePattern = regexp.MustCompile(`\A[A-Za-z0-9_$]{1,64}\z`)
+
+// maxTableNameLength is MySQL's limit on identifiers.
+const maxTableNameLength = 64
+
+// charsetPattern matches character set and collation names, which are
+// interpolated into CREATE DATABASE and CREATE TABLE.
+var charsetPattern = regexp.MustCompile(`\A[A-Za-z0-9_]{1,64}\z`)
+
+// Migrator runs migrations and dumps or loads schemas for a single database,
+// configured by the options it was created with.
+type Migrator struct {
+	src               Source
+	logger            Logger
+	slog              *slog.Logger
+	logLevel          slog.Level
+	tableName         string
+	lock              bool
+	lockTimeout       time.Duration
+	createDatabase    bool
+	strictDates       bool
+	collation         string
+	runMetadata       map[string]string
+	overrides         map[int]Migration
+	overridden        map[int]Migration
+	beforeHooks       []BeforeHook
+	afterHooks        []AfterHook
+	events            chan<- Event
+	schemaOnly        bool
+	metrics           MetricsCollector
+	tracer            Tracer
+	progressInterval  time.Duration
+	dryRun            bool
+	allowOutOfOrder   bool
+	requireContiguous bool
+	appliedBy         string
+	confirmReset      bool
+	forceRedo         bool
+	retryAttempts     int
+	retryBackoff      time.Duration
+	connectAttempts   int
+	connectBackoff    time.Duration
+	connectTimeout    time.Duration
+	failoverAttempts  int
+	failoverBackoff   time.Duration
+	minServerVersions []string
+	sessionVariables  map[string]string
+	skipBinlog        bool
+	binlogSkipped     map[int]bool
+	keepAutoIncrement bool
+	stripDefiner      bool
+	singleFile        bool
+	gzip              bool
+	dumpConcurrency   int
+	migrationTimeout  time.Duration
+	singleStatements  bool
+	repeatables       []*Repeatable
+	seeds             []*Seed
+	environment       string
+	productionSeeds   bool
+	rewriter          func(version int, sql string) (string, error)
+	validators        []func(version int, sql string) error
+	allowDestructive  bool
+	strategies        map[int]ExecutionStrategy
+	largeTableRows    int64
+	maxTableRows      int64
+	skipPlanSizes     bool
+	recordedDurations map[int]time.Duration
+	durationsLocation string
+	verifyTimeout     time.Duration
+	enforceOnlineDDL  bool
+	tags              []string
+	concurrency       int
+	failFast          bool
+	logPrefix         string
+	expectedDatabases []string
+	sharedTables      []string
+	dbCharset         string
+	dbCollation       string
+}
+
+type Option func(*Migrator)
+
+// WithTableName changes the table used to track applied migrations from
+// _migrations.
+func WithTableName(name string) Option {
+	return func(m *Migrator) {
+		m.tableName = name
+	}
+}
+
+// WithLock takes a named advisory lock (GET_LOCK) for the duration of Migrate
+// and LoadSchema, so concurrent callers run one at a time. A negative timeout
+// waits indefinitely.
+func WithLock(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lock = true
+		m.lockTimeout = timeout
+	}
+}
+
+// WithCreateDatabase controls whether the database is created when it
+// doesn't exist. It is enabled by default. Disabling it lets Migrate and
+// LoadSchema run as a user without server level privileges, failing with a
+// clear error when the database is missing.
+func WithCreateDatabase(create bool) Option {
+	return func(m *Migrator) {
+		m.createDatabase = create
+	}
+}
+
+// WithStrictDates makes reading the migration history fail on zero or
+// invalid created_at values instead of treating them as unknown.
+func WithStrictDates() Option {
+	return func(m *Migrator) {
+		m.strictDates = true
+	}
+}
+
+func New(dsn string, opts ...Option) (*Migrator, error) {
+	src, err := sourceFromDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return NewSource(src, opts...)
+}
+
+func NewConfig(cfg *mysql.Config, opts ...Option) (*Migrator, error) {
+	src, err := sourceFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewSource(src, opts...)
+}
+
+func NewSource(src Source, opts ...Option) (*Migrator, error) {
+	if err := src.validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		src:             src,
+		tableName:       defaultTableName,
+		logLevel:        slog.LevelDebug,
+		createDatabase:  true,
+		dbCharset:       defaultCharset,
+		dbCollation:     defaultCollation,
+		dumpConcurrency: defaultDumpConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if len(m.appliedBy) == 0 {
+		m.appliedBy = truncate(defaultAppliedBy(), maxAppliedByLength)
+	}
+	if len(m.appliedBy) > maxAppliedByLength {
+		return nil, errors.Errorf("applied by %q is longer than %d characters", m.appliedBy, maxAppliedByLength)
+	}
+
+	if !charsetPattern.MatchString(m.dbCharset) || !charsetPattern.MatchString(m.dbCollation) {
+		return nil, errors.Errorf("invalid character set %q or collation %q", m.dbCharset, m.dbCollation)
+	}
+
+	for name := range m.sessionVariables {
+		if !sessionVariablePattern.MatchString(name) {
+			return nil, errors.Errorf("invalid session variable name %q", name)
+		}
+	}
+
+	for _, version := range m.minServerVersions {
+		if _, err := parseServerVersion(version); err != nil {
+			return nil, errors.Wrap(err, "invalid minimum server version")
+		}
+	}
+
+	for _, table := range m.trackingTables() {
+		if !tableNamePattern.MatchString(table) {
+			return nil, errors.Errorf("invalid table name %q", table)
+		}
+	}
+	// the bookkeeping tables are named after the tracking table
+	for _, table := range []string{m.repeatableTableName(), m.progressTableName(), m.seedsTableName()} {
+		if len(table) > maxTableNameLength {
+			return nil, errors.Errorf("table name %q is too long, %q would be longer than %d characters", m.tableName, table, maxTableNameLength)
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,177 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigratorWithTableName(t *testing.T) {
+	dbname := "tablenametest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/tablenametest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir))
+
+	m, err := migration.New(fullDSN(dbname), migration.WithTableName("schema_versions"))
+	require.NoError(t, err)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err = m.Migrate(context.Background(), migrations)
+	require.NoError(t, err)
+
+	versions := queryTableVersions(fullDSN(dbname), "schema_versions")
+	require.Equal(t, 1, len(versions))
+	require.Equal(t, 1, versions[0].ID)
+
+	err = m.DumpSchema(context.Background(), dir)
+	require.NoError(t, err)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
+	require.Equal(t, "blarg.sql", files[0].Name())
+	require.Equal(t, "schema_versions.sql", files[1].Name())
+
+	dropDB(dbname)
+
+	err = m.LoadSchema(context.Background(), dir)
+	require.NoError(t, err)
+
+	versions = queryTableVersions(fullDSN(dbname), "schema_versions")
+	require.Equal(t, 1, len(versions))
+}
+
+func TestMigratorWithReservedTableName(t *testing.T) {
+	dbname := "reservedtablenametest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/reservedtablenametest", os.TempDir())
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir))
+
+	m, err := migration.New(fullDSN(dbname),
+		migration.WithTableName("order"),
+		migration.WithConfirmReset(),
+		migration.WithRepeatables(&migration.Repeatable{Name: "blarg_view", Up: `CREATE OR REPLACE VIEW blarg_view AS SELECT id FROM blarg`}),
+	)
+	require.NoError(t, err)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE IF NOT EXISTS gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`},
+	}
+
+	require.NoError(t, m.Migrate(context.Background(), migrations))
+	require.NoError(t, m.Migrate(context.Background(), migrations))
+	require.Len(t, queryTableVersions(fullDSN(dbname), "`order`"), 2)
+
+	upToDate, err := m.IsUpToDate(context.Background(), migrations)
+	require.NoError(t, err)
+	require.True(t, upToDate)
+
+	require.NoError(t, m.ResetVersion(context.Background(), 2))
+	version, err := m.CurrentVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+	require.NoError(t, m.Migrate(context.Background(), migrations))
+
+	require.NoError(t, m.DumpSchema(context.Background(), dir))
+	dropDB(dbname)
+	require.NoError(t, m.LoadSchema(context.Background(), dir))
+	require.Len(t, queryTableVersions(fullDSN(dbname), "`order`"), 2)
+}
+
+func TestMigratorRejectsLongTableName(t *testing.T) {
+	name := strings.Repeat("a", 60)
+	_, err := migration.New(fullDSN("longtablename"), migration.WithTableName(name))
+	require.EqualError(t, err, fmt.Sprintf(`table name %q is too long, "%s_repeatable" would be longer than 64 characters`, name, name))
+}
+
+func TestMigratorRejectsInvalidTableName(t *testing.T) {
+	_, err := migration.New(fullDSN("invalidtablename"), migration.WithTableName("bad name; DROP TABLE x"))
+	require.EqualError(t, err, `invalid table name "bad name; DROP TABLE x"`)
+}
+
+func TestMigratorWithLockWaitsForOtherHolders(t *testing.T) {
+	dbname := "locktest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	conn, err := sql.Open("mysql", partialDSN())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	holder, err := conn.Conn(context.Background())
+	require.NoError(t, err)
+	defer holder.Close()
+
+	var acquired int
+	lockName := "migration:migration_test_" + dbname + "._migrations"
+	err = holder.QueryRowContext(context.Background(), "SELECT GET_LOCK(?, 0)", lockName).Scan(&acquired)
+	require.NoError(t, err)
+	require.Equal(t, 1, acquired)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLock(time.Second))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "waiting for lock")
+
+	_, err = holder.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", lockName)
+	require.NoError(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLock(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(queryVersions(fullDSN(dbname))))
+}
+
+func TestMigratorWithoutCreateDatabase(t *testing.T) {
+	dbname := "nocreatetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), nil, migration.WithCreateDatabase(false))
+	require.EqualError(t, err, `database "migration_test_nocreatetest" does not exist and creation is disabled`)
+	require.False(t, dbExists(dbname))
+
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), t.TempDir(), migration.WithCreateDatabase(false))
+	require.EqualError(t, err, `database "migration_test_nocreatetest" does not exist and creation is disabled`)
+	require.False(t, dbExists(dbname))
+}
+
+func TestMigratorWithoutCreateDatabaseInExistingDatabase(t *testing.T) {
+	dbname := "nocreatetest"
+	dropDB(dbname)
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_nocreatetest")
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithCreateDatabase(false))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+}
@@ -0,0 +1,160 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// defaultConcurrency is how many databases MigrateAll migrates at once
+// without WithConcurrency.
+const defaultConcurrency = 4
+
+// WithConcurrency limits how many databases MigrateAll migrates at once.
+func WithConcurrency(databases int) Option {
+	return func(m *Migrator) {
+		m.concurrency = databases
+	}
+}
+
+// WithFailFast makes MigrateAll stop starting databases once one of them has
+// failed, rather than carrying on with the rest. Databases that are already
+// being migrated are left to finish.
+func WithFailFast() Option {
+	return func(m *Migrator) {
+		m.failFast = true
+	}
+}
+
+// withLogPrefix prefixes everything the Migrator logs with the database it's
+// migrating, so the lines of databases migrated at once can be told apart.
+func withLogPrefix(prefix string) Option {
+	return func(m *Migrator) {
+		m.logPrefix = prefix
+	}
+}
+
+// DatabaseResult is the outcome of migrating one of several databases.
+type DatabaseResult struct {
+	// Database is the address and name of the database, without credentials.
+	Database string
+	Report   Report
+	Err      error
+}
+
+// UpToDate reports whether the database was migrated without anything to
+// apply.
+func (r DatabaseResult) UpToDate() bool {
+	return r.Err == nil && len(r.Report.Applied) == 0
+}
+
+// ErrNotStarted is the Err of the databases WithFailFast didn't start.
+var ErrNotStarted = errors.New("not started as another database failed")
+
+// MigrateAll runs migrations against every one of dsns, such as identical
+// shards, up to WithConcurrency at a time and each under its own lock. Every
+// database is attempted whatever happens to the others, unless WithFailFast
+// is given. The result for each of dsns is returned in the same order, along
+// with an error listing the databases that failed.
+func MigrateAll(ctx context.Context, dsns []string, migrations []Migration, opts ...Option) ([]DatabaseResult, error) {
+	migrators := make([]*Migrator, len(dsns))
+	results := make([]DatabaseResult, len(dsns))
+	for i, dsn := range dsns {
+		m, err := New(dsn, opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid dsn %d", i)
+		}
+		migrators[i] = m
+		results[i].Database = m.databaseLabel()
+	}
+	return migrateEach(ctx, migrators, results, migrations, opts)
+}
+
+// migrateEach migrates each of migrators, up to WithConcurrency at a time,
+// filling in the results for the same index.
+func migrateEach(ctx context.Context, migrators []*Migrator, results []DatabaseResult, migrations []Migration, opts []Option) ([]DatabaseResult, error) {
+	settings := &Migrator{concurrency: defaultConcurrency}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	concurrency := settings.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var failed sync.Once
+	stopped := make(chan struct{})
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, m := range migrators {
+		select {
+		case <-stopped:
+			results[i].Err = ErrNotStarted
+			continue
+		case slots <- struct{}{}:
+		}
+		select {
+		case <-stopped:
+			<-slots
+			results[i].Err = ErrNotStarted
+			continue
+		default:
+		}
+
+		// each database takes its own lock, unless the caller chose how long
+		// to wait for it
+		if !m.lock {
+			m.lock = true
+			m.lockTimeout = -1
+		}
+		withLogPrefix(results[i].Database)(m)
+
+		wg.Add(1)
+		go func(i int, m *Migrator) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			results[i].Report, results[i].Err = m.MigrateWithReport(ctx, migrations)
+			if results[i].Err != nil && settings.failFast {
+				failed.Do(func() { close(stopped) })
+			}
+		}(i, m)
+	}
+	wg.Wait()
+
+	failures := []string{}
+	upToDate := 0
+	for _, result := range results {
+		if result.Err != nil && result.Err != ErrNotStarted {
+			failures = append(failures, fmt.Sprintf("%s: %s", result.Database, result.Err))
+		}
+		if result.UpToDate() {
+			upToDate++
+		}
+	}
+	settings.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("finished migrating %d databases, %d were already up to date and %d failed", len(results), upToDate, len(failures)),
+		slog.Int("databases", len(results)),
+		slog.Int("up_to_date", upToDate),
+		slog.Int("failed", len(failures)),
+	)
+	if len(failures) > 0 {
+		return results, errors.Errorf("migrations failed on %d of %d databases: %s",
+			len(failures), len(results), strings.Join(failures, "; "))
+	}
+	return results, nil
+}
+
+// databaseLabel names the database in logs and results, with its address
+// when it's known.
+func (m *Migrator) databaseLabel() string {
+	if m.src.cfg != nil && len(m.src.cfg.Addr) > 0 {
+		return m.src.cfg.Addr + "/" + m.src.DBName
+	}
+	return m.src.DBName
+}
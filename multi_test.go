@@ -0,0 +1,94 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func shardLabel(dbname string) string {
+	cfg, err := mysql.ParseDSN(partialDSN())
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s/migration_test_%s", cfg.Addr, dbname)
+}
+
+// breakShard creates the table the first migration creates, so migrating the
+// database fails.
+func breakShard(dbname string) {
+	execSQL(partialDSN(), fmt.Sprintf("CREATE DATABASE migration_test_%s", dbname))
+	execSQL(fullDSN(dbname), "CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB")
+}
+
+func TestMigrateAll(t *testing.T) {
+	shards := []string{"shard_a", "shard_b", "shard_c"}
+	dsns := []string{}
+	for _, shard := range shards {
+		dropDB(shard)
+		dsns = append(dsns, fullDSN(shard))
+	}
+	breakShard("shard_b")
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	logger := &capturingLogger{}
+	results, err := migration.MigrateAll(context.Background(), dsns, migrations,
+		migration.WithConcurrency(2),
+		migration.WithLogger(logger),
+	)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "migrations failed on 1 of 3 databases: "+shardLabel("shard_b")+": "), err.Error())
+
+	require.Len(t, results, 3)
+	for i, shard := range shards {
+		require.Equal(t, shardLabel(shard), results[i].Database)
+	}
+	require.NoError(t, results[0].Err)
+	require.Len(t, results[0].Report.Applied, 1)
+	require.Error(t, results[1].Err)
+	require.NoError(t, results[2].Err)
+	require.Len(t, results[2].Report.Applied, 1)
+	require.Len(t, queryVersions(fullDSN("shard_c")), 1)
+
+	require.NotEmpty(t, findLines(logger.lines, "["+shardLabel("shard_a")+"] executed migration 1"))
+	require.NotEmpty(t, findLines(logger.lines, "["+shardLabel("shard_c")+"] executed migration 1"))
+}
+
+func TestMigrateAllWithFailFast(t *testing.T) {
+	shards := []string{"shard_a", "shard_b", "shard_c"}
+	dsns := []string{}
+	for _, shard := range shards {
+		dropDB(shard)
+		dsns = append(dsns, fullDSN(shard))
+	}
+	breakShard("shard_a")
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	results, err := migration.MigrateAll(context.Background(), dsns, migrations,
+		migration.WithConcurrency(1),
+		migration.WithFailFast(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "migrations failed on 1 of 3 databases"), err.Error())
+	require.Error(t, results[0].Err)
+	require.Equal(t, migration.ErrNotStarted, results[1].Err)
+	require.Equal(t, migration.ErrNotStarted, results[2].Err)
+	require.False(t, dbExists("shard_c"))
+}
+
+func TestMigrateAllInvalidDSN(t *testing.T) {
+	_, err := migration.MigrateAll(context.Background(), []string{fullDSN("shard_a"), "root@/"}, nil)
+	require.EqualError(t, err, "invalid dsn 1: dsn missing database name")
+}
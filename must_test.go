@@ -0,0 +1,113 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+type panickingMigration struct {
+	id int
+}
+
+func (p *panickingMigration) Version() int {
+	return p.id
+}
+
+func (p *panickingMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	panic("migration blew up")
+}
+
+func TestMustMigratePanicsWithTypedErrorAfterReleasingLock(t *testing.T) {
+	dbname := "mustmigratetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `THIS IS NOT SQL`,
+		},
+	}
+
+	recovered := recoverPanic(func() {
+		migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLock(time.Second))
+	})
+
+	err, ok := recovered.(error)
+	require.True(t, ok, "expected an error, got %#v", recovered)
+
+	var mysqlErr *mysql.MySQLError
+	require.True(t, errors.As(err, &mysqlErr))
+	require.Equal(t, uint16(1064), mysqlErr.Number)
+
+	require.True(t, lockIsFree(dbname))
+	require.Equal(t, 1, len(queryVersions(fullDSN(dbname))))
+}
+
+func TestMigrationPanicsReleaseLock(t *testing.T) {
+	dbname := "migrationpanictest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&panickingMigration{id: 1},
+	}
+
+	recovered := recoverPanic(func() {
+		migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLock(time.Second))
+	})
+	require.Equal(t, "migration blew up", recovered)
+
+	require.True(t, lockIsFree(dbname))
+}
+
+func TestMustMigrateWithReport(t *testing.T) {
+	dbname := "mustreporttest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	report := migration.MustMigrateWithReport(context.Background(), fullDSN(dbname), migrations)
+	require.Equal(t, 1, len(report.Applied))
+	require.Equal(t, 1, report.Applied[0].Version)
+}
+
+func recoverPanic(f func()) (recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	f()
+	return nil
+}
+
+func lockIsFree(dbname string) bool {
+	conn, err := sql.Open("mysql", partialDSN())
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var free int
+	lockName := "migration:migration_test_" + dbname + "._migrations"
+	if err := conn.QueryRow("SELECT IS_FREE_LOCK(?)", lockName).Scan(&free); err != nil {
+		panic(err)
+	}
+	return free == 1
+}
@@ -0,0 +1,31 @@
+package migration
+
+import "fmt"
+
+// maxNameLength is the size of the tracking table's name column.
+const maxNameLength = 255
+
+// Namer is implemented by migrations with a name to record, for those other
+// than Definition which has a Name field.
+type Namer interface {
+	Name() string
+}
+
+func migrationName(migration Migration) string {
+	switch named := migration.(type) {
+	case *Definition:
+		return named.Name
+	case Namer:
+		return named.Name()
+	}
+	return ""
+}
+
+// describeMigration formats migration for log lines as its version, followed
+// by its name when it has one.
+func describeMigration(migration Migration) string {
+	if name := migrationName(migration); len(name) > 0 {
+		return fmt.Sprintf("%d (%s)", migration.Version(), name)
+	}
+	return fmt.Sprintf("%d", migration.Version())
+}
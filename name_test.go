@@ -0,0 +1,65 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordsMigrationNames(t *testing.T) {
+	dbname := "nametest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	migrations = append(migrations, &migration.Definition{
+		ID:   2,
+		Name: "add_index_on_blarg_name",
+		Up:   `ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ADD INDEX (name)`,
+	})
+
+	logger := &capturingLogger{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(logger))
+	require.NoError(t, err)
+	require.Regexp(t, `\Aexecuted migration 2 \(add_index_on_blarg_name\) in `, logger.lines[len(logger.lines)-1])
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Equal(t, "", applied[0].Name)
+	require.Equal(t, "add_index_on_blarg_name", applied[1].Name)
+
+	dir := fmt.Sprintf("%s/nametest", os.TempDir())
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	trackedMigrations, err := ioutil.ReadFile(dir + "/_migrations.sql")
+	require.NoError(t, err)
+	require.Regexp(t,
+		`\AINSERT INTO \x60_migrations\x60 \(id, created_at, name, applied_by, duration_ms\) VALUES\n\(1, "[^"]+", "", "[^"]+", \d+\),\n\(2, "[^"]+", "add_index_on_blarg_name", "[^"]+", \d+\)\z`,
+		string(trackedMigrations),
+	)
+}
+
+func TestRejectsLongMigrationNames(t *testing.T) {
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Name: string(make([]byte, 256)), Up: `DO 1`},
+	}
+
+	_, err := migration.Plan(context.Background(), fullDSN("nametest"), migrations)
+	require.EqualError(t, err, "name of migration 1 is longer than 255 characters")
+}
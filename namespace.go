@@ -0,0 +1,78 @@
+package migration
+
+// WithNamespace tracks migrations in _migrations_<namespace> rather than
+// _migrations, so modules owning different tables in one database keep
+// separate histories and version numbers. The advisory lock taken WithLock is
+// named after the tracking table, so namespaces don't wait for each other.
+func WithNamespace(namespace string) Option {
+	return func(m *Migrator) {
+		m.tableName = namespaceTable(namespace)
+	}
+}
+
+// WithNamespaces lists the namespaces of the other modules sharing the
+// database, with "" for one using the default _migrations. DumpSchema leaves
+// their tracking tables out of the table dumps and dumps their history
+// alongside this one's, and LoadSchema restores it.
+func WithNamespaces(namespaces ...string) Option {
+	return func(m *Migrator) {
+		for _, namespace := range namespaces {
+			m.sharedTables = append(m.sharedTables, namespaceTable(namespace))
+		}
+	}
+}
+
+func namespaceTable(namespace string) string {
+	if len(namespace) == 0 {
+		return defaultTableName
+	}
+	return defaultTableName + "_" + namespace
+}
+
+// trackingTables lists the Migrator's tracking table followed by those of
+// the other namespaces sharing the database.
+func (m *Migrator) trackingTables() []string {
+	tables := []string{m.tableName}
+	for _, table := range m.sharedTables {
+		if table != m.tableName {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// isBookkeepingTable reports whether table is one the Migrator or another
+// namespace keeps alongside its tracking table, for repeatables, seeds and
+// resumable migrations' progress, or the schema only load marker. Their rows
+// describe what was executed against this database, so they aren't part of
+// its schema.
+func (m *Migrator) isBookkeepingTable(table string) bool {
+	if table == schemaOnlyTable {
+		return true
+	}
+	for _, tracking := range m.trackingTables() {
+		tracker := m.forTrackingTable(tracking)
+		switch table {
+		case tracker.repeatableTableName(), tracker.progressTableName(), tracker.seedsTableName():
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) isTrackingTable(table string) bool {
+	for _, tracking := range m.trackingTables() {
+		if table == tracking {
+			return true
+		}
+	}
+	return false
+}
+
+// forTrackingTable returns a copy of the Migrator using table to track
+// migrations, for working with another namespace's history.
+func (m *Migrator) forTrackingTable(table string) *Migrator {
+	tracker := *m
+	tracker.tableName = table
+	return &tracker
+}
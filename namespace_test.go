@@ -0,0 +1,76 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces(t *testing.T) {
+	dbname := "namespacetest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/namespacetest", os.TempDir())
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir))
+
+	orders := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE orders ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `ALTER TABLE orders ADD COLUMN total INT NULL`},
+	}
+	billing := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE invoices ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	// the same versions are tracked independently
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), orders, migration.WithLogger(migration.NopLogger{})))
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), billing,
+		migration.WithNamespace("billing"),
+		migration.WithLock(time.Second),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+	require.Len(t, queryTableVersions(fullDSN(dbname), "_migrations_billing"), 1)
+
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithNamespaces("billing"))
+	require.NoError(t, err)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	require.Equal(t, []string{"_migrations.sql", "_migrations_billing.sql", "invoices.sql", "orders.sql"}, names)
+
+	dropDB(dbname)
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithNamespaces("billing"))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+	require.Len(t, queryTableVersions(fullDSN(dbname), "_migrations_billing"), 1)
+	require.True(t, tableExists(fullDSN(dbname), "invoices"))
+
+	// the billing module loading the same dump sees the default namespace's
+	// history as its neighbour's
+	dropDB(dbname)
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir,
+		migration.WithNamespace("billing"),
+		migration.WithNamespaces(""),
+	)
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+	require.Len(t, queryTableVersions(fullDSN(dbname), "_migrations_billing"), 1)
+}
+
+func TestNamespaceInvalid(t *testing.T) {
+	_, err := migration.New(fullDSN("namespacetest"), migration.WithNamespaces("billing-v2"))
+	require.EqualError(t, err, `invalid table name "_migrations_billing-v2"`)
+}
@@ -0,0 +1,42 @@
+package migration
+
+import "github.com/rbone/migration/internal/dialect"
+
+// WithKeepAutoIncrement keeps the AUTO_INCREMENT=N table option, each table's
+// next counter value, in the statements DumpSchema writes. It's stripped by
+// default as it changes whenever a row is inserted, making the dump differ
+// between otherwise identical databases.
+func WithKeepAutoIncrement() Option {
+	return func(m *Migrator) {
+		m.keepAutoIncrement = true
+	}
+}
+
+// normalizeTable removes what differs between databases with the same schema
+// from a dumped CREATE TABLE statement.
+func (m *Migrator) normalizeTable(createStatement string) string {
+	if !m.keepAutoIncrement {
+		createStatement = dialect.StripAutoIncrement(createStatement)
+	}
+	return createStatement
+}
+
+// WithStripDefiner removes the DEFINER clause from the views, triggers and
+// routines DumpSchema writes, and from the statements LoadSchema loads, so
+// they're created with the loading user as their definer. A dumped DEFINER
+// names a user that may not exist in other environments, where loading it
+// fails or leaves objects that can't be used.
+func WithStripDefiner() Option {
+	return func(m *Migrator) {
+		m.stripDefiner = true
+	}
+}
+
+// normalizeDefinition removes what differs between environments from a
+// dumped view, trigger or routine, or one being loaded.
+func (m *Migrator) normalizeDefinition(createStatement string) string {
+	if m.stripDefiner {
+		createStatement = dialect.StripDefiner(createStatement)
+	}
+	return createStatement
+}
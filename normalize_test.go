@@ -0,0 +1,104 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpSchemaStripsAutoIncrementCounter(t *testing.T) {
+	dbname := "autoincrementtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE counted ( id INT NOT NULL AUTO_INCREMENT, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `INSERT INTO counted VALUES (), ()`},
+		&migration.Definition{ID: 3, Up: `CREATE TABLE uncounted ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.Contains(t, showSchema(fullDSN(dbname), "counted"), "AUTO_INCREMENT=3")
+
+	dir := t.TempDir()
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+
+	counted, err := ioutil.ReadFile(dir + "/counted.sql")
+	require.NoError(t, err)
+	require.NotContains(t, string(counted), "AUTO_INCREMENT=")
+	require.Contains(t, string(counted), "NOT NULL AUTO_INCREMENT,")
+
+	uncounted, err := ioutil.ReadFile(dir + "/uncounted.sql")
+	require.NoError(t, err)
+	require.Equal(t, showSchema(fullDSN(dbname), "uncounted"), string(uncounted))
+
+	kept := t.TempDir()
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), kept, migration.WithLogger(migration.NopLogger{}), migration.WithKeepAutoIncrement()))
+
+	counted, err = ioutil.ReadFile(kept + "/counted.sql")
+	require.NoError(t, err)
+	require.Equal(t, showSchema(fullDSN(dbname), "counted"), string(counted))
+}
+
+func TestDumpSchemaStripsDefiner(t *testing.T) {
+	dbname := "definertest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE notes ( id INT NOT NULL, body VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE VIEW note_bodies AS SELECT body FROM notes`},
+		&migration.Definition{ID: 3, Up: `CREATE TRIGGER notes_insert BEFORE INSERT ON notes FOR EACH ROW SET NEW.body = TRIM(NEW.body)`},
+		&migration.Definition{ID: 4, Up: `CREATE PROCEDURE clear_notes() DELETE FROM notes`},
+		&migration.Definition{ID: 5, Up: `CREATE FUNCTION note_count() RETURNS INT READS SQL DATA RETURN (SELECT COUNT(*) FROM notes)`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+
+	dir := t.TempDir()
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+
+	view, err := ioutil.ReadFile(dir + "/note_bodies.view.sql")
+	require.NoError(t, err)
+	require.Contains(t, string(view), "DEFINER=")
+
+	stripped := t.TempDir()
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), stripped, migration.WithLogger(migration.NopLogger{}), migration.WithStripDefiner()))
+
+	for _, name := range []string{"note_bodies.view.sql", "notes_insert.trigger.sql", "clear_notes.procedure.sql", "note_count.function.sql"} {
+		definition, err := ioutil.ReadFile(stripped + "/" + name)
+		require.NoError(t, err)
+		require.NotContains(t, string(definition), "DEFINER=", name)
+		// a view's SQL SECURITY DEFINER characteristic isn't a DEFINER clause
+		if name == "note_bodies.view.sql" {
+			require.Contains(t, string(definition), "SQL SECURITY DEFINER")
+		}
+	}
+}
+
+func TestLoadSchemaStripsDefiner(t *testing.T) {
+	dbname := "definertest_load"
+	dropDB(dbname)
+	dir := t.TempDir()
+
+	require.NoError(t, ioutil.WriteFile(dir+"/notes.sql", []byte("CREATE TABLE `notes` ( `id` int NOT NULL, PRIMARY KEY (`id`) ) ENGINE=InnoDB"), 0644))
+	require.NoError(t, ioutil.WriteFile(dir+"/note_ids.view.sql", []byte("CREATE ALGORITHM=UNDEFINED DEFINER=`nobody`@`nowhere` SQL SECURITY DEFINER VIEW `note_ids` AS select `id` AS `id` from `notes`"), 0644))
+	require.NoError(t, ioutil.WriteFile(dir+"/clear_notes.procedure.sql", []byte("DELIMITER ;;\nCREATE DEFINER='nobody'@'nowhere' PROCEDURE `clear_notes`()\nBEGIN\n  DELETE FROM notes;\nEND;;\nDELIMITER ;\n"), 0644))
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{}), migration.WithSchemaOnly(), migration.WithStripDefiner()))
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var definer string
+	require.NoError(t, conn.QueryRow("SELECT DEFINER FROM information_schema.VIEWS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'note_ids'").Scan(&definer))
+	require.NotEqual(t, "nobody@nowhere", definer)
+	require.NoError(t, conn.QueryRow("SELECT DEFINER FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = DATABASE() AND ROUTINE_NAME = 'clear_notes'").Scan(&definer))
+	require.NotEqual(t, "nobody@nowhere", definer)
+
+	// and they work, which they wouldn't with a definer that doesn't exist
+	execSQL(fullDSN(dbname), "CALL clear_notes()")
+	var count int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM note_ids").Scan(&count))
+	require.Equal(t, 0, count)
+}
@@ -0,0 +1,31 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Observers are told about a migration only after it has been recorded in the
+// tracking table, in a fixed order: the metrics collector, then the after
+// hooks in the order they were added, then the events channel. The same goes
+// for the end of a run, where the metrics collector is followed by
+// RunFinished.
+//
+// observe calls fn, one observer's callback, recovering a panic so that it
+// can't stop the observers after it or fail the run. The panic is logged at
+// debug level, as it's the observer's problem rather than the migration's.
+func (m *Migrator) observe(ctx context.Context, observer string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.log(ctx, slog.LevelDebug,
+				fmt.Sprintf("%s panicked: %v", observer, r),
+				slog.String("observer", observer),
+				slog.String("db", m.src.DBName),
+				slog.Any("panic", r),
+			)
+		}
+	}()
+
+	fn()
+}
@@ -0,0 +1,58 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanickingObserverDoesntAffectOthers(t *testing.T) {
+	dbname := "observetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	order := []string{}
+	collector := &recordingCollector{}
+	events := make(chan migration.Event, 10)
+	logger := &capturingLogger{}
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLogger(logger),
+		migration.WithMetrics(collector),
+		migration.WithAfterHook(func(ctx context.Context, m migration.Migration, duration time.Duration, err error) {
+			// the migration is recorded before any observer is called
+			require.Len(t, queryVersions(fullDSN(dbname)), 1)
+			order = append(order, "first hook")
+			panic("deploy annotation failed")
+		}),
+		migration.WithAfterHook(func(ctx context.Context, m migration.Migration, duration time.Duration, err error) {
+			order = append(order, "second hook")
+			// metrics are observed before the hooks, events after them
+			require.Equal(t, []int{1}, collector.migrations)
+			require.Empty(t, events)
+		}),
+		migration.WithEvents(events),
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+
+	require.Equal(t, []string{"first hook", "second hook"}, order)
+	require.Equal(t, []int{1}, collector.migrations)
+	require.Equal(t, []int{1}, collector.runs)
+
+	received := drainEvents(events)
+	require.Equal(t, 4, len(received))
+	require.Equal(t, migration.RunFinished{Applied: 1}, received[3])
+
+	require.Contains(t, logger.lines, "after hook 1 panicked: deploy annotation failed")
+}
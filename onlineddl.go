@@ -0,0 +1,30 @@
+package migration
+
+import (
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+const (
+	errAlterNotSupported       = 1845
+	errAlterNotSupportedReason = 1846
+)
+
+// WithEnforceOnlineDDL appends ALGORITHM=INPLACE, LOCK=NONE to the ALTER
+// TABLE statements of Definitions that don't already choose their algorithm
+// or lock, so that the server rejects an ALTER it can't do without copying or
+// locking the table rather than blocking writes to it. Definitions can opt
+// out with AllowTableCopy.
+func WithEnforceOnlineDDL() Option {
+	return func(m *Migrator) {
+		m.enforceOnlineDDL = true
+	}
+}
+
+func isOnlineDDLRejected(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == errAlterNotSupported || mysqlErr.Number == errAlterNotSupportedReason
+}
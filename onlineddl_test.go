@@ -0,0 +1,59 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEnforceOnlineDDL(t *testing.T) {
+	dbname := "onlineddltest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `ALTER TABLE blarg ADD INDEX name (name)`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithEnforceOnlineDDL(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+
+	// changing a column's type copies the table
+	copying := &migration.Definition{
+		ID: 3,
+		Up: `ALTER TABLE blarg MODIFY id BIGINT NOT NULL`,
+	}
+	migrations = append(migrations, copying)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithEnforceOnlineDDL(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed executing migration 3: WithEnforceOnlineDDL added ALGORITHM=INPLACE, LOCK=NONE as the ALTER has to be done without copying or locking the table, set AllowTableCopy on the Definition if that's acceptable: Error 184")
+
+	must(migration.ResetVersion(context.Background(), fullDSN(dbname), 3,
+		migration.WithConfirmReset(),
+		migration.WithLogger(migration.NopLogger{}),
+	))
+
+	copying.AllowTableCopy = true
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithEnforceOnlineDDL(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 3)
+}
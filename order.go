@@ -0,0 +1,116 @@
+package migration
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithAllowOutOfOrder executes pending migrations older than the newest
+// applied one, such as one merged in from a long-lived branch, instead of
+// failing.
+func WithAllowOutOfOrder() Option {
+	return func(m *Migrator) {
+		m.allowOutOfOrder = true
+	}
+}
+
+// WithRequireContiguous fails the run when there are holes in the version
+// sequence, counting migrations that have already been applied, for
+// sequentially numbered migrations where a gap means one wasn't listed.
+func WithRequireContiguous() Option {
+	return func(m *Migrator) {
+		m.requireContiguous = true
+	}
+}
+
+// sortMigrations returns a copy of migrations sorted by version, the order
+// they're executed in whatever order they were listed.
+func sortMigrations(migrations []Migration) []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version() < sorted[j].Version()
+	})
+	return sorted
+}
+
+// checkOrder fails when a pending migration is older than the newest applied
+// one, as executing it would leave this database with a different history
+// than those that ran the migrations in order.
+func (m *Migrator) checkOrder(migrations []Migration, applied map[int]bool) error {
+	if m.allowOutOfOrder {
+		return nil
+	}
+
+	latest := 0
+	for version := range applied {
+		if version > latest {
+			latest = version
+		}
+	}
+
+	outOfOrder := []int{}
+	for _, migration := range migrations {
+		if !applied[migration.Version()] && migration.Version() < latest {
+			outOfOrder = append(outOfOrder, migration.Version())
+		}
+	}
+
+	if len(outOfOrder) > 0 {
+		sort.Ints(outOfOrder)
+		return errors.Errorf(
+			"migrations %v are older than the latest applied migration %d, give them a newer version or use WithAllowOutOfOrder",
+			outOfOrder, latest,
+		)
+	}
+
+	return nil
+}
+
+// checkContiguous fails listing the versions missing between the lowest and
+// highest of migrations and those already applied. Runs of missing versions
+// are listed as ranges, so one mistyped version such as a timestamp doesn't
+// list millions of them.
+func (m *Migrator) checkContiguous(migrations []Migration, applied map[int]bool) error {
+	if !m.requireContiguous {
+		return nil
+	}
+
+	seen := make(map[int]bool, len(migrations)+len(applied))
+	for version := range applied {
+		seen[version] = true
+	}
+	for _, migration := range migrations {
+		seen[migration.Version()] = true
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+
+	versions := make([]int, 0, len(seen))
+	for version := range seen {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	missing := []string{}
+	for i := 1; i < len(versions); i++ {
+		from, to := versions[i-1]+1, versions[i]-1
+		switch {
+		case from == to:
+			missing = append(missing, strconv.Itoa(from))
+		case from < to:
+			missing = append(missing, fmt.Sprintf("%d-%d", from, to))
+		}
+	}
+
+	if len(missing) > 0 {
+		return errors.Errorf("migrations [%s] are missing between %d and %d", strings.Join(missing, " "), versions[0], versions[len(versions)-1])
+	}
+
+	return nil
+}
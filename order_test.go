@@ -0,0 +1,91 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutOfOrderMigrationsFail(t *testing.T) {
+	dbname := "outofordertest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	definitions := map[int]migration.Migration{
+		1: &migration.Definition{ID: 1, Up: `CREATE TABLE one ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		2: &migration.Definition{ID: 2, Up: `CREATE TABLE two ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		3: &migration.Definition{ID: 3, Up: `CREATE TABLE three ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		4: &migration.Definition{ID: 4, Up: `CREATE TABLE four ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	// 3 comes in from a branch merged after 4 was applied
+	applied := []migration.Migration{definitions[1], definitions[2], definitions[4]}
+	merged := []migration.Migration{definitions[1], definitions[2], definitions[3], definitions[4]}
+
+	migration.MustMigrate(context.Background(), fullDSN(dbname), applied, migration.WithLogger(migration.NopLogger{}))
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), merged, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "migrations [3] are older than the latest applied migration 4, give them a newer version or use WithAllowOutOfOrder")
+	require.Len(t, queryVersions(fullDSN(dbname)), 3)
+
+	_, err = migration.Plan(context.Background(), fullDSN(dbname), merged)
+	require.Error(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), merged,
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithAllowOutOfOrder(),
+	)
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 4)
+}
+
+func TestWithRequireContiguous(t *testing.T) {
+	dbname := "contiguoustest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	definitions := map[int]migration.Migration{}
+	for _, version := range []int{1, 2, 3, 4, 6, 8} {
+		definitions[version] = &migration.Definition{ID: version, Up: `DO 1`}
+	}
+
+	migration.MustMigrate(context.Background(), fullDSN(dbname),
+		[]migration.Migration{definitions[1], definitions[2]},
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithRequireContiguous(),
+	)
+
+	// 1 and 2 have already been applied
+	err := migration.Migrate(context.Background(), fullDSN(dbname),
+		[]migration.Migration{definitions[3], definitions[4]},
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithRequireContiguous(),
+	)
+	require.NoError(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname),
+		[]migration.Migration{definitions[4], definitions[6], definitions[8]},
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithRequireContiguous(),
+	)
+	require.EqualError(t, err, "migrations [5 7] are missing between 1 and 8")
+	require.Len(t, queryVersions(fullDSN(dbname)), 4)
+
+	// a mistyped timestamp version is reported as one range of missing versions
+	err = migration.Migrate(context.Background(), fullDSN(dbname),
+		[]migration.Migration{definitions[4], &migration.Definition{ID: 20240101, Up: `DO 1`}},
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithRequireContiguous(),
+	)
+	require.EqualError(t, err, "migrations [5-20240100] are missing between 1 and 20240101")
+	require.Len(t, queryVersions(fullDSN(dbname)), 4)
+
+	// sparse versions are fine by default
+	err = migration.Migrate(context.Background(), fullDSN(dbname),
+		[]migration.Migration{definitions[4], definitions[6], definitions[8]},
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+}
@@ -0,0 +1,200 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// alterTableStatementPattern splits an ALTER TABLE statement into its table,
+// optionally qualified by database, and what's altered.
+var alterTableStatementPattern = regexp.MustCompile("(?is)^\\s*ALTER\\s+TABLE\\s+(?:`?([^`.\\s]+)`?\\.)?`?([^`.\\s]+)`?\\s+(.+?)\\s*$")
+
+// OnlineSchemaChange is an ExecutionStrategy that runs ALTER TABLE statements
+// through an online schema change tool such as gh-ost or
+// pt-online-schema-change, and executes any other statement as usual. The
+// tool's output is logged a line at a time, and a non-zero exit fails the
+// migration.
+//
+// Args are text/template templates given an OnlineSchemaChangeArgs. The
+// user and password are written to a defaults file only readable by the
+// current user, named by DefaultsFile, rather than passed as arguments that
+// would show up in ps.
+type OnlineSchemaChange struct {
+	Command string
+	Args    []string
+}
+
+// OnlineSchemaChangeArgs are what OnlineSchemaChange's Args are templated
+// with.
+type OnlineSchemaChangeArgs struct {
+	Host     string
+	Port     string
+	Database string
+	Table    string
+	// Alter is the statement after ALTER TABLE and the table name, e.g.
+	// ADD COLUMN name VARCHAR(64).
+	Alter        string
+	DefaultsFile string
+}
+
+// GhOst runs ALTER TABLE statements through gh-ost, with args added to those
+// connecting it to the database.
+func GhOst(args ...string) *OnlineSchemaChange {
+	return &OnlineSchemaChange{
+		Command: "gh-ost",
+		Args: append([]string{
+			"--host={{.Host}}",
+			"--port={{.Port}}",
+			"--conf={{.DefaultsFile}}",
+			"--database={{.Database}}",
+			"--table={{.Table}}",
+			"--alter={{.Alter}}",
+			"--execute",
+		}, args...),
+	}
+}
+
+// PtOnlineSchemaChange runs ALTER TABLE statements through
+// pt-online-schema-change, with args added to those connecting it to the
+// database.
+func PtOnlineSchemaChange(args ...string) *OnlineSchemaChange {
+	return &OnlineSchemaChange{
+		Command: "pt-online-schema-change",
+		Args: append([]string{
+			"--alter={{.Alter}}",
+			"--execute",
+			"F={{.DefaultsFile}},h={{.Host}},P={{.Port}},D={{.Database}},t={{.Table}}",
+		}, args...),
+	}
+}
+
+func (o *OnlineSchemaChange) Execute(ctx context.Context, target ExecutionTarget, statement string) error {
+	match := alterTableStatementPattern.FindStringSubmatch(statement)
+	if match == nil {
+		_, err := target.Conn.ExecContext(ctx, statement)
+		return err
+	}
+
+	if target.Config == nil {
+		return errors.Errorf("%s needs a DSN or *mysql.Config to connect with", o.Command)
+	}
+	if target.Config.Net != "tcp" {
+		return errors.Errorf("%s can only connect over tcp, not %s", o.Command, target.Config.Net)
+	}
+
+	host, port, err := net.SplitHostPort(target.Config.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "invalid address %q", target.Config.Addr)
+	}
+
+	defaults, err := writeDefaultsFile(target.Config.User, target.Config.Passwd)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(defaults)
+
+	database := match[1]
+	if len(database) == 0 {
+		database = target.Config.DBName
+	}
+	args, err := o.args(OnlineSchemaChangeArgs{
+		Host:         host,
+		Port:         port,
+		Database:     database,
+		Table:        match[2],
+		Alter:        match[3],
+		DefaultsFile: defaults,
+	})
+	if err != nil {
+		return err
+	}
+
+	output := &lineLogger{log: func(line string) {
+		target.Log(fmt.Sprintf("%s: %s", o.Command, line))
+	}}
+	defer output.Flush()
+
+	cmd := exec.CommandContext(ctx, o.Command, args...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "%s failed altering table %s", o.Command, match[2])
+	}
+	return nil
+}
+
+func (o *OnlineSchemaChange) args(values OnlineSchemaChangeArgs) ([]string, error) {
+	args := make([]string, len(o.Args))
+	for i, arg := range o.Args {
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s argument %q", o.Command, arg)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, values); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s argument %q", o.Command, arg)
+		}
+		args[i] = b.String()
+	}
+	return args, nil
+}
+
+// writeDefaultsFile writes user and password to a my.cnf style file only
+// readable by the current user, returning its path.
+func writeDefaultsFile(user string, password string) (string, error) {
+	f, err := os.CreateTemp("", "migration-*.cnf")
+	if err != nil {
+		return "", errors.Wrap(err, "failed creating defaults file")
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "[client]\nuser=%s\npassword=%s\n", quoteOption(user), quoteOption(password))
+	if err != nil {
+		os.Remove(f.Name())
+		return "", errors.Wrap(err, "failed writing defaults file")
+	}
+	return f.Name(), nil
+}
+
+// quoteOption quotes value for an option file, so that characters such as #
+// aren't read as the start of a comment.
+func quoteOption(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// lineLogger passes what's written to it to log a line at a time.
+type lineLogger struct {
+	log     func(line string)
+	partial bytes.Buffer
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.partial.Write(p)
+	for {
+		line, err := l.partial.ReadString('\n')
+		if err == io.EOF {
+			// put back the incomplete line until the rest is written
+			l.partial.WriteString(line)
+			return len(p), nil
+		}
+		l.log(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// Flush logs what's left without a trailing newline.
+func (l *lineLogger) Flush() {
+	if l.partial.Len() > 0 {
+		l.log(l.partial.String())
+		l.partial.Reset()
+	}
+}
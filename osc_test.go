@@ -0,0 +1,115 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTool writes a script standing in for an online schema change tool,
+// which records its arguments and the defaults file it's given in dir and
+// exits with status.
+func fakeTool(dir string, status int) string {
+	tool := dir + "/fake-osc"
+	must(ioutil.WriteFile(tool, []byte(fmt.Sprintf(`#!/bin/sh
+echo "$@" > %[1]s/args
+for arg in "$@"; do
+	case "$arg" in
+	--conf=*) cat "${arg#--conf=}" > %[1]s/defaults ;;
+	esac
+done
+echo "copying rows"
+echo "done" >&2
+exit %[2]d
+`, dir, status)), 0755))
+	return tool
+}
+
+func TestOnlineSchemaChange(t *testing.T) {
+	dbname := "osctest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/osctest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	tool := migration.GhOst("--allow-on-master")
+	tool.Command = fakeTool(dir, 0)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+				ALTER TABLE ` + "`blarg`" + ` ADD COLUMN name VARCHAR(64) NOT NULL`,
+			Strategy: tool,
+		},
+	}
+
+	logger := &capturingLogger{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(logger))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+
+	// the CREATE TABLE is executed as usual, only the ALTER goes through the tool
+	require.True(t, tableExists(fullDSN(dbname), "blarg"))
+
+	cfg, err := mysql.ParseDSN(fullDSN(dbname))
+	require.NoError(t, err)
+
+	args, err := ioutil.ReadFile(dir + "/args")
+	require.NoError(t, err)
+	require.Contains(t, string(args), "--database=migration_test_osctest --table=blarg --alter=ADD COLUMN name VARCHAR(64) NOT NULL --execute --allow-on-master")
+	if len(cfg.Passwd) > 0 {
+		require.NotContains(t, string(args), cfg.Passwd)
+	}
+
+	defaults, err := ioutil.ReadFile(dir + "/defaults")
+	require.NoError(t, err)
+	require.Contains(t, string(defaults), fmt.Sprintf("[client]\nuser=%q\n", cfg.User))
+
+	require.Contains(t, logger.lines, tool.Command+": copying rows")
+	require.Contains(t, logger.lines, tool.Command+": done")
+}
+
+func TestOnlineSchemaChangeFailing(t *testing.T) {
+	dbname := "osctest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/osctest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	tool := migration.PtOnlineSchemaChange()
+	tool.Command = fakeTool(dir, 1)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(64) NOT NULL`},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithExecutionStrategy(2, tool),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed executing migration 2: "+tool.Command+" failed altering table blarg: exit status 1")
+
+	args, err := ioutil.ReadFile(dir + "/args")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(args), "--alter=ADD COLUMN name VARCHAR(64) NOT NULL --execute F="), string(args))
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.True(t, applied[1].Dirty)
+}
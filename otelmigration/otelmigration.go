@@ -0,0 +1,75 @@
+// Package otelmigration traces migration runs with OpenTelemetry. It lives
+// apart from the migration package so only users who want OpenTelemetry
+// depend on it:
+//
+//	migration.MustMigrate(ctx, dsn, migrations, otelmigration.WithTracing(nil))
+package otelmigration
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rbone/migration"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/rbone/migration/otelmigration"
+
+// Tracer implements migration.Tracer with OpenTelemetry spans. Spans carry
+// the attributes the migration package passes, such as migration.version and
+// db.name, and a migration.outcome of "success" or "failure".
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ migration.Tracer = (*Tracer)(nil)
+
+// NewTracer creates spans with provider, or the global TracerProvider when
+// provider is nil.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// WithTracing is migration.WithTracer(NewTracer(provider)).
+func WithTracing(provider trace.TracerProvider) migration.Option {
+	return migration.WithTracer(NewTracer(provider))
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(attributes(attrs)...))
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.SetAttributes(attribute.String("migration.outcome", "failure"))
+		} else {
+			span.SetAttributes(attribute.String("migration.outcome", "success"))
+		}
+		span.End()
+	}
+}
+
+func attributes(attrs []slog.Attr) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		switch value.Kind() {
+		case slog.KindBool:
+			kvs = append(kvs, attribute.Bool(attr.Key, value.Bool()))
+		case slog.KindInt64:
+			kvs = append(kvs, attribute.Int64(attr.Key, value.Int64()))
+		case slog.KindFloat64:
+			kvs = append(kvs, attribute.Float64(attr.Key, value.Float64()))
+		default:
+			kvs = append(kvs, attribute.String(attr.Key, value.String()))
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,85 @@
+package otelmigration_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/rbone/migration/otelmigration"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracingCreatesSpans(t *testing.T) {
+	dsn := testDSN("otelmigrationtest")
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `THIS IS NOT SQL`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), dsn, migrations, otelmigration.WithTracing(provider))
+	require.Error(t, err)
+
+	spans := map[string][]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+
+	require.Equal(t, 1, len(spans["migration.run"]))
+	require.Equal(t, 1, len(spans["migration.create_database"]))
+	require.Equal(t, 1, len(spans["migration.prepare_table"]))
+	require.Equal(t, 1, len(spans["migration.record_applied"]))
+	require.Equal(t, 2, len(spans["migration.migrate"]))
+
+	run := spans["migration.run"][0]
+	require.Contains(t, run.Attributes, attribute.String("db.name", "migration_test_otelmigrationtest"))
+	require.Contains(t, run.Attributes, attribute.String("migration.outcome", "failure"))
+
+	applied := spans["migration.migrate"][0]
+	require.Equal(t, run.SpanContext.SpanID(), applied.Parent.SpanID())
+	require.Contains(t, applied.Attributes, attribute.Int64("migration.version", 1))
+	require.Contains(t, applied.Attributes, attribute.String("migration.outcome", "success"))
+
+	failed := spans["migration.migrate"][1]
+	require.Contains(t, failed.Attributes, attribute.Int64("migration.version", 2))
+	require.Contains(t, failed.Attributes, attribute.String("migration.outcome", "failure"))
+	require.Equal(t, 1, len(failed.Events))
+}
+
+// testDSN returns a DSN for a freshly dropped database named dbname on the
+// server in DATABASE_DSN.
+func testDSN(dbname string) string {
+	cfg, err := mysql.ParseDSN(os.Getenv("DATABASE_DSN"))
+	if err != nil {
+		panic(err)
+	}
+
+	conn, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	cfg.DBName = "migration_test_" + dbname
+	if _, err := conn.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", cfg.DBName)); err != nil {
+		panic(err)
+	}
+
+	return cfg.FormatDSN()
+}
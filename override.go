@@ -0,0 +1,84 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// WithOverride runs override in place of the listed migration with the same
+// version, for this run only. It's meant for environments where a single
+// migration needs different SQL, without forking the whole list. The version
+// must be in the list and not yet applied. Overridden migrations are logged
+// and recorded with "override" set in their metadata, and with the listed
+// migration's checksum so later runs without the override verify cleanly.
+func WithOverride(version int, override Migration) Option {
+	return func(m *Migrator) {
+		if m.overrides == nil {
+			m.overrides = map[int]Migration{}
+		}
+		m.overrides[version] = override
+	}
+}
+
+// applyOverrides returns migrations with the overrides swapped in, checking
+// every override before anything runs.
+func (m *Migrator) applyOverrides(ctx context.Context, migrations []Migration, executed func(version int) (bool, error)) ([]Migration, error) {
+	if len(m.overrides) == 0 {
+		return migrations, nil
+	}
+
+	versions := make([]int, 0, len(m.overrides))
+	for version := range m.overrides {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	listed := make(map[int]int, len(migrations))
+	for i, migration := range migrations {
+		listed[migration.Version()] = i
+	}
+
+	overridden := make([]Migration, len(migrations))
+	copy(overridden, migrations)
+	m.overridden = make(map[int]Migration, len(versions))
+
+	for _, version := range versions {
+		override := m.overrides[version]
+		if override == nil {
+			return nil, errors.Errorf("override for migration %d is nil", version)
+		}
+		if override.Version() != version {
+			return nil, errors.Errorf("override for migration %d has version %d", version, override.Version())
+		}
+
+		i, ok := listed[version]
+		if !ok {
+			return nil, errors.Errorf("override for migration %d which isn't in the list of migrations", version)
+		}
+
+		alreadyExecuted, err := executed(version)
+		if err != nil {
+			return nil, err
+		}
+		if alreadyExecuted {
+			return nil, errors.Errorf("migration %d has already been executed and can't be overridden", version)
+		}
+
+		m.overridden[version] = migrations[i]
+		overridden[i] = override
+	}
+
+	for _, version := range versions {
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("overriding migration %d for this run", version),
+			slog.Int("version", version),
+			slog.String("db", m.src.DBName),
+		)
+	}
+
+	return overridden, nil
+}
@@ -0,0 +1,76 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOverrideReplacesMigration(t *testing.T) {
+	dbname := "overridetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(255) NOT NULL, ALGORITHM=UNSUPPORTED_BY_THIS_FORK`,
+		},
+	}
+
+	logger := &capturingLogger{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLogger(logger),
+		migration.WithOverride(2, &migration.Definition{
+			ID: 2,
+			Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(255) NOT NULL`,
+		}),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(queryVersions(fullDSN(dbname))))
+	require.Contains(t, showSchema(fullDSN(dbname), "blarg"), "`name` varchar(255)")
+	require.Equal(t, map[string]string{"override": "true"}, queryMetadata(fullDSN(dbname), 2))
+	require.Contains(t, logger.lines, "overriding migration 2 for this run")
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithOverride(2, &migration.Definition{
+			ID: 2,
+			Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(255) NOT NULL`,
+		}),
+	)
+	require.EqualError(t, err, "migration 2 has already been executed and can't be overridden")
+
+	// the listed migration's checksum is recorded, so runs without the
+	// override don't see it as changed
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+}
+
+func TestWithOverrideRequiresListedVersion(t *testing.T) {
+	dbname := "overrideunlistedtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithOverride(3, &migration.Definition{
+			ID: 3,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		}),
+	)
+	require.EqualError(t, err, "override for migration 3 which isn't in the list of migrations")
+	require.Equal(t, 0, len(queryVersions(fullDSN(dbname))))
+}
@@ -0,0 +1,325 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+// PlannedMigration is a migration that hasn't been executed yet.
+type PlannedMigration struct {
+	Version int
+	// SQL is the statement the migration runs, for migrations where it is
+	// known up front such as a Definition.
+	SQL string
+	// Filtered is set when none of the migration's tags are enabled, see
+	// WithTags, so Migrate won't execute it.
+	Filtered bool
+	// Tables are the tables the migration's statements create or alter, as
+	// far as they can be told from a Definition's SQL. It's left empty by
+	// WithoutPlanTableSizes.
+	Tables []PlannedTable
+	// EstimatedDuration is roughly how long the migration will take, if
+	// there's anything to go on, see EstimateTotal.
+	EstimatedDuration DurationEstimate
+}
+
+// PlannedTable is a table a pending migration touches, with its size going
+// by information_schema.TABLES, which is only as accurate as the table's
+// statistics.
+type PlannedTable struct {
+	Database string
+	Table    string
+	// New is set when the table doesn't exist yet, e.g. as the migration or
+	// an earlier pending one creates it.
+	New                 bool
+	EstimatedRows       int64
+	EstimatedDataLength int64
+}
+
+func Plan(ctx context.Context, dsn string, migrations []Migration, opts ...Option) ([]PlannedMigration, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Plan(ctx, migrations)
+}
+
+func PlanConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) ([]PlannedMigration, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Plan(ctx, migrations)
+}
+
+func PlanSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) ([]PlannedMigration, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Plan(ctx, migrations)
+}
+
+// Plan returns the migrations Migrate would execute, in the order it would
+// execute them, without changing anything. A database or tracking table that
+// doesn't exist yet means every migration is pending. Pending migrations
+// WithTags excludes are included, marked Filtered. Each lists the tables it
+// touches with their estimated size, see PlannedTable, and an estimate of
+// how long it will take.
+func (m *Migrator) Plan(ctx context.Context, migrations []Migration) ([]PlannedMigration, error) {
+	pending, _, err := m.plan(ctx, migrations)
+	return pending, err
+}
+
+// ErrPendingMigrations is returned by CheckNoPending when there are
+// migrations that haven't been executed.
+var ErrPendingMigrations = errors.New("pending migrations")
+
+func CheckPending(ctx context.Context, dsn string, migrations []Migration, opts ...Option) ([]int, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.CheckPending(ctx, migrations)
+}
+
+func CheckPendingConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) ([]int, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.CheckPending(ctx, migrations)
+}
+
+func CheckPendingSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) ([]int, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.CheckPending(ctx, migrations)
+}
+
+// CheckPending returns the versions of the migrations that haven't been
+// executed, like Plan, leaving out those WithTags excludes.
+func (m *Migrator) CheckPending(ctx context.Context, migrations []Migration) ([]int, error) {
+	pending, _, err := m.plan(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(pending))
+	for _, planned := range pending {
+		if !planned.Filtered {
+			versions = append(versions, planned.Version)
+		}
+	}
+	return versions, nil
+}
+
+func CheckNoPending(ctx context.Context, dsn string, migrations []Migration, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return err
+	}
+	return m.CheckNoPending(ctx, migrations)
+}
+
+func CheckNoPendingConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return err
+	}
+	return m.CheckNoPending(ctx, migrations)
+}
+
+func CheckNoPendingSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return err
+	}
+	return m.CheckNoPending(ctx, migrations)
+}
+
+// CheckNoPending fails with ErrPendingMigrations when any of migrations
+// haven't been executed, e.g. to gate a release in CI.
+func (m *Migrator) CheckNoPending(ctx context.Context, migrations []Migration) error {
+	pending, err := m.CheckPending(ctx, migrations)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return errors.Wrapf(ErrPendingMigrations, "migrations %v haven't been executed", pending)
+	}
+	return nil
+}
+
+// plan runs the same checks as Migrate, apart from checkTableSizes, and splits
+// migrations into those that are pending and the versions that have already
+// been executed. Checksums are verified without recording missing ones.
+func (m *Migrator) plan(ctx context.Context, migrations []Migration) (pending []PlannedMigration, skipped []int, err error) {
+	migrations = sortMigrations(migrations)
+	if err := validateMigrations(migrations); err != nil {
+		return nil, nil, err
+	}
+
+	applied, recorded, err := m.appliedIfExists(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrations, err = m.applyOverrides(ctx, migrations, func(version int) (bool, error) {
+		return applied[version], nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.validateMetadata(migrations); err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.validateStatements(migrations); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkChecksums(migrations, recorded); err != nil {
+		return nil, nil, err
+	}
+
+	all := migrations
+	migrations, filtered := m.filterByTags(migrations, applied)
+
+	if err := m.checkOrder(migrations, applied); err != nil {
+		return nil, nil, err
+	}
+	if err := m.checkContiguous(all, applied); err != nil {
+		return nil, nil, err
+	}
+	if err := checkDependencies(migrations, applied); err != nil {
+		return nil, nil, err
+	}
+	if err := m.checkPendingStatements(ctx, migrations, applied); err != nil {
+		return nil, nil, err
+	}
+
+	durations, err := m.durations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pending = []PlannedMigration{}
+	skipped = []int{}
+	isFiltered := make(map[int]bool, len(filtered))
+	for _, version := range filtered {
+		isFiltered[version] = true
+	}
+	for _, migration := range all {
+		if applied[migration.Version()] {
+			skipped = append(skipped, migration.Version())
+			continue
+		}
+		pending = append(pending, PlannedMigration{
+			Version:           migration.Version(),
+			SQL:               migrationSQL(migration),
+			Filtered:          isFiltered[migration.Version()],
+			EstimatedDuration: estimateDuration(migration, durations),
+		})
+	}
+
+	if !m.skipPlanSizes {
+		if err := m.planTables(ctx, pending, all, applied); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return pending, skipped, nil
+}
+
+// appliedIfExists reads the applied versions and their recorded checksums
+// without creating anything, returning none when the database or tracking
+// table doesn't exist yet.
+func (m *Migrator) appliedIfExists(ctx context.Context) (map[int]bool, map[int]sql.NullString, error) {
+	conn, err := m.openIfExists(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if conn == nil {
+		return map[int]bool{}, map[int]sql.NullString{}, nil
+	}
+	defer conn.Close()
+
+	if err := m.checkTracked(ctx, conn); err != nil {
+		return nil, nil, err
+	}
+
+	exists, err := m.migrationsTableExists(ctx, conn)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed checking if table %q exists", m.tableName)
+	}
+	if !exists {
+		return map[int]bool{}, map[int]sql.NullString{}, nil
+	}
+
+	applied, err := m.appliedVersionSet(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	recorded, err := m.readChecksums(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return applied, recorded, nil
+}
+
+// openIfExists opens the database without creating it, returning a nil
+// *sql.DB when it doesn't exist.
+func (m *Migrator) openIfExists(ctx context.Context) (*sql.DB, error) {
+	server, err := m.src.Server(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer server.Close()
+
+	exists, err := dbExists(ctx, server, m.src.DBName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed checking if db %q exists", m.src.DBName)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	return m.openDatabase(ctx)
+}
+
+// appliedVersionSet reads every version in the tracking table, failing with
+// a DirtyError if one of them is dirty. Resumable migrations left dirty by a
+// run that was killed are pending rather than applied.
+func (m *Migrator) appliedVersionSet(ctx context.Context, conn *sql.DB) (map[int]bool, error) {
+	applied, dirty, err := m.readVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkDirty(ctx, conn, dirty); err != nil {
+		return nil, err
+	}
+	for _, version := range dirty {
+		delete(applied, version)
+	}
+	return applied, nil
+}
+
+// migrationSQL returns the SQL a migration runs when it can be known without
+// running it.
+func migrationSQL(migration Migration) string {
+	if definition, ok := migration.(*Definition); ok {
+		if len(definition.UpStatements) > 0 {
+			return strings.Join(definition.UpStatements, ";\n")
+		}
+		return definition.Up
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanListsPendingMigrationsInOrder(t *testing.T) {
+	dbname := "plantest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	// everything is pending before the database exists
+	planned, err := migration.Plan(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Equal(t, []migration.PlannedMigration{
+		{
+			Version: 1,
+			SQL:     `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Tables:  []migration.PlannedTable{{Database: "migration_test_plantest", Table: "blarg", New: true}},
+		},
+		{
+			Version: 2,
+			SQL:     `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+			Tables:  []migration.PlannedTable{{Database: "migration_test_plantest", Table: "gralb", New: true}},
+		},
+	}, planned)
+	require.False(t, dbExists(dbname))
+
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations[:1], migration.WithLogger(migration.NopLogger{}))
+
+	planned, err = migration.Plan(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Equal(t, []migration.PlannedMigration{
+		{
+			Version: 2,
+			SQL:     `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+			Tables:  []migration.PlannedTable{{Database: "migration_test_plantest", Table: "gralb", New: true}},
+		},
+	}, planned)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+}
+
+func TestPlanVerifiesChecksums(t *testing.T) {
+	dbname := "planchecksumtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	// edited after it was applied
+	migrations[0].(*migration.Definition).Up = `CREATE TABLE blarg ( id BIGINT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`
+
+	_, err := migration.Plan(context.Background(), fullDSN(dbname), migrations)
+	var mismatch *migration.ChecksumMismatchError
+	require.True(t, errors.As(err, &mismatch), "got %v", err)
+	require.Equal(t, []int{1}, mismatch.Versions)
+
+	// a missing checksum is left for Migrate to record
+	execSQL(fullDSN(dbname), "UPDATE _migrations SET checksum = NULL")
+	_, err = migration.Plan(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.False(t, queryChecksum(fullDSN(dbname), 1).Valid)
+}
+
+func TestPlanReportsTableSizes(t *testing.T) {
+	dbname := "plantablestest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	execSQL(fullDSN(dbname), "INSERT INTO blarg VALUES (1), (2), (3)")
+	execSQL(fullDSN(dbname), "ANALYZE TABLE blarg")
+
+	migrations = append(migrations,
+		&migration.Definition{
+			ID: 2,
+			UpStatements: []string{
+				`ALTER TABLE blarg ADD COLUMN name VARCHAR(64)`,
+				`CREATE INDEX name ON blarg (name)`,
+			},
+		},
+		&migration.Definition{
+			ID: 3,
+			UpStatements: []string{
+				`CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+				`ALTER TABLE gralb ADD COLUMN name VARCHAR(64)`,
+			},
+		},
+		&migration.Definition{ID: 4, Up: `INSERT INTO blarg (id) VALUES (4)`},
+	)
+
+	planned, err := migration.Plan(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Len(t, planned, 3)
+
+	// each table is listed once per migration, with its estimated size
+	require.Len(t, planned[0].Tables, 1)
+	blarg := planned[0].Tables[0]
+	require.Equal(t, "migration_test_plantablestest", blarg.Database)
+	require.Equal(t, "blarg", blarg.Table)
+	require.False(t, blarg.New)
+	require.Equal(t, int64(3), blarg.EstimatedRows)
+	require.True(t, blarg.EstimatedDataLength > 0)
+
+	// tables that don't exist yet are new, even when altered
+	require.Equal(t, []migration.PlannedTable{
+		{Database: "migration_test_plantablestest", Table: "gralb", New: true},
+	}, planned[1].Tables)
+
+	// only statements that create or alter a table are considered
+	require.Empty(t, planned[2].Tables)
+
+	planned, err = migration.Plan(context.Background(), fullDSN(dbname), migrations, migration.WithoutPlanTableSizes())
+	require.NoError(t, err)
+	require.Len(t, planned, 3)
+	for _, pending := range planned {
+		require.Empty(t, pending.Tables)
+	}
+}
+
+func TestPlanRejectsDuplicateVersions(t *testing.T) {
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `SELECT 1`},
+		&migration.Definition{ID: 1, Up: `SELECT 2`},
+	}
+
+	_, err := migration.Plan(context.Background(), fullDSN("plantest"), migrations)
+	require.EqualError(t, err, "duplicate migration version 1")
+}
+
+func TestCheckPending(t *testing.T) {
+	dbname := "checkpendingtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	pending, err := migration.CheckPending(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, pending)
+	require.False(t, dbExists(dbname))
+
+	err = migration.CheckNoPending(context.Background(), fullDSN(dbname), migrations)
+	require.True(t, errors.Is(err, migration.ErrPendingMigrations), "got %v", err)
+	require.EqualError(t, err, "migrations [1 2] haven't been executed: pending migrations")
+
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	pending, err = migration.CheckPending(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+	require.NoError(t, migration.CheckNoPending(context.Background(), fullDSN(dbname), migrations))
+}
@@ -0,0 +1,75 @@
+package migration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+var dumpedVersionPattern = regexp.MustCompile(`(?m)^\((\d+),`)
+
+// checkSchemaDir looks for signs of an interrupted dump before LoadSchema
+// touches the database: files that end part way through a statement, or a
+// migration history without any tables to go with it. Every problem found is
+// reported in the one error.
+func (m *Migrator) checkSchemaDir(location string) error {
+	files, err := ioutil.ReadDir(location)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading dir %q", location)
+	}
+
+	historyFile := m.tableName + ".sql"
+	problems := []string{}
+	tables := 0
+	maxVersion := 0
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		schema, err := ioutil.ReadFile(filepath.Join(location, name))
+		if err != nil {
+			return errors.Wrapf(err, "unable to read %q", name)
+		}
+
+		if err := dialect.CheckComplete(string(schema)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, err))
+		}
+
+		if name != historyFile {
+			if !m.isTrackingTable(strings.TrimSuffix(name, ".sql")) {
+				tables++
+			}
+			continue
+		}
+
+		versions := dumpedVersionPattern.FindAllStringSubmatch(string(schema), -1)
+		if len(versions) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: no migration versions", name))
+		}
+		for _, match := range versions {
+			version, err := strconv.Atoi(match[1])
+			if err == nil && version > maxVersion {
+				maxVersion = version
+			}
+		}
+	}
+
+	if maxVersion > 0 && tables == 0 {
+		problems = append(problems, fmt.Sprintf("%s records migrations up to %d but there are no table files", historyFile, maxVersion))
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("schema in %q looks incomplete, not loading it: %s", location, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
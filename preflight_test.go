@@ -0,0 +1,53 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSchemaRejectsTruncatedFiles(t *testing.T) {
+	dbname := "truncatedschematest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/truncatedschematest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	must(ioutil.WriteFile(dir+"/blarg.sql", []byte("CREATE TABLE `blarg` (\n  `id` int(11) NOT NULL,\n  `name` varchar(64) DEFAULT 'it''s'"), 0644))
+	must(ioutil.WriteFile(dir+"/gralb.sql", []byte("CREATE TABLE `gralb` (\n  `di` int(11) NOT NULL COMMENT 'half"), 0644))
+	must(ioutil.WriteFile(dir+"/_migrations.sql", []byte("INSERT INTO _migrations (id, created_at) VALUES\n(1, \"2019-01-01 00:00:00\"),\n(2, \"2019-01-01 00:00:00\")"), 0644))
+
+	err := migration.LoadSchema(context.Background(), fullDSN(dbname), dir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "blarg.sql: unbalanced parentheses")
+	require.Contains(t, err.Error(), "gralb.sql: unterminated ' quoted string")
+	require.False(t, dbExists(dbname))
+}
+
+func TestLoadSchemaRejectsHistoryWithoutTables(t *testing.T) {
+	dbname := "historyonlyschematest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/historyonlyschematest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	must(ioutil.WriteFile(dir+"/_migrations.sql", []byte("INSERT INTO _migrations (id, created_at) VALUES\n(1, \"2019-01-01 00:00:00\"),\n(7, \"2019-01-01 00:00:00\")"), 0644))
+
+	err := migration.LoadSchema(context.Background(), fullDSN(dbname), dir)
+	require.EqualError(t, err, fmt.Sprintf(
+		"schema in %q looks incomplete, not loading it: _migrations.sql records migrations up to 7 but there are no table files",
+		dir,
+	))
+	require.False(t, dbExists(dbname))
+}
@@ -0,0 +1,183 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+const (
+	errOptionPreventsStatement = 1290 // ER_OPTION_PREVENTS_STATEMENT
+	errReadOnlyTransaction     = 1792 // ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION
+)
+
+// ErrReadOnly matches a ReadOnlyError with errors.Is.
+var ErrReadOnly = errors.New("read-only server")
+
+// ReadOnlyError is returned when Migrate connects to a server that can't be
+// written to, such as a replica or an Aurora writer part way through failing
+// over.
+type ReadOnlyError struct {
+	Server string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("connected to a read-only replica at %s, migrations have to be run against the primary", e.Server)
+}
+
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == ErrReadOnly
+}
+
+// WithFailoverRetry runs Migrate again up to attempts times in all when the
+// server turns read-only part way through, as an Aurora writer does while it
+// fails over, waiting backoff before the first retry and twice as long before
+// each one after it. Every attempt opens new connections, resolving the host
+// again, and carries on from the first migration that isn't applied. A
+// migration interrupted by the failover is only executed again when it's
+// idempotent, otherwise it's left dirty.
+func WithFailoverRetry(attempts int, backoff time.Duration) Option {
+	return func(m *Migrator) {
+		m.failoverAttempts = attempts
+		m.failoverBackoff = backoff
+	}
+}
+
+// isReadOnly reports whether err is down to the server being read-only.
+func isReadOnly(err error) bool {
+	if errors.Is(err, ErrReadOnly) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	// 1290 is also returned for options other than read_only
+	return mysqlErr.Number == errReadOnlyTransaction ||
+		(mysqlErr.Number == errOptionPreventsStatement && strings.Contains(mysqlErr.Message, "read-only"))
+}
+
+// checkWritable fails with a ReadOnlyError when the server is read-only, so
+// that Migrate stops before executing anything rather than part way through.
+func (m *Migrator) checkWritable(ctx context.Context) error {
+	conn, err := m.src.Server(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var readOnly, innodbReadOnly bool
+	err = conn.QueryRowContext(ctx, "SELECT @@global.read_only, @@global.innodb_read_only").Scan(&readOnly, &innodbReadOnly)
+	if err != nil {
+		return errors.Wrapf(err, "failed checking if %s is read-only", m.serverLabel())
+	}
+	if readOnly || innodbReadOnly {
+		return &ReadOnlyError{Server: m.serverLabel()}
+	}
+	return nil
+}
+
+// migrateWithFailoverRetry runs migrateOnce, retrying it WithFailoverRetry.
+func (m *Migrator) migrateWithFailoverRetry(ctx context.Context, migrations []Migration, report *Report) (started bool, err error) {
+	start := time.Now()
+	backoff := m.failoverBackoff
+	since := time.Time{}
+	for attempt := 1; ; attempt++ {
+		attemptStarted, err := m.migrateOnce(ctx, migrations, report, since)
+		started = started || attemptStarted
+		if err == nil || attempt >= m.failoverAttempts || !isReadOnly(err) {
+			report.Skipped = withoutApplied(report.Skipped, report.Applied)
+			return started, err
+		}
+
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("%s turned read-only on attempt %d, reconnecting in %s as it may be failing over: %s", m.serverLabel(), attempt, backoff, err),
+			slog.String("db", m.src.DBName),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+		if err := sleep(ctx, backoff); err != nil {
+			return started, errors.Wrapf(err, "gave up waiting to reconnect to %s", m.serverLabel())
+		}
+		backoff *= 2
+		if since.IsZero() {
+			since = start
+		}
+
+		// worked out again from scratch on the next attempt
+		report.Skipped = []int{}
+		report.Filtered = []int{}
+	}
+}
+
+// withoutApplied drops the versions applied by an earlier attempt from those
+// a later one skipped.
+func withoutApplied(skipped []int, applied []AppliedMigration) []int {
+	kept := []int{}
+	for _, version := range skipped {
+		found := false
+		for _, migration := range applied {
+			found = found || migration.Version == version
+		}
+		if !found {
+			kept = append(kept, version)
+		}
+	}
+	return kept
+}
+
+// clearFailedOver forgets the idempotent migrations left dirty by an attempt
+// that failed over, which are those started since the run began, so they're
+// executed again. Any other dirty migration is left for readApplied to refuse.
+func (m *Migrator) clearFailedOver(ctx context.Context, conn *sql.DB, migrations []Migration, since time.Time) error {
+	rows, err := conn.QueryContext(ctx,
+		fmt.Sprintf("SELECT id FROM %s WHERE dirty = 1 AND created_at >= ?", dialect.QuoteIdentifier(m.tableName)),
+		since.UTC().Format(createdAtWriteFormat),
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading migrations interrupted by the failover from %q", m.tableName)
+	}
+	dirty := []int{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return errors.Wrapf(err, "failed reading migrations interrupted by the failover from %q", m.tableName)
+		}
+		dirty = append(dirty, version)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrapf(err, "failed reading migrations interrupted by the failover from %q", m.tableName)
+	}
+
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version()] = migration
+	}
+
+	for _, version := range dirty {
+		migration, ok := byVersion[version]
+		if !ok || !migrationIdempotent(migration) {
+			continue
+		}
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ? AND dirty = 1", dialect.QuoteIdentifier(m.tableName)), version)
+		if err != nil {
+			return errors.Wrapf(err, "failed clearing migration %d interrupted by the failover", version)
+		}
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("executing migration %d again as it was interrupted by the failover", version),
+			slog.Int("version", version),
+			slog.String("db", m.src.DBName),
+		)
+	}
+	return nil
+}
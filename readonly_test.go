@@ -0,0 +1,70 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+var errReadOnly = &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}
+
+func TestWithFailoverRetry(t *testing.T) {
+	dbname := "failovertest"
+	dropDB(dbname)
+
+	flaky := &flakyMigration{failures: 1, err: errReadOnly, idempotent: true}
+	migrations := []migration.Migration{
+		flaky,
+		&migration.Definition{ID: 2, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	logger := &capturingLogger{}
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithFailoverRetry(3, 10*time.Millisecond),
+		migration.WithLogger(logger),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 2, flaky.calls)
+	require.Len(t, report.Applied, 2)
+	require.Empty(t, report.Skipped)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+
+	require.Len(t, findLines(logger.lines, "executing migration 1 again as it was interrupted by the failover"), 1)
+}
+
+func TestWithFailoverRetryLeavesOtherMigrationsDirty(t *testing.T) {
+	dbname := "failovertest"
+	dropDB(dbname)
+
+	flaky := &flakyMigration{failures: 1, err: errReadOnly}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), []migration.Migration{flaky},
+		migration.WithFailoverRetry(3, 10*time.Millisecond),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.True(t, errors.Is(err, migration.ErrDirty), "got %v", err)
+	require.Equal(t, 1, flaky.calls)
+}
+
+func TestReadOnlyWithoutFailoverRetry(t *testing.T) {
+	dbname := "failovertest"
+	dropDB(dbname)
+
+	flaky := &flakyMigration{failures: 1, err: errReadOnly, idempotent: true}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), []migration.Migration{flaky}, migration.WithLogger(migration.NopLogger{}))
+
+	var mysqlErr *mysql.MySQLError
+	require.True(t, errors.As(err, &mysqlErr), "got %v", err)
+	require.Equal(t, uint16(1290), mysqlErr.Number)
+	require.Equal(t, 1, flaky.calls)
+}
+
+func TestReadOnlyError(t *testing.T) {
+	err := error(&migration.ReadOnlyError{Server: "db.example.com:3306"})
+	require.EqualError(t, err, "connected to a read-only replica at db.example.com:3306, migrations have to be run against the primary")
+	require.True(t, errors.Is(err, migration.ErrReadOnly))
+}
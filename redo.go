@@ -0,0 +1,171 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// Reverter is implemented by migrations that can undo themselves for Redo,
+// for those other than Definition which has a Down field.
+type Reverter interface {
+	Revert(ctx context.Context, conn *sql.DB) error
+}
+
+// WithForceRedo lets Redo redo a migration that later applied migrations may
+// depend on.
+func WithForceRedo() Option {
+	return func(m *Migrator) {
+		m.forceRedo = true
+	}
+}
+
+func Redo(ctx context.Context, dsn string, migrations []Migration, version int, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return err
+	}
+	return m.Redo(ctx, migrations, version)
+}
+
+func RedoConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, version int, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return err
+	}
+	return m.Redo(ctx, migrations, version)
+}
+
+func RedoSource(ctx context.Context, src Source, migrations []Migration, version int, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return err
+	}
+	return m.Redo(ctx, migrations, version)
+}
+
+// Redo reverts an applied migration when it can be reverted, deletes its
+// record and executes it again, e.g. while iterating on the latest migration
+// in development. It refuses to redo a migration with later migrations
+// applied unless WithForceRedo is used. It always holds the advisory lock,
+// waiting up to a minute for it unless WithLock says otherwise.
+func (m *Migrator) Redo(ctx context.Context, migrations []Migration, version int) error {
+	if err := validateMigrations(migrations); err != nil {
+		return err
+	}
+
+	var migration Migration
+	for _, candidate := range migrations {
+		if candidate.Version() == version {
+			migration = candidate
+		}
+	}
+	if migration == nil {
+		return errors.Errorf("can't redo migration %d, it isn't one of the migrations given", version)
+	}
+
+	if err := m.validateMetadata([]Migration{migration}); err != nil {
+		return err
+	}
+
+	conn, err := m.openIfExists(ctx)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return &NotRecordedError{Version: version}
+	}
+	defer conn.Close()
+
+	// a Migrate run between deleting the record and executing the migration
+	// again would execute it too, so the lock is always taken
+	locker := *m
+	if !m.lock {
+		locker.lockTimeout = defaultLockTimeout
+	}
+	unlock, err := locker.acquireLock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	exists, err := m.migrationsTableExists(ctx, conn)
+	if err != nil {
+		return errors.Wrapf(err, "failed checking if table %q exists", m.tableName)
+	}
+	if !exists {
+		return &NotRecordedError{Version: version}
+	}
+
+	applied, err := m.appliedVersionSet(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if !applied[version] {
+		return &NotRecordedError{Version: version}
+	}
+
+	later := []int{}
+	for v := range applied {
+		if v > version {
+			later = append(later, v)
+		}
+	}
+	if len(later) > 0 && !m.forceRedo {
+		sort.Ints(later)
+		return errors.Errorf("can't redo migration %d, migrations %v have been applied since, use WithForceRedo to redo it anyway", version, later)
+	}
+
+	// the Up is executed again, so it's held to the same policy as a pending
+	// migration
+	if err := m.checkPendingStatements(ctx, []Migration{migration}, map[int]bool{}); err != nil {
+		return err
+	}
+
+	if err := revertMigration(ctx, conn, migration); err != nil {
+		return errors.Wrapf(err, "failed reverting migration %d", version)
+	}
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", dialect.QuoteIdentifier(m.tableName)), version)
+	if err != nil {
+		return errors.Wrapf(err, "failed deleting migration %d from %q", version, m.tableName)
+	}
+
+	redone, err := m.executeMigration(ctx, conn, migration, m.newBinlogTracker(conn))
+	if err != nil {
+		return err
+	}
+
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("redid migration %s in %s", describeMigration(migration), redone.Duration),
+		slog.Int("version", version),
+		slog.String("name", migrationName(migration)),
+		slog.String("db", m.src.DBName),
+		slog.Duration("duration", redone.Duration),
+	)
+
+	return nil
+}
+
+// revertMigration undoes migration if it knows how to, and does nothing
+// otherwise.
+func revertMigration(ctx context.Context, conn *sql.DB, migration Migration) error {
+	switch reverter := migration.(type) {
+	case *Definition:
+		if len(reverter.DownFile) > 0 {
+			return execFile(ctx, conn, reverter.DownFile)
+		}
+		if len(reverter.Down) > 0 {
+			return execStatements(ctx, conn, dialect.SplitStatements(reverter.Down))
+		}
+	case Reverter:
+		return reverter.Revert(ctx, conn)
+	}
+	return nil
+}
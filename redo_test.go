@@ -0,0 +1,183 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedoRevertsAndReappliesMigration(t *testing.T) {
+	dbname := "redotest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:   1,
+			Up:   `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Down: `DROP TABLE blarg`,
+		},
+		&migration.Definition{
+			ID:   2,
+			Up:   `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+			Down: `DROP TABLE gralb`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	// tweaked after it was applied
+	migrations[1].(*migration.Definition).Up = `CREATE TABLE gralb ( di BIGINT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`
+
+	err := migration.Redo(context.Background(), fullDSN(dbname), migrations, 2, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, "bigint", columnType(fullDSN(dbname), "gralb", "di"))
+	require.Equal(t, migrations[1].(*migration.Definition).Checksum(), queryChecksum(fullDSN(dbname), 2).String)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+
+	// and the next run is happy with the tweaked checksum
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	err = migration.Redo(context.Background(), fullDSN(dbname), migrations, 1, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "can't redo migration 1, migrations [2] have been applied since, use WithForceRedo to redo it anyway")
+
+	err = migration.Redo(context.Background(), fullDSN(dbname), migrations, 1, migration.WithForceRedo(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+}
+
+func TestRedoWithReverter(t *testing.T) {
+	dbname := "redotest"
+	dropDB(dbname)
+
+	redo := &redoMigration{}
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		redo,
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	err := migration.Redo(context.Background(), fullDSN(dbname), migrations, 2, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, 1, redo.reverted)
+	require.Equal(t, 2, redo.migrated)
+
+	// Redo holds the lock without WithLock, unlike Migrate
+	require.Equal(t, []bool{true}, redo.revertedLocked)
+	require.Equal(t, []bool{false, true}, redo.migratedLocked)
+}
+
+func TestRedoWithMultipleDownStatements(t *testing.T) {
+	dbname := "redomultitest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+			Down: `DROP TABLE gralb;
+DROP TABLE blarg`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	err := migration.Redo(context.Background(), fullDSN(dbname), migrations, 1, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.True(t, tableExists(fullDSN(dbname), "blarg"))
+	require.True(t, tableExists(fullDSN(dbname), "gralb"))
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+}
+
+func TestRedoChecksStatementsLikePendingMigrations(t *testing.T) {
+	dbname := "redochecktest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:   1,
+			Up:   `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Down: `DROP TABLE blarg`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	// changed locally after it was applied
+	migrations[0].(*migration.Definition).Up = `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+DROP TABLE IF EXISTS gralb`
+
+	err := migration.Redo(context.Background(), fullDSN(dbname), migrations, 1, migration.WithLogger(migration.NopLogger{}))
+	var destructive *migration.DestructiveError
+	require.True(t, errors.As(err, &destructive), "got %v", err)
+	require.Equal(t, []migration.DestructiveStatement{{Version: 1, Kind: "DROP TABLE", SQL: "DROP TABLE IF EXISTS gralb"}}, destructive.Statements)
+
+	err = migration.Redo(context.Background(), fullDSN(dbname), migrations, 1,
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithAllowDestructive(),
+		migration.WithValidator(func(version int, sql string) error {
+			if strings.Contains(sql, "gralb") {
+				return errors.New("gralb is owned by another service")
+			}
+			return nil
+		}),
+	)
+	require.EqualError(t, err, "migration 1 failed validation (DROP TABLE IF EXISTS gralb): gralb is owned by another service")
+
+	// nothing was reverted
+	require.Equal(t, "varchar", columnType(fullDSN(dbname), "blarg", "name"))
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+}
+
+type redoMigration struct {
+	migrated       int
+	reverted       int
+	migratedLocked []bool
+	revertedLocked []bool
+}
+
+func (r *redoMigration) Version() int {
+	return 2
+}
+
+func (r *redoMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	r.migrated++
+	r.migratedLocked = append(r.migratedLocked, lockHeld(ctx, conn))
+	return nil
+}
+
+func (r *redoMigration) Revert(ctx context.Context, conn *sql.DB) error {
+	r.reverted++
+	r.revertedLocked = append(r.revertedLocked, lockHeld(ctx, conn))
+	return nil
+}
+
+// lockHeld reports whether the advisory lock for the connection's database
+// and the default tracking table is held.
+func lockHeld(ctx context.Context, conn *sql.DB) bool {
+	var owner sql.NullInt64
+	must(conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(CONCAT('migration:', DATABASE(), '._migrations'))").Scan(&owner))
+	return owner.Valid
+}
+
+func columnType(dsn string, table string, column string) string {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var dataType string
+	must(conn.QueryRow(
+		"SELECT DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		table, column,
+	).Scan(&dataType))
+	return dataType
+}
@@ -0,0 +1,149 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// Repeatable is a migration identified by name rather than version, executed
+// again whenever Up changes, for views, stored procedures and functions where
+// the latest definition wins. Up should replace what an earlier version of it
+// created, e.g. with CREATE OR REPLACE VIEW.
+type Repeatable struct {
+	Name string
+	Up   string
+}
+
+// Checksum is the hex encoded SHA-256 of Up.
+func (r *Repeatable) Checksum() string {
+	sum := sha256.Sum256([]byte(r.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// WithRepeatables makes Migrate execute each of repeatables whose Up has
+// changed since it was last executed, in the order given, once every
+// versioned migration has been applied. They're tracked in a table named
+// after the tracking table with a _repeatable suffix.
+func WithRepeatables(repeatables ...*Repeatable) Option {
+	return func(m *Migrator) {
+		m.repeatables = append(m.repeatables, repeatables...)
+	}
+}
+
+const repeatableSuffix = "_repeatable"
+
+func (m *Migrator) repeatableTableName() string {
+	return m.tableName + repeatableSuffix
+}
+
+func validateRepeatables(repeatables []*Repeatable) error {
+	names := make(map[string]bool, len(repeatables))
+	for _, repeatable := range repeatables {
+		switch {
+		case len(repeatable.Name) == 0:
+			return errors.New("repeatable migrations must have a name")
+		case len(repeatable.Name) > maxNameLength:
+			return errors.Errorf("name of repeatable migration %q is longer than %d characters", repeatable.Name, maxNameLength)
+		case names[repeatable.Name]:
+			return errors.Errorf("duplicate repeatable migration %q", repeatable.Name)
+		case len(strings.TrimSpace(repeatable.Up)) == 0:
+			return errors.Errorf("repeatable migration %q has no Up", repeatable.Name)
+		}
+		names[repeatable.Name] = true
+	}
+	return nil
+}
+
+// runRepeatables executes the repeatables whose checksum differs from the one
+// recorded when they were last executed.
+func (m *Migrator) runRepeatables(ctx context.Context, conn *sql.DB, report *Report) error {
+	if len(m.repeatables) == 0 {
+		return nil
+	}
+
+	if err := m.createRepeatableTableIfNotExists(ctx, conn); err != nil {
+		return err
+	}
+
+	recorded, err := m.readRepeatables(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for _, repeatable := range m.repeatables {
+		checksum := repeatable.Checksum()
+		if recorded[repeatable.Name] == checksum {
+			continue
+		}
+
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("executing repeatable migration %q", repeatable.Name),
+			slog.String("repeatable", repeatable.Name),
+			slog.String("db", m.src.DBName),
+		)
+
+		if err := execStatements(ctx, conn, dialect.SplitStatements(repeatable.Up)); err != nil {
+			return errors.Wrapf(err, "failed executing repeatable migration %q", repeatable.Name)
+		}
+
+		_, err := conn.ExecContext(ctx,
+			fmt.Sprintf(
+				"INSERT INTO %s (name, checksum, applied_at, applied_by) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE checksum = VALUES(checksum), applied_at = VALUES(applied_at), applied_by = VALUES(applied_by)",
+				dialect.QuoteIdentifier(m.repeatableTableName()),
+			),
+			repeatable.Name, checksum, time.Now().UTC().Format(createdAtWriteFormat), m.appliedBy,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed recording repeatable migration %q", repeatable.Name)
+		}
+
+		report.Repeated = append(report.Repeated, repeatable.Name)
+	}
+
+	return nil
+}
+
+func (m *Migrator) readRepeatables(ctx context.Context, conn *sql.DB) (map[string]string, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT name, checksum FROM %s", dialect.QuoteIdentifier(m.repeatableTableName())))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading repeatable migrations from %q", m.repeatableTableName())
+	}
+	defer rows.Close()
+
+	recorded := map[string]string{}
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return nil, errors.Wrapf(err, "failed reading repeatable migrations from %q", m.repeatableTableName())
+		}
+		recorded[name] = checksum
+	}
+	return recorded, rows.Err()
+}
+
+func (m *Migrator) createRepeatableTableIfNotExists(ctx context.Context, conn *sql.DB) error {
+	_, err := conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s (
+				name VARCHAR(%d) NOT NULL,
+				checksum CHAR(64) NOT NULL,
+				applied_at DATETIME(6) NOT NULL,
+				applied_by VARCHAR(255) NOT NULL DEFAULT '',
+				PRIMARY KEY (name)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci`,
+			dialect.QuoteIdentifier(m.repeatableTableName()),
+			maxNameLength,
+		),
+	)
+	return errors.Wrapf(err, "failed creating table %q", m.repeatableTableName())
+}
@@ -0,0 +1,112 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeatables(t *testing.T) {
+	dbname := "repeatabletest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	view := &migration.Repeatable{
+		Name: "blarg_names",
+		Up:   `CREATE OR REPLACE VIEW blarg_names AS SELECT name FROM blarg`,
+	}
+	procedure := &migration.Repeatable{
+		Name: "add_blarg",
+		Up: `DROP PROCEDURE IF EXISTS add_blarg;
+			CREATE PROCEDURE add_blarg(IN blarg_id INT) INSERT INTO blarg (id, name) VALUES (blarg_id, 'added')`,
+	}
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithRepeatables(view, procedure),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"blarg_names", "add_blarg"}, report.Repeated)
+
+	execSQL(fullDSN(dbname), "CALL add_blarg(1)")
+	require.Equal(t, "added", queryBlargView(fullDSN(dbname)))
+
+	report, err = migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithRepeatables(view, procedure),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Empty(t, report.Repeated)
+
+	view.Up = `CREATE OR REPLACE VIEW blarg_names AS SELECT UPPER(name) AS name FROM blarg`
+	report, err = migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithRepeatables(view, procedure),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"blarg_names"}, report.Repeated)
+	require.Equal(t, "ADDED", queryBlargView(fullDSN(dbname)))
+}
+
+func TestRepeatablesRunAfterVersionedMigrations(t *testing.T) {
+	dbname := "repeatabletest"
+	dropDB(dbname)
+
+	view := &migration.Repeatable{
+		Name: "blarg_names",
+		Up:   `CREATE OR REPLACE VIEW blarg_names AS SELECT name FROM blarg`,
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), []migration.Migration{},
+		migration.WithRepeatables(view),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `failed executing repeatable migration "blarg_names"`)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithRepeatables(view),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.True(t, tableExists(fullDSN(dbname), "blarg_names"))
+}
+
+func TestInvalidRepeatables(t *testing.T) {
+	err := migration.Migrate(context.Background(), fullDSN("repeatabletest"), []migration.Migration{},
+		migration.WithRepeatables(
+			&migration.Repeatable{Name: "blarg_names", Up: "SELECT 1"},
+			&migration.Repeatable{Name: "blarg_names", Up: "SELECT 2"},
+		),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, `duplicate repeatable migration "blarg_names"`)
+}
+
+func queryBlargView(dsn string) string {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var name string
+	must(conn.QueryRow("SELECT name FROM blarg_names").Scan(&name))
+	return name
+}
@@ -0,0 +1,49 @@
+package migration
+
+import "time"
+
+// Report describes what a Migrate run did.
+type Report struct {
+	Applied []AppliedMigration
+	// Skipped lists the versions that had already been executed.
+	Skipped []int
+	// Filtered lists the pending versions that weren't executed as none of
+	// their tags were enabled, see WithTags.
+	Filtered []int
+	// Repeated lists the repeatable migrations that were executed, see
+	// WithRepeatables.
+	Repeated []string
+	// Seeded lists the seeds that were executed, see WithSeeds.
+	Seeded        []string
+	TotalDuration time.Duration
+	// Binlog totals the binlog written by the applied migrations that it
+	// could be measured for. GTIDs isn't totalled.
+	Binlog BinlogDelta
+	// DryRun is set when the run was made WithDryRun, in which case Applied
+	// is empty and Pending lists what would have been executed.
+	DryRun  bool
+	Pending []PlannedMigration
+}
+
+type AppliedMigration struct {
+	Version   int
+	StartedAt time.Time
+	Duration  time.Duration
+	// Binlog is nil when the binlog position couldn't be read, e.g. binary
+	// logging is off or the user lacks REPLICATION CLIENT.
+	Binlog *BinlogDelta
+	// RowsAffected is the rows a Chunked migration affected, and 0 for
+	// other migrations.
+	RowsAffected int64
+	// Metadata is what was recorded alongside the migration, see
+	// WithRunMetadata.
+	Metadata map[string]string
+}
+
+func (r *Report) add(applied AppliedMigration) {
+	r.Applied = append(r.Applied, applied)
+	if applied.Binlog != nil {
+		r.Binlog.Bytes += applied.Binlog.Bytes
+		r.Binlog.Transactions += applied.Binlog.Transactions
+	}
+}
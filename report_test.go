@@ -0,0 +1,74 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateWithReport(t *testing.T) {
+	dbname := "reporttest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(report.Applied))
+	require.Equal(t, 1, report.Applied[0].Version)
+	require.Equal(t, 2, report.Applied[1].Version)
+	require.False(t, report.Applied[0].StartedAt.IsZero())
+	require.Empty(t, report.Skipped)
+	require.True(t, report.TotalDuration > 0)
+
+	migrations = append(migrations,
+		&migration.Definition{
+			ID: 3,
+			Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(255) NOT NULL`,
+		},
+		&migration.Definition{
+			ID: 4,
+			Up: `THIS IS NOT SQL`,
+		},
+	)
+
+	report, err = migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations)
+	require.Error(t, err)
+	require.Equal(t, 1, len(report.Applied))
+	require.Equal(t, 3, report.Applied[0].Version)
+	require.Equal(t, []int{1, 2}, report.Skipped)
+}
+
+func TestMigrateWithReportIncludesMetadata(t *testing.T) {
+	dbname := "reportmetadatatest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:       1,
+			Up:       `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Metadata: map[string]string{"ticket": "OPS-1"},
+		},
+	}
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithRunMetadata(map[string]string{"deploy": "42", "ticket": "OPS-0"}),
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, map[string]string{"deploy": "42", "ticket": "OPS-1"}, report.Applied[0].Metadata)
+	require.Equal(t, report.Applied[0].Metadata, queryMetadata(fullDSN(dbname), 1))
+}
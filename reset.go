@@ -0,0 +1,122 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// NotRecordedError is returned by ResetVersion when the version isn't
+// recorded as applied.
+type NotRecordedError struct {
+	Version int
+}
+
+func (e *NotRecordedError) Error() string {
+	return fmt.Sprintf("migration %d isn't recorded as applied", e.Version)
+}
+
+// WithConfirmReset confirms that ResetVersion should delete the record of a
+// migration, which it otherwise refuses to do.
+func WithConfirmReset() Option {
+	return func(m *Migrator) {
+		m.confirmReset = true
+	}
+}
+
+func ResetVersion(ctx context.Context, dsn string, version int, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return err
+	}
+	return m.ResetVersion(ctx, version)
+}
+
+func ResetVersionConfig(ctx context.Context, cfg *mysql.Config, version int, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return err
+	}
+	return m.ResetVersion(ctx, version)
+}
+
+func ResetVersionSource(ctx context.Context, src Source, version int, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return err
+	}
+	return m.ResetVersion(ctx, version)
+}
+
+// ResetVersion deletes the record of version having been applied, so that
+// the next Migrate executes it again, e.g. after it failed part way through
+// DDL that couldn't be rolled back. It doesn't undo anything the migration
+// did. It requires WithConfirmReset, and the next Migrate needs
+// WithAllowOutOfOrder when version isn't the latest applied.
+func (m *Migrator) ResetVersion(ctx context.Context, version int) error {
+	if !m.confirmReset {
+		return errors.Errorf("refusing to reset migration %d without WithConfirmReset", version)
+	}
+
+	conn, err := m.openIfExists(ctx)
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return &NotRecordedError{Version: version}
+	}
+	defer conn.Close()
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	exists, err := m.migrationsTableExists(ctx, conn)
+	if err != nil {
+		return errors.Wrapf(err, "failed checking if table %q exists", m.tableName)
+	}
+	if !exists {
+		return &NotRecordedError{Version: version}
+	}
+
+	history, err := m.readHistory(ctx, conn)
+	if err != nil {
+		return err
+	}
+	var removed *historyRow
+	for i := range history {
+		if history[i].ID == version {
+			removed = &history[i]
+		}
+	}
+	if removed == nil {
+		return &NotRecordedError{Version: version}
+	}
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", dialect.QuoteIdentifier(m.tableName)), version)
+	if err != nil {
+		return errors.Wrapf(err, "failed deleting migration %d from %q", version, m.tableName)
+	}
+
+	m.log(ctx, slog.LevelWarn,
+		fmt.Sprintf(
+			"RESET migration %d in db %q, it will be executed again by the next run (name %q, applied at %s by %q)",
+			version, m.src.DBName, removed.Name, removed.CreatedAt.Format(createdAtWriteFormat), removed.AppliedBy,
+		),
+		slog.String("db", m.src.DBName),
+		slog.Int("version", version),
+		slog.String("name", removed.Name),
+		slog.Time("created_at", removed.CreatedAt),
+		slog.String("applied_by", removed.AppliedBy),
+	)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetVersionReExecutesMigration(t *testing.T) {
+	dbname := "resettest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	err := migration.ResetVersion(context.Background(), fullDSN(dbname), 2, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "refusing to reset migration 2 without WithConfirmReset")
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+
+	err = migration.ResetVersion(context.Background(), fullDSN(dbname), 3, migration.WithConfirmReset(), migration.WithLogger(migration.NopLogger{}))
+	var notRecorded *migration.NotRecordedError
+	require.True(t, errors.As(err, &notRecorded), "got %v", err)
+	require.Equal(t, 3, notRecorded.Version)
+
+	// the half applied migration is undone by hand before resetting it
+	execSQL(fullDSN(dbname), "DROP TABLE gralb")
+	err = migration.ResetVersion(context.Background(), fullDSN(dbname), 2, migration.WithConfirmReset(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, 2, report.Applied[0].Version)
+	require.True(t, tableExists(fullDSN(dbname), "gralb"))
+}
+
+func TestResetVersionOfOlderMigrationNeedsAllowOutOfOrder(t *testing.T) {
+	dbname := "resetoldertest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 3, Up: `CREATE TABLE bralg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	execSQL(fullDSN(dbname), "DROP TABLE gralb")
+	err := migration.ResetVersion(context.Background(), fullDSN(dbname), 2, migration.WithConfirmReset(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "migrations [2] are older than the latest applied migration 3, give them a newer version or use WithAllowOutOfOrder")
+	require.False(t, tableExists(fullDSN(dbname), "gralb"))
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithAllowOutOfOrder(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, 2, report.Applied[0].Version)
+	require.True(t, tableExists(fullDSN(dbname), "gralb"))
+	require.Len(t, queryVersions(fullDSN(dbname)), 3)
+}
@@ -0,0 +1,253 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// ResumableMigration executes an UPDATE or DELETE over a table one range of
+// keys at a time, picking up where it stopped when it's interrupted, see
+// Resumable.
+type ResumableMigration struct {
+	version   int
+	table     string
+	key       string
+	query     string
+	batchSize int
+	chunking
+}
+
+// Resumable returns a migration walking table in key order, batchSize rows at
+// a time, executing query with the first and last key of each batch, e.g.
+//
+//	UPDATE orders SET total = price * quantity WHERE id BETWEEN ? AND ?
+//
+// The last key of each batch is recorded in a table named after the tracking
+// table with a _progress suffix, in the same transaction as the batch. A run
+// that's interrupted isn't left dirty, and the next Migrate continues after
+// the last batch that was committed. key should be the table's primary key.
+func Resumable(version int, table, key, query string, batchSize int, opts ...ChunkOption) *ResumableMigration {
+	r := &ResumableMigration{version: version, table: table, key: key, query: query, batchSize: batchSize}
+	for _, opt := range opts {
+		opt(&r.chunking)
+	}
+	return r
+}
+
+func (r *ResumableMigration) Version() int {
+	return r.version
+}
+
+func (r *ResumableMigration) Name() string {
+	return r.name
+}
+
+// Idempotent is true, as it continues from the last batch that was committed.
+func (r *ResumableMigration) Idempotent() bool {
+	return true
+}
+
+func (r *ResumableMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	e := executionFrom(ctx)
+	switch {
+	case e == nil || e.m == nil:
+		return errors.Errorf("resumable migration %d has to be executed by Migrate, which tracks its progress", r.version)
+	case r.batchSize <= 0:
+		return errors.Errorf("batch size of resumable migration %d is %d, it has to be above 0", r.version, r.batchSize)
+	case !tableNamePattern.MatchString(r.table):
+		return errors.Errorf("invalid table name %q", r.table)
+	case !tableNamePattern.MatchString(r.key):
+		return errors.Errorf("invalid key column %q", r.key)
+	}
+
+	m := e.m
+	if err := m.createProgressTableIfNotExists(ctx, conn); err != nil {
+		return err
+	}
+
+	cursor, err := m.readProgress(ctx, conn, r.version)
+	if err != nil {
+		return err
+	}
+	if cursor.Valid {
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("resuming migration %d after %s %s", r.version, r.key, cursor.String),
+			slog.Int("version", r.version),
+			slog.String("db", m.src.DBName),
+			slog.String("cursor", cursor.String),
+		)
+	}
+
+	start := time.Now()
+	var total int64
+	for batches := 1; ; batches++ {
+		first, last, err := r.nextBatch(ctx, conn, cursor)
+		if err != nil {
+			return errors.Wrapf(err, "failed reading batch %d from %s", batches, r.table)
+		}
+
+		if !first.Valid {
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = ?", dialect.QuoteIdentifier(m.progressTableName())), r.version); err != nil {
+				return errors.Wrapf(err, "failed clearing progress of migration %d", r.version)
+			}
+			logChunk(ctx, e, r.version, slog.LevelInfo,
+				fmt.Sprintf("resumable migration %d affected %d rows in %d batches", r.version, total, batches-1),
+				total, batches-1, start)
+			return nil
+		}
+
+		affected, err := r.execBatch(ctx, conn, m, first.String, last.String)
+		if err != nil {
+			return errors.Wrapf(err, "batch %d from %s %s to %s failed after %d rows", batches, r.key, first.String, last.String, total)
+		}
+		cursor = last
+		total += affected
+		e.rowsAffected += affected
+
+		logChunk(ctx, e, r.version, slog.LevelDebug,
+			fmt.Sprintf("resumable migration %d batch %d up to %s %s affected %d rows, %d so far", r.version, batches, r.key, last.String, affected, total),
+			total, batches, start)
+
+		if err := sleep(ctx, r.pause); err != nil {
+			return errors.Wrapf(err, "stopped after %s %s", r.key, last.String)
+		}
+	}
+}
+
+// nextBatch reads the first and last key of the batch after cursor, which are
+// both NULL once there are no rows left.
+func (r *ResumableMigration) nextBatch(ctx context.Context, conn *sql.DB, cursor sql.NullString) (first, last sql.NullString, err error) {
+	where, args := "", []interface{}{}
+	if cursor.Valid {
+		where, args = fmt.Sprintf("WHERE %s > ?", r.key), append(args, cursor.String)
+	}
+	err = conn.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT MIN(k), MAX(k) FROM (SELECT %s AS k FROM %s %s ORDER BY %s LIMIT %d) batch", r.key, r.table, where, r.key, r.batchSize),
+		args...,
+	).Scan(&first, &last)
+	return first, last, err
+}
+
+// execBatch executes the query over the keys from first to last, recording
+// last as the migration's progress in the same transaction.
+func (r *ResumableMigration) execBatch(ctx context.Context, conn *sql.DB, m *Migrator, first, last string) (int64, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, r.query, first, last)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (version, last_key, updated_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE last_key = VALUES(last_key), updated_at = VALUES(updated_at)", dialect.QuoteIdentifier(m.progressTableName())),
+		r.version, last, time.Now().UTC().Format(createdAtWriteFormat),
+	)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed recording progress of migration %d", r.version)
+	}
+
+	return affected, tx.Commit()
+}
+
+const progressSuffix = "_progress"
+
+func (m *Migrator) progressTableName() string {
+	return m.tableName + progressSuffix
+}
+
+func (m *Migrator) readProgress(ctx context.Context, conn *sql.DB, version int) (sql.NullString, error) {
+	var cursor sql.NullString
+	err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT last_key FROM %s WHERE version = ?", dialect.QuoteIdentifier(m.progressTableName())), version).Scan(&cursor)
+	if err == sql.ErrNoRows {
+		return cursor, nil
+	}
+	return cursor, errors.Wrapf(err, "failed reading progress of migration %d", version)
+}
+
+func (m *Migrator) createProgressTableIfNotExists(ctx context.Context, conn *sql.DB) error {
+	_, err := conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s (
+				version BIGINT NOT NULL,
+				last_key VARCHAR(255) NOT NULL,
+				updated_at DATETIME(6) NOT NULL,
+				PRIMARY KEY (version)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci`,
+			dialect.QuoteIdentifier(m.progressTableName()),
+		),
+	)
+	return errors.Wrapf(err, "failed creating table %q", m.progressTableName())
+}
+
+// checkDirty fails with a DirtyError for the first dirty version unless it's
+// a resumable migration with recorded progress. Those were left dirty by a
+// run that was killed before it could clear their record, and are continued
+// from their progress instead.
+func (m *Migrator) checkDirty(ctx context.Context, conn *sql.DB, dirty []int) error {
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	interrupted, err := m.interruptedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, version := range dirty {
+		if !interrupted[version] {
+			return &DirtyError{Version: version}
+		}
+	}
+	return nil
+}
+
+// interruptedVersions reads the versions of the resumable migrations that
+// have recorded progress.
+func (m *Migrator) interruptedVersions(ctx context.Context, conn *sql.DB) (map[int]bool, error) {
+	interrupted := map[int]bool{}
+
+	exists, err := oneExists(ctx, conn, "SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", m.progressTableName())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed checking if table %q exists", m.progressTableName())
+	}
+	if !exists {
+		return interrupted, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s", dialect.QuoteIdentifier(m.progressTableName())))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading progress from %q", m.progressTableName())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, errors.Wrapf(err, "failed reading progress from %q", m.progressTableName())
+		}
+		interrupted[version] = true
+	}
+	return interrupted, rows.Err()
+}
+
+// clearInterrupted deletes the dirty record a resumable migration was left
+// with by a run that was killed, or by versions of the package that recorded
+// it as started, before it continues from its progress.
+func (m *Migrator) clearInterrupted(ctx context.Context, conn *sql.DB, migration Migration) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ? AND dirty = 1", dialect.QuoteIdentifier(m.tableName)), migration.Version())
+	return errors.Wrapf(err, "failed clearing the record of interrupted migration %d", migration.Version())
+}
@@ -0,0 +1,140 @@
+package migration_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func resumableMigrations(pause time.Duration) []migration.Migration {
+	return []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			UpStatements: []string{
+				`CREATE TABLE orders ( id INT NOT NULL, price INT NOT NULL, quantity INT NOT NULL, total INT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+				`INSERT INTO orders (id, price, quantity) VALUES (1, 2, 3), (2, 4, 5), (3, 6, 7), (5, 8, 9), (8, 10, 11)`,
+			},
+		},
+		migration.Resumable(2, "orders", "id", `UPDATE orders SET total = price * quantity WHERE id BETWEEN ? AND ?`, 2,
+			migration.WithChunkName("backfill_order_totals"),
+			migration.WithChunkPause(pause),
+		),
+	}
+}
+
+func TestResumable(t *testing.T) {
+	dbname := "resumabletest"
+	dropDB(dbname)
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), resumableMigrations(0),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 2)
+	require.Equal(t, int64(5), report.Applied[1].RowsAffected)
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.False(t, oneExists(conn, "SELECT 1 FROM orders WHERE total IS NULL"))
+	require.True(t, oneExists(conn, "SELECT 1 FROM orders WHERE id = 8 AND total = 110"))
+	require.False(t, oneExists(conn, "SELECT 1 FROM _migrations_progress"))
+}
+
+func TestResumableContinuesAfterInterruption(t *testing.T) {
+	dbname := "resumabletest"
+	dropDB(dbname)
+
+	// the first batch is committed, then the run is cancelled while pausing
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := migration.Migrate(ctx, fullDSN(dbname), resumableMigrations(time.Hour), migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "stopped after id 2")
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+	versions := queryVersions(fullDSN(dbname))
+	require.Len(t, versions, 1)
+	require.Equal(t, 1, versions[0].ID)
+	require.True(t, oneExists(conn, "SELECT 1 FROM _migrations_progress WHERE version = 2 AND last_key = '2'"))
+	require.True(t, oneExists(conn, "SELECT 1 FROM orders WHERE id = 2 AND total = 20"))
+	require.True(t, oneExists(conn, "SELECT 1 FROM orders WHERE id = 3 AND total IS NULL"))
+
+	// rows already migrated are left alone when resuming
+	execSQL(fullDSN(dbname), "UPDATE orders SET total = 0 WHERE id <= 2")
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), resumableMigrations(0), migration.WithSlog(logger))
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, int64(3), report.Applied[0].RowsAffected)
+
+	resuming := findRecord(t, &buf, "resuming migration 2 after id 2")
+	require.Equal(t, "2", resuming["cursor"])
+	findRecord(t, &buf, "resumable migration 2 affected 3 rows in 2 batches")
+
+	require.True(t, oneExists(conn, "SELECT 1 FROM orders WHERE id = 2 AND total = 0"))
+	require.False(t, oneExists(conn, "SELECT 1 FROM orders WHERE total IS NULL"))
+	require.False(t, oneExists(conn, "SELECT 1 FROM _migrations_progress"))
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+}
+
+func TestResumableContinuesAfterBeingKilled(t *testing.T) {
+	dbname := "resumablekilledtest"
+	dropDB(dbname)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := migration.Migrate(ctx, fullDSN(dbname), resumableMigrations(time.Hour), migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+
+	// a process that's killed never clears the record, which older versions
+	// recorded as dirty before executing the migration
+	execSQL(fullDSN(dbname), "INSERT INTO _migrations (id, created_at, dirty) VALUES (2, UTC_TIMESTAMP(6), 1)")
+
+	planned, err := migration.Plan(context.Background(), fullDSN(dbname), resumableMigrations(0))
+	require.NoError(t, err)
+	require.Len(t, planned, 1)
+	require.Equal(t, 2, planned[0].Version)
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), resumableMigrations(0), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, int64(3), report.Applied[0].RowsAffected)
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.False(t, oneExists(conn, "SELECT 1 FROM orders WHERE total IS NULL"))
+	require.False(t, oneExists(conn, "SELECT 1 FROM _migrations WHERE dirty = 1"))
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+}
+
+func TestResumableLeftDirtyWithoutProgressIsDirty(t *testing.T) {
+	dbname := "resumabledirtytest"
+	dropDB(dbname)
+
+	migrations := resumableMigrations(0)
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations[:1], migration.WithLogger(migration.NopLogger{}))
+	execSQL(fullDSN(dbname), "INSERT INTO _migrations (id, created_at, dirty) VALUES (2, UTC_TIMESTAMP(6), 1)")
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	var dirty *migration.DirtyError
+	require.True(t, errors.As(err, &dirty), "got %v", err)
+	require.Equal(t, 2, dirty.Version)
+}
+
+func TestResumableNeedsMigrator(t *testing.T) {
+	err := migration.Resumable(1, "orders", "id", `DELETE FROM orders WHERE id BETWEEN ? AND ?`, 10).Migrate(context.Background(), nil)
+	require.EqualError(t, err, "resumable migration 1 has to be executed by Migrate, which tracks its progress")
+}
@@ -0,0 +1,83 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+const (
+	errLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+	errLockDeadlock    = 1213 // ER_LOCK_DEADLOCK
+)
+
+// Idempotent is implemented by migrations that say whether they're safe to
+// execute again after failing part way, for those other than Definition which
+// has an Idempotent field. Only idempotent migrations are retried.
+type Idempotent interface {
+	Idempotent() bool
+}
+
+// WithRetry executes idempotent migrations up to attempts times in all when
+// they fail with a deadlock or lock wait timeout, waiting backoff before the
+// first retry and twice as long before each one after it.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(m *Migrator) {
+		m.retryAttempts = attempts
+		m.retryBackoff = backoff
+	}
+}
+
+func migrationIdempotent(migration Migration) bool {
+	switch idempotent := migration.(type) {
+	case *Definition:
+		return idempotent.Idempotent
+	case Idempotent:
+		return idempotent.Idempotent()
+	}
+	return false
+}
+
+// isTransient reports whether err is worth retrying, as it's down to
+// contention with other transactions rather than the migration itself.
+func isTransient(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == errLockWaitTimeout || mysqlErr.Number == errLockDeadlock
+}
+
+// migrateWithRetry executes migration, retrying it WithRetry.
+func (m *Migrator) migrateWithRetry(ctx context.Context, conn *sql.DB, migration Migration) error {
+	backoff := m.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := migration.Migrate(ctx, conn)
+		if err == nil || attempt >= m.retryAttempts || !isTransient(err) || !migrationIdempotent(migration) {
+			return err
+		}
+
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("migration %d failed on attempt %d of %d, retrying in %s: %s", migration.Version(), attempt, m.retryAttempts, backoff, err),
+			slog.Int("version", migration.Version()),
+			slog.String("db", m.src.DBName),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("error", err),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrapf(ctx.Err(), "gave up retrying after attempt %d failed with %s", attempt, err)
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
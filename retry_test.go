@@ -0,0 +1,94 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetriesTransientErrors(t *testing.T) {
+	dbname := "retrytest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	flaky := &flakyMigration{failures: 2, err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, idempotent: true}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), []migration.Migration{flaky},
+		migration.WithRetry(3, time.Millisecond),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, flaky.calls)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+}
+
+func TestGivesUpRetrying(t *testing.T) {
+	cases := map[string]*flakyMigration{
+		"too many failures": {failures: 5, err: &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, idempotent: true},
+		"not transient":     {failures: 5, err: &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}, idempotent: true},
+		"not idempotent":    {failures: 5, err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}},
+	}
+	calls := map[string]int{"too many failures": 3, "not transient": 1, "not idempotent": 1}
+
+	for name, flaky := range cases {
+		t.Run(name, func(t *testing.T) {
+			dbname := "retrytest"
+			dropDB(dbname)
+
+			err := migration.Migrate(context.Background(), fullDSN(dbname), []migration.Migration{flaky},
+				migration.WithRetry(3, time.Millisecond),
+				migration.WithLogger(migration.NopLogger{}),
+			)
+			require.True(t, errors.Is(err, flaky.err), "got %v", err)
+			require.Equal(t, calls[name], flaky.calls)
+		})
+	}
+}
+
+func TestRetryBackoffRespectsContext(t *testing.T) {
+	dbname := "retrytest"
+	dropDB(dbname)
+
+	flaky := &flakyMigration{failures: 5, err: &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, idempotent: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	err := migration.Migrate(ctx, fullDSN(dbname), []migration.Migration{flaky},
+		migration.WithRetry(3, time.Hour),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	require.True(t, time.Since(started) < time.Minute)
+	require.Equal(t, 1, flaky.calls)
+}
+
+type flakyMigration struct {
+	failures   int
+	err        error
+	idempotent bool
+	calls      int
+}
+
+func (f *flakyMigration) Version() int {
+	return 1
+}
+
+func (f *flakyMigration) Idempotent() bool {
+	return f.idempotent
+}
+
+func (f *flakyMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
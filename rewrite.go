@@ -0,0 +1,34 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// WithStatementRewriter passes every statement a Definition or LoadSchema
+// executes through rewrite just before it's executed, e.g. to add a routing
+// hint comment. version is 0 for LoadSchema. Rewritten statements are logged
+// at debug level. An error stops the run, and is checked for every pending
+// Definition before any of them is executed.
+func WithStatementRewriter(rewrite func(version int, sql string) (string, error)) Option {
+	return func(m *Migrator) {
+		m.rewriter = rewrite
+	}
+}
+
+// rewriteStatement rewrites statement with the Migrator's rewriter, if it
+// has one.
+func rewriteStatement(ctx context.Context, statement string) (string, error) {
+	r := executionFrom(ctx)
+	if r == nil || r.m.rewriter == nil {
+		return statement, nil
+	}
+
+	rewritten, err := r.m.rewriter(r.version, statement)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed rewriting statement (%s)", dialect.Snippet(statement, 80))
+	}
+	return rewritten, nil
+}
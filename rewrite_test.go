@@ -0,0 +1,101 @@
+package migration_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStatementRewriter(t *testing.T) {
+	dbname := "rewritetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+				CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	rewritten := []string{}
+	rewriter := func(version int, sql string) (string, error) {
+		sql = fmt.Sprintf("/* migration %d */ %s", version, strings.Replace(sql, "TABLE ", "TABLE rewritten_", 1))
+		rewritten = append(rewritten, sql)
+		return sql, nil
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithStatementRewriter(rewriter),
+		migration.WithSlog(logger),
+	)
+	require.NoError(t, err)
+	require.True(t, tableExists(fullDSN(dbname), "rewritten_blarg"))
+	require.True(t, tableExists(fullDSN(dbname), "rewritten_gralb"))
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+	require.Contains(t, rewritten, "/* migration 1 */ CREATE TABLE rewritten_blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB")
+
+	executing := findRecord(t, &buf, "executing /* migration 1 */ CREATE TABLE rewritten_blarg")
+	require.Equal(t, "DEBUG", executing["level"])
+	require.Equal(t, float64(1), executing["version"])
+}
+
+func TestStatementRewriterErrorStopsRunUpFront(t *testing.T) {
+	dbname := "rewritetest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `RENAME TABLE blarg TO gralb`},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithStatementRewriter(func(version int, sql string) (string, error) {
+			if strings.HasPrefix(sql, "RENAME") {
+				return "", errors.New("no renames")
+			}
+			return sql, nil
+		}),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, "failed rewriting statement of migration 2 (RENAME TABLE blarg TO gralb): no renames")
+	require.Len(t, queryVersions(fullDSN(dbname)), 0)
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+}
+
+func TestLoadSchemaWithStatementRewriter(t *testing.T) {
+	dbname := "rewritetest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/rewritetest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+	must(ioutil.WriteFile(dir+"/blarg.sql", []byte("CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;"), 0644))
+
+	versions := []int{}
+	err := migration.LoadSchema(context.Background(), fullDSN(dbname), dir,
+		migration.WithSchemaOnly(),
+		migration.WithStatementRewriter(func(version int, sql string) (string, error) {
+			versions = append(versions, version)
+			return strings.Replace(sql, "blarg", "rewritten_blarg", 1), nil
+		}),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, versions)
+	require.True(t, tableExists(fullDSN(dbname), "rewritten_blarg"))
+}
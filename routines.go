@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// procedureSuffix and functionSuffix name the files stored routines are
+// dumped to, which LoadSchema creates after the tables and before the views
+// that may call them.
+const (
+	procedureSuffix = ".procedure.sql"
+	functionSuffix  = ".function.sql"
+)
+
+type routine struct {
+	name string
+	// kind is PROCEDURE or FUNCTION.
+	kind string
+}
+
+// routines lists the stored procedures and functions in the database.
+func (m *Migrator) routines(ctx context.Context, conn *sql.DB) ([]routine, error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT ROUTINE_NAME, ROUTINE_TYPE FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = DATABASE() ORDER BY ROUTINE_TYPE, ROUTINE_NAME",
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to show routines")
+	}
+	defer rows.Close()
+
+	routines := []routine{}
+	for rows.Next() {
+		var r routine
+		if err := rows.Scan(&r.name, &r.kind); err != nil {
+			return nil, errors.Wrap(err, "unable to scan routine name")
+		}
+		if r.kind != "PROCEDURE" && r.kind != "FUNCTION" {
+			// e.g. MariaDB's packages
+			continue
+		}
+		routines = append(routines, r)
+	}
+	return routines, rows.Err()
+}
+
+// dumpRoutine writes r's CREATE PROCEDURE or CREATE FUNCTION statement to
+// dir, between DELIMITER lines like a trigger.
+func (m *Migrator) dumpRoutine(ctx context.Context, conn *sql.DB, dir SchemaWriter, r routine) error {
+	kind := strings.ToLower(r.kind)
+	if !validFileName(r.name) {
+		return errors.Errorf("%s %q can't be dumped as its name isn't a valid file name", kind, r.name)
+	}
+
+	column := "Create Procedure"
+	suffix := procedureSuffix
+	if r.kind == "FUNCTION" {
+		column = "Create Function"
+		suffix = functionSuffix
+	}
+
+	createStatement, err := showCreate(ctx, conn, fmt.Sprintf("SHOW CREATE %s %s", r.kind, dialect.QuoteIdentifier(r.name)), column)
+	if err != nil {
+		return errors.Wrapf(err, "failed showing create statement for %s %q", kind, r.name)
+	}
+	createStatement = strings.Replace(createStatement, dialect.QuoteIdentifier(m.src.DBName)+".", "", -1)
+	createStatement = m.normalizeDefinition(createStatement)
+
+	dump := fmt.Sprintf("DELIMITER %s\n%s%s\nDELIMITER ;\n", schemaDelimiter, createStatement, schemaDelimiter)
+	err = dir.WriteFile(r.name+suffix, []byte(dump))
+	if err != nil {
+		return errors.Wrapf(err, "failed writing out create statement for %s %q", kind, r.name)
+	}
+	return nil
+}
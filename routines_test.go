@@ -0,0 +1,56 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpAndLoadRoutines(t *testing.T) {
+	dbname := "routinestest"
+	dropDB(dbname)
+	dropDB("routinestest_copy")
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE prices ( id INT NOT NULL, amount INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `
+DELIMITER ;;
+CREATE FUNCTION with_tax(amount INT) RETURNS INT DETERMINISTIC
+BEGIN
+  DECLARE taxed INT;
+  SET taxed = amount + amount DIV 10;
+  RETURN taxed;
+END;;
+CREATE PROCEDURE add_price(IN price_id INT, IN price_amount INT)
+BEGIN
+  INSERT INTO prices VALUES (price_id, price_amount);
+  UPDATE prices SET amount = amount + 1 WHERE id = price_id;
+END;;
+DELIMITER ;`},
+		// can't be created before the function it calls
+		&migration.Definition{ID: 3, Up: `CREATE VIEW taxed_prices AS SELECT id, with_tax(amount) AS amount FROM prices`},
+	}
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir))
+
+	require.FileExists(t, dir+"/with_tax.function.sql")
+	require.FileExists(t, dir+"/add_price.procedure.sql")
+
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("routinestest_copy"), dir, migration.WithLogger(migration.NopLogger{})))
+	require.Len(t, queryVersions(fullDSN("routinestest_copy")), 3)
+
+	execSQL(fullDSN("routinestest_copy"), "CALL add_price(1, 99)")
+
+	conn, err := sql.Open("mysql", fullDSN("routinestest_copy"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var amount int
+	require.NoError(t, conn.QueryRow("SELECT amount FROM taxed_prices WHERE id = 1").Scan(&amount))
+	require.Equal(t, 110, amount)
+}
@@ -0,0 +1,16 @@
+package migration
+
+import "context"
+
+// Runner is what deploy tooling calls on a Migrator, so that it can be unit
+// tested against a fake such as migrationfake.Fake rather than MySQL. Redo
+// is the closest there is to a rollback.
+type Runner interface {
+	Migrate(ctx context.Context, migrations []Migration) error
+	MigrateWithReport(ctx context.Context, migrations []Migration) (Report, error)
+	Plan(ctx context.Context, migrations []Migration) ([]PlannedMigration, error)
+	AppliedVersions(ctx context.Context) ([]Applied, error)
+	Redo(ctx context.Context, migrations []Migration, version int) error
+}
+
+var _ Runner = (*Migrator)(nil)
@@ -0,0 +1,107 @@
+package migration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// tempFileSuffix ends the names of the files schemaDir writes to before
+// renaming them, which LoadSchema ignores as they don't end in .sql.
+const tempFileSuffix = ".tmp"
+
+// schemaDir is the directory DumpSchema writes to, the default SchemaWriter.
+// It remembers the files it wrote, so that those left by a previous dump can
+// be removed.
+type schemaDir struct {
+	location string
+	// mu guards written, as tables are dumped concurrently.
+	mu      sync.Mutex
+	written map[string]bool
+}
+
+// newSchemaDir creates location, and any missing parents, if it doesn't
+// exist, and checks it can be written to before anything is dumped.
+func newSchemaDir(location string) (*schemaDir, error) {
+	if info, err := os.Stat(location); err == nil && !info.IsDir() {
+		return nil, errors.Errorf("schema location %q isn't a directory", location)
+	}
+	if err := os.MkdirAll(location, 0755); err != nil {
+		return nil, errors.Wrapf(err, "unable to create schema location %q", location)
+	}
+
+	probe, err := ioutil.TempFile(location, ".probe.*"+tempFileSuffix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schema location %q isn't writable", location)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return &schemaDir{location: location, written: map[string]bool{}}, nil
+}
+
+// WriteFile replaces the file name with contents, through a temporary file
+// that's renamed over it, so a failure never leaves it partially written.
+func (d *schemaDir) WriteFile(name string, contents []byte) error {
+	f, err := ioutil.TempFile(d.location, "."+name+".*"+tempFileSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(d.location, name)); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.written[name] = true
+	d.mu.Unlock()
+	return nil
+}
+
+// removeStale removes the .sql and .sql.gz files that weren't written, for
+// tables and other objects dropped since the last dump, which LoadSchema
+// would otherwise recreate, along with the temporary files of an interrupted
+// dump. Other files are left alone.
+func (d *schemaDir) removeStale() ([]string, error) {
+	files, err := ioutil.ReadDir(d.location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading dir %q", d.location)
+	}
+
+	removed := []string{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || d.written[name] {
+			continue
+		}
+		stale := strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".sql"+gzipSuffix) ||
+			strings.HasPrefix(name, ".") && strings.Contains(name, ".sql.") && strings.HasSuffix(name, tempFileSuffix)
+		if !stale {
+			continue
+		}
+		if err := os.Remove(filepath.Join(d.location, name)); err != nil {
+			return removed, errors.Wrapf(err, "failed removing stale %q", name)
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
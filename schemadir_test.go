@@ -0,0 +1,89 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpSchemaRemovesStaleFiles(t *testing.T) {
+	dbname := "staledumptest"
+	dropDB(dbname)
+	dropDB("staledumptest_copy")
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE kept ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE dropped ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 3, Up: `CREATE VIEW dropped_ids AS SELECT id FROM dropped`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+	require.FileExists(t, dir+"/dropped.sql")
+	require.FileExists(t, dir+"/dropped_ids.view.sql")
+
+	// left by an interrupted dump, and files the dump doesn't own
+	require.NoError(t, ioutil.WriteFile(dir+"/.kept.sql.123456.tmp", []byte("CREATE TABLE"), 0644))
+	require.NoError(t, ioutil.WriteFile(dir+"/README.md", []byte("the schema"), 0644))
+	require.NoError(t, os.Mkdir(dir+"/fixtures.sql", 0755))
+
+	migrations = append(migrations,
+		&migration.Definition{ID: 4, Up: `DROP VIEW dropped_ids`},
+		&migration.Definition{ID: 5, Up: `DROP TABLE dropped`},
+	)
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	require.Equal(t, []string{"README.md", "_migrations.sql", "fixtures.sql", "kept.sql"}, names)
+
+	require.NoError(t, os.Remove(dir+"/fixtures.sql"))
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("staledumptest_copy"), dir, migration.WithLogger(migration.NopLogger{})))
+	require.Equal(t, showSchema(fullDSN(dbname), "kept"), showSchema(fullDSN("staledumptest_copy"), "kept"))
+	require.False(t, tableExists(fullDSN("staledumptest_copy"), "dropped"))
+	require.False(t, tableExists(fullDSN("staledumptest_copy"), "dropped_ids"))
+	require.Len(t, queryVersions(fullDSN("staledumptest_copy")), 5)
+}
+
+func TestDumpSchemaCreatesNestedLocation(t *testing.T) {
+	dbname := "nesteddumptest"
+	dropDB(dbname)
+	dropDB("nesteddumptest_copy")
+	dir := filepath.Join(t.TempDir(), "db", "schema")
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE things ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+	require.FileExists(t, filepath.Join(dir, "things.sql"))
+
+	// both given with a trailing separator
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir+string(filepath.Separator), migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("nesteddumptest_copy"), dir+string(filepath.Separator), migration.WithLogger(migration.NopLogger{})))
+	require.True(t, tableExists(fullDSN("nesteddumptest_copy"), "things"))
+	require.Len(t, queryVersions(fullDSN("nesteddumptest_copy")), 1)
+}
+
+func TestDumpSchemaLocationIsAFile(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "schema")
+	require.NoError(t, ioutil.WriteFile(location, []byte("not a directory"), 0644))
+
+	err := migration.DumpSchema(context.Background(), fullDSN("filedumptest"), location, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, fmt.Sprintf("schema location %q isn't a directory", location))
+
+	err = migration.DumpSchema(context.Background(), fullDSN("filedumptest"), filepath.Join(location, "nested"), migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to create schema location")
+}
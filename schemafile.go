@@ -0,0 +1,145 @@
+package migration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// schemaFileMarker starts the line naming the file that the statements
+// following it in a single file dump would have been dumped to.
+const schemaFileMarker = "-- file: "
+
+// WithSingleFile makes DumpSchema write the whole schema, and the migration
+// history, to the file at location rather than a file per table in it. The
+// statements are separated by semicolons and in the order they're loaded in.
+// LoadSchema loads such a file when it's given its path.
+func WithSingleFile() Option {
+	return func(m *Migrator) {
+		m.singleFile = true
+	}
+}
+
+// dumpSchemaFile dumps the schema to a temporary directory and then packs
+// its files into the single file name written to target.
+func (m *Migrator) dumpSchemaFile(ctx context.Context, target SchemaWriter, name string, destination string) error {
+	tmp, err := ioutil.TempDir("", "migration-schema-*")
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary schema dir")
+	}
+	defer os.RemoveAll(tmp)
+
+	dir, err := newSchemaDir(tmp)
+	if err != nil {
+		return err
+	}
+	if err := m.dumpSchema(ctx, dir, destination); err != nil {
+		return err
+	}
+
+	contents, err := packSchemaFile(tmp, m.src.DBName)
+	if err != nil {
+		return err
+	}
+	if err := target.WriteFile(name, contents); err != nil {
+		return errors.Wrapf(err, "failed writing out schema to %s", destination)
+	}
+	return nil
+}
+
+// packSchemaFile concatenates the files dumped to location, in the order
+// LoadSchema loads them, each after a marker naming it.
+func packSchemaFile(location string, dbname string) ([]byte, error) {
+	files, err := ioutil.ReadDir(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading dir %q", location)
+	}
+	names := []string{}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".sql") {
+			names = append(names, file.Name())
+		}
+	}
+	names, err = orderSchemaFiles(location, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- schema of db %q\n", dbname)
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(filepath.Join(location, name))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read %q", name)
+		}
+		statement := strings.TrimSpace(string(contents))
+		// triggers and routines end by restoring the delimiter
+		if !strings.HasSuffix(statement, "DELIMITER ;") {
+			statement = statement + ";"
+		}
+		fmt.Fprintf(&b, "\n%s%s\n%s\n", schemaFileMarker, name, statement)
+	}
+	return []byte(b.String()), nil
+}
+
+// unpackSchemaFile splits the single file dump at path back into the files
+// it was packed from, in a temporary directory the caller removes.
+func unpackSchemaFile(path string) (string, error) {
+	contents, err := readSchemaFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to read %q", path)
+	}
+
+	files := map[string]*strings.Builder{}
+	names := []string{}
+	var current *strings.Builder
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, schemaFileMarker) {
+			name := strings.TrimSpace(strings.TrimPrefix(line, schemaFileMarker))
+			if !validFileName(name) || !strings.HasSuffix(name, ".sql") {
+				return "", errors.Errorf("%q names an invalid schema file %q", path, name)
+			}
+			if _, ok := files[name]; ok {
+				return "", errors.Errorf("%q contains %q more than once", path, name)
+			}
+			current = &strings.Builder{}
+			files[name] = current
+			names = append(names, name)
+			continue
+		}
+		if current == nil {
+			if trimmed := strings.TrimSpace(line); len(trimmed) > 0 && !strings.HasPrefix(trimmed, "--") {
+				return "", errors.Errorf("%q isn't a single file schema dump, its statements have to follow a %q line", path, strings.TrimSpace(schemaFileMarker)+" <name>")
+			}
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrapf(err, "unable to read %q", path)
+	}
+
+	dir, err := ioutil.TempDir("", "migration-schema-*")
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create temporary schema dir")
+	}
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(files[name].String()), 0644); err != nil {
+			os.RemoveAll(dir)
+			return "", errors.Wrapf(err, "failed unpacking %q", name)
+		}
+	}
+	return dir, nil
+}
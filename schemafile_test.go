@@ -0,0 +1,67 @@
+package migration_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpAndLoadSingleFile(t *testing.T) {
+	dbname := "singlefiletest"
+	dropDB(dbname)
+	dropDB("singlefiletest_copy")
+	path := filepath.Join(t.TempDir(), "db", "schema.sql")
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE widgets ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE audit ( widget_id INT NOT NULL ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 3, Up: `CREATE VIEW widget_names AS SELECT name FROM widgets`},
+		&migration.Definition{ID: 4, Up: `
+DELIMITER ;;
+CREATE TRIGGER widgets_insert AFTER INSERT ON widgets FOR EACH ROW
+BEGIN
+  INSERT INTO audit VALUES (NEW.id);
+END;;
+DELIMITER ;`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), path, migration.WithLogger(migration.NopLogger{}), migration.WithSingleFile()))
+
+	dump, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	markers := []string{}
+	for _, line := range strings.Split(string(dump), "\n") {
+		if strings.HasPrefix(line, "-- file: ") {
+			markers = append(markers, strings.TrimPrefix(line, "-- file: "))
+		}
+	}
+	require.Equal(t, []string{"_migrations.sql", "audit.sql", "widgets.sql", "widget_names.view.sql", "widgets_insert.trigger.sql"}, markers)
+	require.Contains(t, string(dump), "PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci;\n")
+
+	// dumping again gives the same file
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), path, migration.WithLogger(migration.NopLogger{}), migration.WithSingleFile()))
+	again, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(dump), string(again))
+
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("singlefiletest_copy"), path, migration.WithLogger(migration.NopLogger{})))
+	require.Len(t, queryVersions(fullDSN("singlefiletest_copy")), 4)
+	require.True(t, tableExists(fullDSN("singlefiletest_copy"), "widget_names"))
+
+	execSQL(fullDSN("singlefiletest_copy"), "INSERT INTO widgets VALUES (1, 'sprocket')")
+	require.Equal(t, 1, countRows(fullDSN("singlefiletest_copy"), "audit"))
+}
+
+func TestLoadSchemaRejectsFileThatIsntADump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	require.NoError(t, ioutil.WriteFile(path, []byte("-- hand written\nCREATE TABLE a (id INT);\n"), 0644))
+
+	err := migration.LoadSchema(context.Background(), fullDSN("notadumptest"), path, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "isn't a single file schema dump")
+}
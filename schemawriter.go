@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+// SchemaWriter is where DumpSchemaTo writes the schema's files, e.g. a
+// bucket rather than a directory. name is one of:
+//
+//   - <table>.sql for each table's CREATE TABLE statement
+//   - <view>.view.sql, <trigger>.trigger.sql, <procedure>.procedure.sql and
+//     <function>.function.sql for the other objects
+//   - _migrations.sql, or the WithTableName table's name, for the migration
+//     history, and one for each other tracking table with WithNamespaces
+//   - schema.sql for everything with WithSingleFile
+//
+// with .gz added to each with WithGzip. Names never contain a path
+// separator, and LoadSchema expects the files side by side in a directory.
+// WriteFile is called concurrently, see WithDumpConcurrency, and files left
+// from a previous dump aren't removed.
+type SchemaWriter interface {
+	WriteFile(name string, contents []byte) error
+}
+
+// singleFileName is the file WithSingleFile dumps to through a SchemaWriter.
+const singleFileName = "schema.sql"
+
+func DumpSchemaTo(ctx context.Context, dsn string, w SchemaWriter, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return errors.Wrap(err, "unable to dump schema")
+	}
+	return m.DumpSchemaTo(ctx, w)
+}
+
+func DumpSchemaToConfig(ctx context.Context, cfg *mysql.Config, w SchemaWriter, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return errors.Wrap(err, "unable to dump schema")
+	}
+	return m.DumpSchemaTo(ctx, w)
+}
+
+func DumpSchemaToSource(ctx context.Context, src Source, w SchemaWriter, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return errors.Wrap(err, "unable to dump schema")
+	}
+	return m.DumpSchemaTo(ctx, w)
+}
+
+// DumpSchemaTo dumps the schema like DumpSchema, writing its files to w
+// rather than a directory.
+func (m *Migrator) DumpSchemaTo(ctx context.Context, w SchemaWriter) error {
+	destination := fmt.Sprintf("%T", w)
+	if m.singleFile {
+		return m.dumpSchemaFile(ctx, m.compressing(w), singleFileName, destination)
+	}
+	return m.dumpSchema(ctx, m.compressing(w), destination)
+}
@@ -0,0 +1,68 @@
+package migration_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryWriter keeps the files written to it, as a writer to a bucket might
+// upload them.
+type memoryWriter struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func (w *memoryWriter) WriteFile(name string, contents []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.files == nil {
+		w.files = map[string]string{}
+	}
+	w.files["schemas/"+name] = string(contents)
+	return nil
+}
+
+func (w *memoryWriter) names() []string {
+	names := []string{}
+	for name := range w.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDumpSchemaTo(t *testing.T) {
+	dbname := "schemawritertest"
+	dropDB(dbname)
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE parts ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE VIEW part_ids AS SELECT id FROM parts`},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{})))
+
+	w := &memoryWriter{}
+	require.NoError(t, migration.DumpSchemaTo(context.Background(), fullDSN(dbname), w, migration.WithLogger(migration.NopLogger{})))
+	require.Equal(t, []string{"schemas/_migrations.sql", "schemas/part_ids.view.sql", "schemas/parts.sql"}, w.names())
+
+	// the same as the directory it would otherwise have been dumped to
+	for name, contents := range readDir(t, dir) {
+		require.Equal(t, contents, w.files["schemas/"+name], name)
+	}
+
+	compressed := &memoryWriter{}
+	require.NoError(t, migration.DumpSchemaTo(context.Background(), fullDSN(dbname), compressed, migration.WithLogger(migration.NopLogger{}), migration.WithGzip()))
+	require.Equal(t, []string{"schemas/_migrations.sql.gz", "schemas/part_ids.view.sql.gz", "schemas/parts.sql.gz"}, compressed.names())
+
+	single := &memoryWriter{}
+	require.NoError(t, migration.DumpSchemaTo(context.Background(), fullDSN(dbname), single, migration.WithLogger(migration.NopLogger{}), migration.WithSingleFile()))
+	require.Equal(t, []string{"schemas/schema.sql"}, single.names())
+	require.Contains(t, single.files["schemas/schema.sql"], "-- file: parts.sql\nCREATE TABLE `parts`")
+}
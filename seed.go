@@ -0,0 +1,270 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// seedsSuffix names the table tracking which seeds have been executed,
+// separately from the schema's history, after the tracking table.
+const seedsSuffix = "_seeds"
+
+// Seed inserts reference data, executed once per database and tracked
+// separately from migrations so it doesn't clutter the schema's history.
+type Seed struct {
+	Name string
+	Up   string
+	// OnlyIfEmpty names a table the seed is skipped for while it has any
+	// rows, e.g. so data entered by hand isn't added to. It may be qualified
+	// with its database as db.table.
+	OnlyIfEmpty string
+}
+
+// Checksum is the hex encoded SHA-256 of Up.
+func (s *Seed) Checksum() string {
+	sum := sha256.Sum256([]byte(s.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// WithEnvironment names the environment the database belongs to, e.g.
+// development or production. Seeding requires it.
+func WithEnvironment(name string) Option {
+	return func(m *Migrator) {
+		m.environment = name
+	}
+}
+
+// WithProductionSeeds allows seeding a database WithEnvironment production,
+// which is otherwise refused.
+func WithProductionSeeds() Option {
+	return func(m *Migrator) {
+		m.productionSeeds = true
+	}
+}
+
+// WithSeeds makes Migrate execute seeds once the migrations have been
+// applied, as Seed does.
+func WithSeeds(seeds ...*Seed) Option {
+	return func(m *Migrator) {
+		m.seeds = append(m.seeds, seeds...)
+	}
+}
+
+func SeedDatabase(ctx context.Context, dsn string, seeds []*Seed, opts ...Option) ([]string, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Seed(ctx, seeds)
+}
+
+func SeedDatabaseConfig(ctx context.Context, cfg *mysql.Config, seeds []*Seed, opts ...Option) ([]string, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Seed(ctx, seeds)
+}
+
+func SeedDatabaseSource(ctx context.Context, src Source, seeds []*Seed, opts ...Option) ([]string, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Seed(ctx, seeds)
+}
+
+// Seed executes the seeds that haven't been executed against the database
+// yet, in the order given, and returns their names. It requires
+// WithEnvironment, and refuses to seed production without
+// WithProductionSeeds.
+func (m *Migrator) Seed(ctx context.Context, seeds []*Seed) ([]string, error) {
+	if err := m.checkSeedable(seeds); err != nil {
+		return nil, err
+	}
+
+	conn, err := m.openDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	return m.runSeeds(ctx, conn, seeds)
+}
+
+func (m *Migrator) checkSeedable(seeds []*Seed) error {
+	if len(seeds) == 0 {
+		return nil
+	}
+
+	switch {
+	case len(m.environment) == 0:
+		return errors.Errorf("refusing to seed db %q without WithEnvironment", m.src.DBName)
+	case isProduction(m.environment) && !m.productionSeeds:
+		return errors.Errorf("refusing to seed db %q in %s without WithProductionSeeds", m.src.DBName, m.environment)
+	}
+
+	names := make(map[string]bool, len(seeds))
+	for _, seed := range seeds {
+		switch {
+		case len(seed.Name) == 0:
+			return errors.New("seeds must have a name")
+		case len(seed.Name) > maxNameLength:
+			return errors.Errorf("name of seed %q is longer than %d characters", seed.Name, maxNameLength)
+		case names[seed.Name]:
+			return errors.Errorf("duplicate seed %q", seed.Name)
+		case len(strings.TrimSpace(seed.Up)) == 0:
+			return errors.Errorf("seed %q has no Up", seed.Name)
+		case len(seed.OnlyIfEmpty) > 0 && !isTableReference(seed.OnlyIfEmpty):
+			return errors.Errorf("invalid table name %q in OnlyIfEmpty of seed %q", seed.OnlyIfEmpty, seed.Name)
+		}
+		names[seed.Name] = true
+	}
+	return nil
+}
+
+// isTableReference reports whether table is a table name, optionally
+// qualified with its database as db.table.
+func isTableReference(table string) bool {
+	for _, name := range strings.SplitN(table, ".", 2) {
+		if !tableNamePattern.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteTableReference quotes a table name, or both parts of a db.table one.
+func quoteTableReference(table string) string {
+	names := strings.SplitN(table, ".", 2)
+	for i, name := range names {
+		names[i] = dialect.QuoteIdentifier(name)
+	}
+	return strings.Join(names, ".")
+}
+
+func isProduction(environment string) bool {
+	return strings.EqualFold(environment, "production") || strings.EqualFold(environment, "prod")
+}
+
+func (m *Migrator) runSeeds(ctx context.Context, conn *sql.DB, seeds []*Seed) ([]string, error) {
+	seeded := []string{}
+	if len(seeds) == 0 {
+		return seeded, nil
+	}
+
+	if err := m.createSeedsTableIfNotExists(ctx, conn); err != nil {
+		return seeded, err
+	}
+
+	executed, err := m.readSeeds(ctx, conn)
+	if err != nil {
+		return seeded, err
+	}
+
+	for _, seed := range seeds {
+		if executed[seed.Name] {
+			continue
+		}
+
+		if len(seed.OnlyIfEmpty) > 0 {
+			hasRows, err := oneExists(ctx, conn, fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", quoteTableReference(seed.OnlyIfEmpty)))
+			if err != nil {
+				return seeded, errors.Wrapf(err, "failed checking if table %q of seed %q is empty", seed.OnlyIfEmpty, seed.Name)
+			}
+			if hasRows {
+				m.log(ctx, slog.LevelInfo,
+					fmt.Sprintf("skipping seed %q, table %s isn't empty", seed.Name, seed.OnlyIfEmpty),
+					slog.String("seed", seed.Name),
+					slog.String("db", m.src.DBName),
+				)
+				continue
+			}
+		}
+
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("executing seed %q", seed.Name),
+			slog.String("seed", seed.Name),
+			slog.String("db", m.src.DBName),
+		)
+
+		if err := execStatements(ctx, conn, dialect.SplitStatements(seed.Up)); err != nil {
+			return seeded, errors.Wrapf(err, "failed executing seed %q", seed.Name)
+		}
+
+		_, err := conn.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO %s (name, checksum, seeded_at, applied_by) VALUES (?, ?, ?, ?)", dialect.QuoteIdentifier(m.seedsTableName())),
+			seed.Name, seed.Checksum(), time.Now().UTC().Format(createdAtWriteFormat), m.appliedBy,
+		)
+		if err != nil {
+			return seeded, errors.Wrapf(err, "failed recording seed %q", seed.Name)
+		}
+
+		seeded = append(seeded, seed.Name)
+	}
+
+	return seeded, nil
+}
+
+// seedsTableName is _seeds for the default tracking table, as it was named
+// before it followed WithTableName and WithNamespace.
+func (m *Migrator) seedsTableName() string {
+	if m.tableName == defaultTableName {
+		return seedsSuffix
+	}
+	return m.tableName + seedsSuffix
+}
+
+func (m *Migrator) readSeeds(ctx context.Context, conn *sql.DB) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT name FROM %s", dialect.QuoteIdentifier(m.seedsTableName())))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading seeds from %q", m.seedsTableName())
+	}
+	defer rows.Close()
+
+	executed := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.Wrapf(err, "failed reading seeds from %q", m.seedsTableName())
+		}
+		executed[name] = true
+	}
+	return executed, rows.Err()
+}
+
+func (m *Migrator) createSeedsTableIfNotExists(ctx context.Context, conn *sql.DB) error {
+	_, err := conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s (
+				name VARCHAR(%d) NOT NULL,
+				checksum CHAR(64) NOT NULL,
+				seeded_at DATETIME(6) NOT NULL,
+				applied_by VARCHAR(255) NOT NULL DEFAULT '',
+				PRIMARY KEY (name)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci`,
+			dialect.QuoteIdentifier(m.seedsTableName()),
+			maxNameLength,
+		),
+	)
+	return errors.Wrapf(err, "failed creating table %q", m.seedsTableName())
+}
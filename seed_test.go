@@ -0,0 +1,173 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedDatabase(t *testing.T) {
+	dbname := "seedtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	execSQL(fullDSN(dbname), "INSERT INTO gralb (di) VALUES (1)")
+
+	seeds := []*migration.Seed{
+		{Name: "blargs", Up: "INSERT INTO blarg (id, name) VALUES (1, 'one'), (2, 'two')"},
+		{Name: "gralbs", Up: "INSERT INTO gralb (di) VALUES (2)", OnlyIfEmpty: "gralb"},
+	}
+
+	seeded, err := migration.SeedDatabase(context.Background(), fullDSN(dbname), seeds,
+		migration.WithEnvironment("development"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"blargs"}, seeded)
+	require.Equal(t, 2, countRows(fullDSN(dbname), "blarg"))
+	require.Equal(t, 1, countRows(fullDSN(dbname), "gralb"))
+
+	seeded, err = migration.SeedDatabase(context.Background(), fullDSN(dbname), seeds,
+		migration.WithEnvironment("development"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Empty(t, seeded)
+	require.Equal(t, 2, countRows(fullDSN(dbname), "blarg"))
+
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+}
+
+func TestMigrateWithSeeds(t *testing.T) {
+	dbname := "seedtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	seed := &migration.Seed{Name: "blargs", Up: "INSERT INTO blarg (id, name) VALUES (1, 'one')"}
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations,
+		migration.WithSeeds(seed),
+		migration.WithEnvironment("test"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"blargs"}, report.Seeded)
+	require.Equal(t, 1, countRows(fullDSN(dbname), "blarg"))
+}
+
+func TestSeedOnlyIfEmptyQuotesTable(t *testing.T) {
+	dbname := "seedtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: "CREATE TABLE `order` ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB",
+		},
+	}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	seeds := []*migration.Seed{
+		{Name: "orders", Up: "INSERT INTO `order` (id) VALUES (1)", OnlyIfEmpty: "order"},
+		{Name: "more orders", Up: "INSERT INTO `order` (id) VALUES (2)", OnlyIfEmpty: "migration_test_seedtest.order"},
+	}
+	seeded, err := migration.SeedDatabase(context.Background(), fullDSN(dbname), seeds,
+		migration.WithEnvironment("development"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"orders"}, seeded)
+	require.Equal(t, 1, countRows(fullDSN(dbname), "`order`"))
+
+	_, err = migration.SeedDatabase(context.Background(), fullDSN(dbname),
+		[]*migration.Seed{{Name: "blargs", Up: "SELECT 1", OnlyIfEmpty: "blarg; DROP TABLE `order`"}},
+		migration.WithEnvironment("development"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, "invalid table name \"blarg; DROP TABLE `order`\" in OnlyIfEmpty of seed \"blargs\"")
+	require.Equal(t, 1, countRows(fullDSN(dbname), "`order`"))
+}
+
+func TestSeedingRefused(t *testing.T) {
+	dbname := "seedtest"
+	dropDB(dbname)
+
+	seeds := []*migration.Seed{{Name: "blargs", Up: "SELECT 1"}}
+
+	_, err := migration.SeedDatabase(context.Background(), fullDSN(dbname), seeds, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, `refusing to seed db "migration_test_seedtest" without WithEnvironment`)
+
+	_, err = migration.SeedDatabase(context.Background(), fullDSN(dbname), seeds,
+		migration.WithEnvironment("production"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, `refusing to seed db "migration_test_seedtest" in production without WithProductionSeeds`)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), []migration.Migration{},
+		migration.WithSeeds(seeds...),
+		migration.WithEnvironment("production"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, `refusing to seed db "migration_test_seedtest" in production without WithProductionSeeds`)
+	require.False(t, dbExists(dbname))
+}
+
+func TestSeedsTrackedPerNamespaceAndLeftOutOfDumps(t *testing.T) {
+	dbname := "seednamespacetest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/seednamespacetest", os.TempDir())
+	must(os.RemoveAll(dir))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	seeds := []*migration.Seed{
+		{Name: "blargs", Up: "INSERT INTO blarg (id, name) VALUES (1, 'one')"},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithNamespace("billing"),
+		migration.WithRepeatables(&migration.Repeatable{Name: "blarg_names", Up: `CREATE OR REPLACE VIEW blarg_names AS SELECT name FROM blarg`}),
+		migration.WithSeeds(seeds...),
+		migration.WithEnvironment("development"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, countRows(fullDSN(dbname), "_migrations_billing_seeds"))
+	require.False(t, tableExists(fullDSN(dbname), "_seeds"))
+
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithNamespace("billing"))
+	require.NoError(t, err)
+	files := readDir(t, dir)
+	require.Len(t, files, 3)
+	require.Contains(t, files, "_migrations_billing.sql")
+	require.Contains(t, files, "blarg.sql")
+	require.Contains(t, files, "blarg_names.view.sql")
+}
@@ -0,0 +1,109 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var serverVersionPattern = regexp.MustCompile(`\A(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// WithMinServerVersion makes Migrate refuse to run against a server older
+// than version, such as "8.0.0" for migrations using MySQL 8 only syntax.
+// MariaDB versions are numbered separately and given with a -MariaDB suffix,
+// e.g. "10.5.0-MariaDB". When several are given the server has to meet the
+// one for its own kind, and a server of a kind none of them are for fails.
+func WithMinServerVersion(versions ...string) Option {
+	return func(m *Migrator) {
+		m.minServerVersions = versions
+	}
+}
+
+// serverVersion is a MySQL or MariaDB version as reported by VERSION().
+type serverVersion struct {
+	flavor string
+	parts  [3]int
+}
+
+func parseServerVersion(version string) (serverVersion, error) {
+	parsed := serverVersion{flavor: "MySQL"}
+	trimmed := version
+	if strings.Contains(version, "MariaDB") {
+		parsed.flavor = "MariaDB"
+		// older MariaDB servers prefix their version to look like MySQL 5.5
+		trimmed = strings.TrimPrefix(version, "5.5.5-")
+	}
+
+	match := serverVersionPattern.FindStringSubmatch(trimmed)
+	if match == nil {
+		return serverVersion{}, errors.Errorf("unrecognised server version %q", version)
+	}
+	for i, part := range match[1:] {
+		if len(part) > 0 {
+			parsed.parts[i], _ = strconv.Atoi(part)
+		}
+	}
+	return parsed, nil
+}
+
+func (v serverVersion) String() string {
+	return fmt.Sprintf("%s %d.%d.%d", v.flavor, v.parts[0], v.parts[1], v.parts[2])
+}
+
+func (v serverVersion) olderThan(other serverVersion) bool {
+	for i := range v.parts {
+		if v.parts[i] != other.parts[i] {
+			return v.parts[i] < other.parts[i]
+		}
+	}
+	return false
+}
+
+// checkServerVersion logs the server's version, and fails if it's older than
+// WithMinServerVersion allows.
+func (m *Migrator) checkServerVersion(ctx context.Context) error {
+	conn, err := m.src.Server(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var raw string
+	if err := conn.QueryRowContext(ctx, "SELECT VERSION()").Scan(&raw); err != nil {
+		return errors.Wrapf(err, "failed reading the version of %s", m.serverLabel())
+	}
+
+	version, err := parseServerVersion(raw)
+	described := raw
+	if err == nil {
+		described = version.String()
+	}
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("connected to %s at %s", described, m.serverLabel()),
+		slog.String("db", m.src.DBName),
+		slog.String("server_version", raw),
+	)
+
+	if len(m.minServerVersions) == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	required := []string{}
+	for _, min := range m.minServerVersions {
+		// already checked by NewSource
+		minimum, _ := parseServerVersion(min)
+		if minimum.flavor == version.flavor && !version.olderThan(minimum) {
+			return nil
+		}
+		required = append(required, minimum.String()+" or later")
+	}
+	return errors.Errorf("the server at %s is %s but %s is required", m.serverLabel(), version, strings.Join(required, ", or "))
+}
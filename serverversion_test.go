@@ -0,0 +1,38 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMinServerVersion(t *testing.T) {
+	dbname := "serverversiontest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	logger := &capturingLogger{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithMinServerVersion("5.0", "5.0.0-MariaDB"),
+		migration.WithLogger(logger),
+	)
+	require.NoError(t, err)
+	require.Regexp(t, `\Aconnected to (MySQL|MariaDB) \d+\.\d+\.\d+ at `, logger.lines[0])
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithMinServerVersion("99.0.0", "99.1-MariaDB"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Regexp(t, `\Athe server at \S+ is (MySQL|MariaDB) \d+\.\d+\.\d+ but MySQL 99\.0\.0 or later, or MariaDB 99\.1\.0 or later is required\z`, err.Error())
+}
+
+func TestWithMinServerVersionInvalid(t *testing.T) {
+	_, err := migration.New(fullDSN("serverversiontest"), migration.WithMinServerVersion("eight"))
+	require.EqualError(t, err, `invalid minimum server version: unrecognised server version "eight"`)
+}
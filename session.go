@@ -0,0 +1,135 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// WithCollation pins the session collation (and its character set) used for
+// every statement, instead of the database's default collation.
+func WithCollation(collation string) Option {
+	return func(m *Migrator) {
+		m.collation = collation
+	}
+}
+
+// sessionVariablePattern matches the system variable names WithSessionVariables
+// interpolates into SET SESSION.
+var sessionVariablePattern = regexp.MustCompile(`\A[A-Za-z_][A-Za-z0-9_]*\z`)
+
+// numberPattern matches values that are set as numbers rather than strings,
+// as numeric variables refuse strings.
+var numberPattern = regexp.MustCompile(`\A-?[0-9]+(\.[0-9]+)?\z`)
+
+// WithSessionVariables sets system variables such as sql_mode on the session
+// every migration and schema file is executed on, so they behave the same
+// whatever the server's defaults are.
+func WithSessionVariables(variables map[string]string) Option {
+	return func(m *Migrator) {
+		m.sessionVariables = variables
+	}
+}
+
+// setSessionVariables issues SET SESSION for WithSessionVariables on db, which
+// has to be a single connection for them to stick.
+func (m *Migrator) setSessionVariables(ctx context.Context, db *sql.DB) error {
+	if len(m.sessionVariables) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(m.sessionVariables))
+	for name := range m.sessionVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	assignments := make([]string, len(names))
+	for i, name := range names {
+		value := m.sessionVariables[name]
+		if !numberPattern.MatchString(value) {
+			value = dialect.QuoteString(value)
+		}
+		assignments[i] = fmt.Sprintf("%s = %s", name, value)
+	}
+
+	if _, err := db.ExecContext(ctx, "SET SESSION "+strings.Join(assignments, ", ")); err != nil {
+		return errors.Wrapf(err, "failed setting session variables %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// openDatabase connects to the database with the session character set and
+// collation pinned, so that statements relying on them (string literals,
+// CREATE TABLE ... SELECT) behave the same whatever the server and driver
+// defaults are. Sources built from custom openers are used as they are.
+func (m *Migrator) openDatabase(ctx context.Context) (*sql.DB, error) {
+	if m.src.cfg == nil {
+		return m.src.Database(ctx)
+	}
+
+	charset, collation, err := m.sessionCollation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(collation) == 0 {
+		return m.src.Database(ctx)
+	}
+
+	cfg := cloneConfig(m.src.cfg)
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	// the driver issues SET NAMES with this on every new connection
+	cfg.Params["charset"] = fmt.Sprintf("%s COLLATE %s", charset, collation)
+
+	return connect(cfg.FormatDSN())
+}
+
+// sessionCollation resolves the character set and collation to pin, which is
+// empty when the database doesn't exist yet and no collation was configured.
+func (m *Migrator) sessionCollation(ctx context.Context) (string, string, error) {
+	conn, err := m.src.Server(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer conn.Close()
+
+	var charset, collation string
+
+	if len(m.collation) > 0 {
+		err := conn.QueryRowContext(
+			ctx,
+			"SELECT CHARACTER_SET_NAME, COLLATION_NAME FROM information_schema.COLLATIONS WHERE COLLATION_NAME = ?",
+			m.collation,
+		).Scan(&charset, &collation)
+		if err == sql.ErrNoRows {
+			return "", "", errors.Errorf("unknown collation %q", m.collation)
+		}
+		if err != nil {
+			return "", "", errors.Wrapf(err, "failed looking up collation %q", m.collation)
+		}
+		return charset, collation, nil
+	}
+
+	err = conn.QueryRowContext(
+		ctx,
+		"SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?",
+		m.src.DBName,
+	).Scan(&charset, &collation)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed looking up default collation of db %q", m.src.DBName)
+	}
+
+	return charset, collation, nil
+}
@@ -0,0 +1,133 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinsSessionCollationToDatabaseDefault(t *testing.T) {
+	servers := map[string]string{"default": partialDSN()}
+	if dsn := os.Getenv("LATIN1_DATABASE_DSN"); len(dsn) > 0 {
+		servers["latin1"] = dsn
+	}
+
+	for name, server := range servers {
+		t.Run(name, func(t *testing.T) {
+			dsn := dsnFor(server, "migration_test_sessioncollationtest")
+			execSQL(server, "DROP DATABASE IF EXISTS migration_test_sessioncollationtest")
+
+			migrations := []migration.Migration{
+				&migration.Definition{
+					ID: 1,
+					Up: `CREATE TABLE blarg ( id INT NOT NULL, label VARCHAR(10), PRIMARY KEY(id) ) ENGINE=InnoDB`,
+				},
+				&migration.Definition{
+					ID: 2,
+					Up: `CREATE TABLE derived ENGINE=InnoDB AS SELECT 'x' AS label`,
+				},
+			}
+
+			err := migration.Migrate(context.Background(), dsn, migrations)
+			require.NoError(t, err)
+
+			require.Equal(t, "utf8mb4_unicode_520_ci", columnCollation(dsn, "blarg", "label"))
+			require.Equal(t, "utf8mb4_unicode_520_ci", columnCollation(dsn, "derived", "label"))
+		})
+	}
+}
+
+func TestWithCollation(t *testing.T) {
+	dbname := "withcollationtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE derived ENGINE=InnoDB AS SELECT 'x' AS label`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithCollation("utf8mb4_general_ci"))
+	require.NoError(t, err)
+	require.Equal(t, "utf8mb4_general_ci", columnCollation(fullDSN(dbname), "derived", "label"))
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithCollation("not_a_collation"))
+	require.EqualError(t, err, `unknown collation "not_a_collation"`)
+}
+
+func dsnFor(server string, dbname string) string {
+	cfg, err := mysql.ParseDSN(server)
+	if err != nil {
+		panic(err)
+	}
+
+	cfg.DBName = dbname
+	return cfg.FormatDSN()
+}
+
+func columnCollation(dsn string, table string, column string) string {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var collation string
+	err = conn.QueryRow(
+		`SELECT COLLATION_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`,
+		table, column,
+	).Scan(&collation)
+	if err != nil {
+		panic(fmt.Errorf("failed reading collation of %s.%s: %s", table, column, err))
+	}
+
+	return collation
+}
+
+func TestWithSessionVariables(t *testing.T) {
+	dbname := "sessionvariablestest"
+	dropDB(dbname)
+
+	variables := migration.WithSessionVariables(map[string]string{
+		"sql_mode":                 "ANSI_QUOTES,STRICT_ALL_TABLES",
+		"auto_increment_increment": "5",
+	})
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE "settings" ( increment INT NOT NULL, mode VARCHAR(255) NOT NULL ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `INSERT INTO "settings" SELECT @@SESSION.auto_increment_increment, @@SESSION.sql_mode`},
+	}
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, variables, migration.WithLogger(migration.NopLogger{})))
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var increment int
+	var mode string
+	require.NoError(t, conn.QueryRow("SELECT increment, mode FROM settings").Scan(&increment, &mode))
+	require.Equal(t, 5, increment)
+	require.Equal(t, "ANSI_QUOTES,STRICT_ALL_TABLES", mode)
+
+	// schema files are loaded with them too
+	dir := t.TempDir()
+	must(ioutil.WriteFile(dir+"/quoted.sql", []byte(`CREATE TABLE "quoted" ( id INT NOT NULL ) ENGINE=InnoDB`), 0644))
+	dropDB(dbname)
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN(dbname), dir, variables, migration.WithSchemaOnly(), migration.WithLogger(migration.NopLogger{})))
+	require.True(t, tableExists(fullDSN(dbname), "quoted"))
+}
+
+func TestWithSessionVariablesInvalidName(t *testing.T) {
+	_, err := migration.New(fullDSN("sessionvariablestest"), migration.WithSessionVariables(map[string]string{"sql_mode = ''; DROP TABLE x; --": ""}))
+	require.EqualError(t, err, `invalid session variable name "sql_mode = ''; DROP TABLE x; --"`)
+}
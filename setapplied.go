@@ -0,0 +1,137 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// manualAppliedBy is recorded in applied_by for migrations marked with
+// SetApplied.
+const manualAppliedBy = "manual"
+
+func SetApplied(ctx context.Context, dsn string, migrations []Migration, version int, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return err
+	}
+	return m.SetApplied(ctx, migrations, version)
+}
+
+func SetAppliedConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, version int, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return err
+	}
+	return m.SetApplied(ctx, migrations, version)
+}
+
+func SetAppliedSource(ctx context.Context, src Source, migrations []Migration, version int, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return err
+	}
+	return m.SetApplied(ctx, migrations, version)
+}
+
+// SetApplied records version as applied without executing it, e.g. after it
+// was applied by hand during an incident. When migrations are given version
+// must be one of them, and its name and checksum are recorded too. Recording
+// a version that's already recorded does nothing, unless it's dirty, in which
+// case it's assumed to have been finished by hand.
+func (m *Migrator) SetApplied(ctx context.Context, migrations []Migration, version int) error {
+	var migration Migration = manualMigration(version)
+	if migrations != nil {
+		if err := validateMigrations(migrations); err != nil {
+			return err
+		}
+		found := false
+		for _, candidate := range migrations {
+			if candidate.Version() == version {
+				migration, found = candidate, true
+			}
+		}
+		if !found {
+			return errors.Errorf("can't mark migration %d as applied, it isn't one of the migrations given", version)
+		}
+	}
+
+	if err := m.validateMetadata([]Migration{migration}); err != nil {
+		return err
+	}
+
+	conn, err := m.openDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	if err := m.createMigrationsTableIfNotExists(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, dirty, err := m.readVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, v := range dirty {
+		if v != version {
+			continue
+		}
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET dirty = 0, error_text = NULL, failed_at = NULL WHERE id = ?", dialect.QuoteIdentifier(m.tableName)), version)
+		if err != nil {
+			return errors.Wrapf(err, "failed recording migration %d", version)
+		}
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("marked dirty migration %d as applied in db %q, it's assumed to have been finished by hand", version, m.src.DBName),
+			slog.String("db", m.src.DBName),
+			slog.Int("version", version),
+		)
+		return nil
+	}
+	if applied[version] {
+		m.log(ctx, slog.LevelInfo,
+			fmt.Sprintf("migration %d is already recorded as applied in db %q", version, m.src.DBName),
+			slog.String("db", m.src.DBName),
+			slog.Int("version", version),
+		)
+		return nil
+	}
+
+	if err := m.recordMigration(ctx, conn, migration, manualAppliedBy, false); err != nil {
+		return errors.Wrapf(err, "failed recording migration %d", version)
+	}
+
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("marked migration %s as applied in db %q without executing it", describeMigration(migration), m.src.DBName),
+		slog.String("db", m.src.DBName),
+		slog.Int("version", version),
+	)
+
+	return nil
+}
+
+// manualMigration stands in for a migration SetApplied was given no
+// definition of. It has no checksum, so Migrate fills one in later.
+type manualMigration int
+
+func (v manualMigration) Version() int {
+	return int(v)
+}
+
+func (v manualMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	return errors.Errorf("migration %d was marked as applied by hand and can't be executed", int(v))
+}
@@ -0,0 +1,73 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAppliedRecordsAManualChange(t *testing.T) {
+	dbname := "setappliedtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:   1,
+			Name: "create_blarg",
+			Up:   `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_"+dbname)
+	execSQL(fullDSN(dbname), migrations[0].(*migration.Definition).Up)
+
+	err := migration.SetApplied(context.Background(), fullDSN(dbname), migrations, 3, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "can't mark migration 3 as applied, it isn't one of the migrations given")
+
+	err = migration.SetApplied(context.Background(), fullDSN(dbname), migrations, 1, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	// marking it again does nothing
+	err = migration.SetApplied(context.Background(), fullDSN(dbname), migrations, 1, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	applied, err := migration.AppliedVersions(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.Equal(t, "create_blarg", applied[0].Name)
+	require.Equal(t, "manual", applied[0].AppliedBy)
+	require.Equal(t, migrations[0].(*migration.Definition).Checksum(), queryChecksum(fullDSN(dbname), 1).String)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+}
+
+func TestSetAppliedWithoutMigrations(t *testing.T) {
+	dbname := "setappliedtest"
+	dropDB(dbname)
+
+	err := migration.SetApplied(context.Background(), fullDSN(dbname), nil, 1, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.False(t, queryChecksum(fullDSN(dbname), 1).Valid)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	// the checksum is filled in by the next run, which doesn't execute it
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, migrations[0].(*migration.Definition).Checksum(), queryChecksum(fullDSN(dbname), 1).String)
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+}
@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+const errSpecificAccessDenied = 1227 // ER_SPECIFIC_ACCESS_DENIED_ERROR
+
+// WithSkipBinlog turns the binary log off for the session LoadSchema loads
+// files on, and for the migrations with the given versions, such as ones
+// backfilling data that replicas don't need. Their changes aren't replicated,
+// so it's meant for rebuilding test and staging databases rather than a
+// replicated primary. It needs the SUPER, SYSTEM_VARIABLES_ADMIN or
+// SESSION_VARIABLES_ADMIN privilege.
+func WithSkipBinlog(versions ...int) Option {
+	return func(m *Migrator) {
+		m.skipBinlog = true
+		m.binlogSkipped = map[int]bool{}
+		for _, version := range versions {
+			m.binlogSkipped[version] = true
+		}
+	}
+}
+
+// skipsBinlog reports whether migration is executed without the binary log.
+func (m *Migrator) skipsBinlog(migration Migration) bool {
+	return m.binlogSkipped[migration.Version()]
+}
+
+// disableBinlog turns the binary log off for db, which has to be a single
+// connection, returning a func that turns it back on.
+func (m *Migrator) disableBinlog(ctx context.Context, db *sql.DB) (func(), error) {
+	if _, err := db.ExecContext(ctx, "SET SESSION sql_log_bin = 0"); err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == errSpecificAccessDenied {
+			return nil, errors.Errorf("skipping the binary log needs the SUPER, SYSTEM_VARIABLES_ADMIN or SESSION_VARIABLES_ADMIN privilege: %s", mysqlErr.Message)
+		}
+		return nil, errors.Wrap(err, "failed skipping the binary log")
+	}
+
+	return func() {
+		// restored even when ctx is done, as the connection may be reused
+		if _, err := db.ExecContext(context.WithoutCancel(ctx), "SET SESSION sql_log_bin = 1"); err != nil {
+			m.log(ctx, slog.LevelWarn,
+				fmt.Sprintf("failed turning the binary log back on: %s", err),
+				slog.String("db", m.src.DBName),
+				slog.Any("error", err),
+			)
+		}
+	}, nil
+}
@@ -0,0 +1,86 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func binlogFlags(dsn string, table string) []int {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query("SELECT log_bin FROM " + table + " ORDER BY id")
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	flags := []int{}
+	for rows.Next() {
+		var flag int
+		must(rows.Scan(&flag))
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+func TestWithSkipBinlog(t *testing.T) {
+	dbname := "skipbinlogtest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE flags ( id INT NOT NULL, log_bin INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `INSERT INTO flags SELECT 2, @@SESSION.sql_log_bin`},
+		&migration.Definition{ID: 3, Up: `INSERT INTO flags SELECT 3, @@SESSION.sql_log_bin`},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithSkipBinlog(2), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, binlogFlags(fullDSN(dbname), "flags"))
+
+	dir := t.TempDir()
+	must(ioutil.WriteFile(dir+"/flags.sql", []byte(`CREATE TABLE flags ENGINE=InnoDB SELECT 1 AS id, @@SESSION.sql_log_bin AS log_bin`), 0644))
+	dropDB(dbname)
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithSkipBinlog(), migration.WithSchemaOnly(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, binlogFlags(fullDSN(dbname), "flags"))
+}
+
+func TestWithSkipBinlogWithoutPrivilege(t *testing.T) {
+	dbname := "skipbinlogtest"
+	dropDB(dbname)
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_skipbinlogtest")
+
+	execSQL(partialDSN(), `DROP USER IF EXISTS 'migration_unprivileged'@'%'`)
+	execSQL(partialDSN(), `CREATE USER 'migration_unprivileged'@'%' IDENTIFIED BY 'unprivileged'`)
+	execSQL(partialDSN(), `GRANT ALL PRIVILEGES ON migration_test_skipbinlogtest.* TO 'migration_unprivileged'@'%'`)
+	defer execSQL(partialDSN(), `DROP USER 'migration_unprivileged'@'%'`)
+
+	cfg, err := mysql.ParseDSN(fullDSN(dbname))
+	require.NoError(t, err)
+	cfg.User = "migration_unprivileged"
+	cfg.Passwd = "unprivileged"
+
+	dir := t.TempDir()
+	must(ioutil.WriteFile(dir+"/blarg.sql", []byte(`CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`), 0644))
+
+	err = migration.LoadSchemaConfig(context.Background(), cfg, dir,
+		migration.WithSkipBinlog(),
+		migration.WithSchemaOnly(),
+		migration.WithCreateDatabase(false),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "skipping the binary log needs the SUPER, SYSTEM_VARIABLES_ADMIN or SESSION_VARIABLES_ADMIN privilege: "), err.Error())
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+}
@@ -0,0 +1,62 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunsMigrationsInVersionOrder(t *testing.T) {
+	dbname := "sorttest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 3,
+			Up: `ALTER TABLE blarg ADD COLUMN something VARCHAR(64)`,
+		},
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	executed := []int{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLogger(migration.NopLogger{}),
+		migration.WithBeforeHook(func(ctx context.Context, m migration.Migration) error {
+			executed = append(executed, m.Version())
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, executed)
+
+	// the caller's slice is left as it was
+	require.Equal(t, 3, migrations[0].Version())
+
+	dir := fmt.Sprintf("%s/sorttest", os.TempDir())
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	err = migration.DumpSchema(context.Background(), fullDSN(dbname), dir, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	trackedMigrations, err := ioutil.ReadFile(dir + "/_migrations.sql")
+	require.NoError(t, err)
+	require.Regexp(t,
+		regexp.MustCompile(`\AINSERT INTO \x60_migrations\x60 \(id, created_at, applied_by, duration_ms\) VALUES\n\(1, "[^"]+", "[^"]+", \d+\),\n\(2, "[^"]+", "[^"]+", \d+\),\n\(3, "[^"]+", "[^"]+", \d+\)\z`),
+		string(trackedMigrations),
+	)
+}
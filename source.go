@@ -0,0 +1,153 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+// OpenFunc opens a connection pool, either to the server as a whole or to
+// a specific database on it. Each call should return a new pool, as the
+// package closes them once it's done.
+type OpenFunc func(ctx context.Context) (*sql.DB, error)
+
+// OpenConnector returns an OpenFunc backed by a driver.Connector, for
+// setups such as Cloud SQL IAM auth where there is no DSN to hand over.
+func OpenConnector(connector driver.Connector) OpenFunc {
+	return func(ctx context.Context) (*sql.DB, error) {
+		db := sql.OpenDB(connector)
+		limitPool(db)
+		return db, nil
+	}
+}
+
+// Source describes how to reach the database being migrated without going
+// through a DSN string. Server must connect without a database selected and
+// is used for server-level work such as creating DBName when it doesn't exist
+// yet, Database must connect to DBName itself.
+//
+// Connections opened through a Source are used as they are, so pinning the
+// session character set and collation is up to the connector.
+type Source struct {
+	Server   OpenFunc
+	Database OpenFunc
+	DBName   string
+
+	// cfg is set for sources built from a DSN or *mysql.Config, letting the
+	// package adjust session parameters on the connections it opens.
+	cfg *mysql.Config
+}
+
+// maxIdentifierLength is the longest database or table name MySQL allows.
+const maxIdentifierLength = 64
+
+func (s Source) validate() error {
+	if s.Server == nil {
+		return errors.New("source missing server connection")
+	}
+	if s.Database == nil {
+		return errors.New("source missing database connection")
+	}
+	if len(s.DBName) == 0 {
+		return errors.New("source missing database name")
+	}
+	if utf8.RuneCountInString(s.DBName) > maxIdentifierLength {
+		return errors.Errorf("database name %q is longer than %d characters", s.DBName, maxIdentifierLength)
+	}
+	return nil
+}
+
+func sourceFromDSN(dsn string) (Source, error) {
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return Source{}, errors.Wrapf(err, "unable to parse dsn %q", sanitizeDSN(dsn))
+	}
+
+	if len(parsed.DBName) == 0 {
+		return Source{}, errors.Errorf("dsn missing database name")
+	}
+
+	return sourceFromConfig(parsed)
+}
+
+func sourceFromConfig(cfg *mysql.Config) (Source, error) {
+	if cfg == nil {
+		return Source{}, errors.New("missing mysql config")
+	}
+
+	if len(cfg.DBName) == 0 {
+		return Source{}, errors.Errorf("config missing database name")
+	}
+
+	server := cloneConfig(cfg)
+	server.DBName = ""
+	requireParams(server)
+
+	database := cloneConfig(cfg)
+	requireParams(database)
+
+	return Source{
+		Server:   openDSN(server.FormatDSN()),
+		Database: openDSN(database.FormatDSN()),
+		DBName:   cfg.DBName,
+		cfg:      database,
+	}, nil
+}
+
+// sanitizeDSN replaces the password in dsn with ***, so it can be included in
+// errors and logs. It splits dsn the way the driver does, as it may not parse.
+func sanitizeDSN(dsn string) string {
+	slash := strings.LastIndex(dsn, "/")
+	if slash < 0 {
+		slash = len(dsn)
+	}
+	at := strings.LastIndex(dsn[:slash], "@")
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.Index(dsn[:at], ":")
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:colon+1] + "***" + dsn[at:]
+}
+
+// driverCollation is the driver's default connection collation, which is
+// utf8 rather than utf8mb4.
+const driverCollation = "utf8_general_ci"
+
+// requireParams sets the parameters the package relies on on its own
+// connections, whatever the caller's DSN says: DATETIMEs parsed as UTC
+// time.Times, and a connection character set that can hold any string unless
+// the caller chose one.
+func requireParams(cfg *mysql.Config) {
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	if cfg.Collation == driverCollation && len(cfg.Params["charset"]) == 0 {
+		cfg.Collation = "utf8mb4_general_ci"
+	}
+}
+
+// cloneConfig copies cfg so the caller's config is never mutated.
+func cloneConfig(cfg *mysql.Config) *mysql.Config {
+	clone := *cfg
+	if cfg.Params != nil {
+		clone.Params = make(map[string]string, len(cfg.Params))
+		for k, v := range cfg.Params {
+			clone.Params[k] = v
+		}
+	}
+	return &clone
+}
+
+func openDSN(dsn string) OpenFunc {
+	return func(ctx context.Context) (*sql.DB, error) {
+		return connect(dsn)
+	}
+}
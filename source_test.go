@@ -0,0 +1,207 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+type dsnConnector struct {
+	dsn string
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.Driver().Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return mysql.MySQLDriver{}
+}
+
+func TestMigrateSourceWithConnectors(t *testing.T) {
+	dbname := "sourcetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	src := migration.Source{
+		Server:   migration.OpenConnector(dsnConnector{partialDSN()}),
+		Database: migration.OpenConnector(dsnConnector{fullDSN(dbname)}),
+		DBName:   "migration_test_" + dbname,
+	}
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.MigrateSource(context.Background(), src, migrations)
+	require.NoError(t, err)
+	require.True(t, dbExists(dbname))
+
+	versions := queryVersions(fullDSN(dbname))
+	require.Equal(t, 1, len(versions))
+	require.Equal(t, 1, versions[0].ID)
+}
+
+func TestMigrateSourceRequiresDatabaseName(t *testing.T) {
+	src := migration.Source{
+		Server:   migration.OpenConnector(dsnConnector{partialDSN()}),
+		Database: migration.OpenConnector(dsnConnector{partialDSN()}),
+	}
+
+	err := migration.MigrateSource(context.Background(), src, nil)
+	require.EqualError(t, err, "source missing database name")
+}
+
+func TestMigrateConfigWithAwkwardPassword(t *testing.T) {
+	dbname := "configtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	password := "p/ss@w0rd:/@"
+	execSQL(partialDSN(), `DROP USER IF EXISTS 'migration_awkward'@'%'`)
+	execSQL(partialDSN(), `CREATE USER 'migration_awkward'@'%' IDENTIFIED WITH mysql_native_password BY '`+password+`'`)
+	// privileges on the database include creating it
+	execSQL(partialDSN(), `GRANT ALL PRIVILEGES ON migration_test_configtest.* TO 'migration_awkward'@'%'`)
+	defer execSQL(partialDSN(), `DROP USER 'migration_awkward'@'%'`)
+
+	cfg, err := mysql.ParseDSN(partialDSN())
+	require.NoError(t, err)
+	cfg.User = "migration_awkward"
+	cfg.Passwd = password
+	cfg.DBName = "migration_test_" + dbname
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err = migration.MigrateConfig(context.Background(), cfg, migrations)
+	require.NoError(t, err)
+	require.True(t, dbExists(dbname))
+	require.Equal(t, "migration_test_"+dbname, cfg.DBName)
+
+	versions := queryVersions(fullDSN(dbname))
+	require.Equal(t, 1, len(versions))
+}
+
+func TestMigrateConfigRequiresDatabaseName(t *testing.T) {
+	cfg, err := mysql.ParseDSN(partialDSN())
+	require.NoError(t, err)
+	cfg.DBName = ""
+
+	err = migration.MigrateConfig(context.Background(), cfg, nil)
+	require.EqualError(t, err, "config missing database name")
+}
+
+func TestMigrateAwkwardDatabaseNames(t *testing.T) {
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: "CREATE TABLE `order-items` ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB"},
+	}
+
+	// a generated tenant name with hyphens, and a reserved word that can't
+	// carry the usual test prefix
+	reserved, err := mysql.ParseDSN(partialDSN())
+	require.NoError(t, err)
+	reserved.DBName = "select"
+	defer execSQL(partialDSN(), "DROP DATABASE IF EXISTS `select`")
+
+	for _, dsn := range []string{fullDSN("tenant-west-2"), reserved.FormatDSN()} {
+		cfg, err := mysql.ParseDSN(dsn)
+		require.NoError(t, err)
+		execSQL(partialDSN(), "DROP DATABASE IF EXISTS `"+cfg.DBName+"`")
+
+		require.NoError(t, migration.Migrate(context.Background(), dsn, migrations, migration.WithLogger(migration.NopLogger{})))
+		require.True(t, tableExists(dsn, "order-items"))
+		require.Len(t, queryVersions(dsn), 1)
+
+		dir := t.TempDir()
+		require.NoError(t, migration.DumpSchema(context.Background(), dsn, dir))
+
+		execSQL(partialDSN(), "DROP DATABASE `"+cfg.DBName+"`")
+		require.NoError(t, migration.LoadSchema(context.Background(), dsn, dir))
+		require.True(t, tableExists(dsn, "order-items"))
+		require.Len(t, queryVersions(dsn), 1)
+	}
+	dropDB("tenant-west-2")
+}
+
+func TestMigrateDatabaseNameTooLong(t *testing.T) {
+	cfg, err := mysql.ParseDSN(partialDSN())
+	require.NoError(t, err)
+	cfg.DBName = strings.Repeat("a", 65)
+
+	err = migration.MigrateConfig(context.Background(), cfg, nil)
+	require.EqualError(t, err, fmt.Sprintf("database name %q is longer than 64 characters", cfg.DBName))
+}
+
+func TestMigrateWithoutParseTime(t *testing.T) {
+	dbname := "parsetimetest"
+	dropDB(dbname)
+
+	cfg, err := mysql.ParseDSN(fullDSN(dbname))
+	require.NoError(t, err)
+	cfg.ParseTime = false
+	cfg.Loc = time.Local
+	dsn := cfg.FormatDSN()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, created_at DATETIME NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&timeScanningMigration{version: 2},
+	}
+	require.NoError(t, migration.Migrate(context.Background(), dsn, migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), dsn, t.TempDir()))
+
+	// the caller's config is left as it was
+	require.NoError(t, migration.MigrateConfig(context.Background(), cfg, migrations, migration.WithLogger(migration.NopLogger{})))
+	require.False(t, cfg.ParseTime)
+	require.Equal(t, time.Local, cfg.Loc)
+}
+
+// timeScanningMigration scans a DATETIME into a time.Time, which only works
+// with parseTime set.
+type timeScanningMigration struct {
+	version int
+}
+
+func (m *timeScanningMigration) Version() int {
+	return m.version
+}
+
+func (m *timeScanningMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	var now time.Time
+	return conn.QueryRowContext(ctx, "SELECT NOW()").Scan(&now)
+}
+
+func TestErrorsDontContainPassword(t *testing.T) {
+	// unparseable, the driver wants a slash before the database name
+	_, err := migration.New("root:hunter2@tcp(127.0.0.1:3306)")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unable to parse dsn "root:***@tcp(127.0.0.1:3306)"`)
+	require.NotContains(t, err.Error(), "hunter2")
+
+	_, err = migration.MigrateMatching(context.Background(), "root:p@ss:w@rd@tcp(127.0.0.1:3306)/?parseTime=maybe", "tenant_", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `"root:***@tcp(127.0.0.1:3306)/?parseTime=maybe"`)
+	require.NotContains(t, err.Error(), "w@rd")
+
+	cfg, err := mysql.ParseDSN(fullDSN("passwordtest"))
+	require.NoError(t, err)
+	cfg.Passwd = "definitely-not-the-password"
+
+	err = migration.MigrateConfig(context.Background(), cfg, nil, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), cfg.Passwd)
+}
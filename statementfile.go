@@ -0,0 +1,98 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// checkStatementFile catches a Definition's UpFile or DownFile being missing
+// or empty before anything is executed, rather than part way through a run.
+func checkStatementFile(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return errors.Wrapf(err, "can't read %q", file)
+	}
+	if info.IsDir() {
+		return errors.Errorf("%q is a directory", file)
+	}
+	if info.Size() == 0 {
+		return errors.Errorf("%q is empty", file)
+	}
+	return nil
+}
+
+// execFile executes the statements in file one at a time as they're read, so
+// the whole file is never held in memory.
+func execFile(ctx context.Context, conn *sql.DB, file string) error {
+	executed := 0
+	err := forEachFileStatement(ctx, file, func(statement string) error {
+		executed++
+		statement, err := rewriteStatement(ctx, statement)
+		if err != nil {
+			return errors.Wrapf(err, "statement %d of %q", executed, file)
+		}
+		if err := execStatement(ctx, conn, statement); err != nil {
+			return errors.Wrapf(err, "statement %d of %q failed (%s)", executed, file, dialect.Snippet(statement, 80))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if executed == 0 {
+		return errors.Errorf("%q has no statements", file)
+	}
+	return nil
+}
+
+// forEachFileStatement calls fn with each statement in file as it's read.
+func forEachFileStatement(ctx context.Context, file string, fn func(statement string) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return errors.Wrapf(err, "failed opening %q", file)
+	}
+	defer f.Close()
+
+	scanner := dialect.NewScanner(&contextReader{ctx: ctx, r: f})
+	for scanner.Scan() {
+		if err := fn(scanner.Statement()); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(scanner.Err(), "failed reading %q", file)
+}
+
+// fileChecksum is the hex encoded SHA-256 of file's contents.
+func fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed opening %q", file)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", errors.Wrapf(err, "failed reading %q", file)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contextReader stops reading once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,128 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionUpFile(t *testing.T) {
+	dbname := "upfiletest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	dir := fmt.Sprintf("%s/upfiletest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	inserts := &strings.Builder{}
+	inserts.WriteString("CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;\n")
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(inserts, "INSERT INTO blarg (id, name) VALUES (%d, 'row;%d');\n", i, i)
+	}
+	must(ioutil.WriteFile(dir+"/0001_up.sql", []byte(inserts.String()), 0644))
+	must(ioutil.WriteFile(dir+"/0001_down.sql", []byte("DROP TABLE blarg;\n"), 0644))
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, UpFile: dir + "/0001_up.sql", DownFile: dir + "/0001_down.sql"},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+	require.Equal(t, migrations[0].(*migration.Definition).Checksum(), queryChecksum(fullDSN(dbname), 1).String)
+	require.Equal(t, 5000, countRows(fullDSN(dbname), "blarg"))
+
+	err = migration.Redo(context.Background(), fullDSN(dbname), migrations, 1, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, 5000, countRows(fullDSN(dbname), "blarg"))
+}
+
+func TestDefinitionUpFileUnreadableWhenRecorded(t *testing.T) {
+	dbname := "upfiletest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/upfiletest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+	must(ioutil.WriteFile(dir+"/0001_up.sql", []byte("CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;\n"), 0644))
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, UpFile: dir + "/0001_up.sql"},
+	}
+
+	// removed after validation, as a deploy swapping the files out might
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithBeforeHook(func(ctx context.Context, m migration.Migration) error {
+			return os.Remove(dir + "/0001_up.sql")
+		}),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("failed checksumming migration 1: failed opening %q", dir+"/0001_up.sql"))
+	require.Len(t, queryVersions(fullDSN(dbname)), 0)
+}
+
+func TestDefinitionUpFileValidation(t *testing.T) {
+	dbname := "upfiletest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/upfiletest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+	must(ioutil.WriteFile(dir+"/empty.sql", nil, 0644))
+
+	cases := map[string]struct {
+		definition *migration.Definition
+		err        string
+	}{
+		"missing file": {
+			definition: &migration.Definition{ID: 2, UpFile: dir + "/missing.sql"},
+			err:        fmt.Sprintf("invalid migration 2: can't read %q", dir+"/missing.sql"),
+		},
+		"empty file": {
+			definition: &migration.Definition{ID: 2, UpFile: dir + "/empty.sql"},
+			err:        fmt.Sprintf("invalid migration 2: %q is empty", dir+"/empty.sql"),
+		},
+		"up and up file": {
+			definition: &migration.Definition{ID: 2, Up: "SELECT 1", UpFile: dir + "/empty.sql"},
+			err:        "invalid migration 2: UpFile is set along with Up or UpStatements",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			migrations := []migration.Migration{
+				&migration.Definition{ID: 1, Up: "CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB"},
+				c.definition,
+			}
+
+			err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.err)
+			require.False(t, tableExists(fullDSN(dbname), "blarg"))
+		})
+	}
+}
+
+func countRows(dsn string, table string) int {
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	var rows int
+	must(conn.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&rows))
+	return rows
+}
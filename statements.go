@@ -0,0 +1,29 @@
+package migration
+
+import (
+	"github.com/pkg/errors"
+)
+
+// WithSingleStatements rejects Definitions whose Up or UpStatements hold more
+// than one statement, before anything is executed.
+func WithSingleStatements() Option {
+	return func(m *Migrator) {
+		m.singleStatements = true
+	}
+}
+
+func (m *Migrator) validateStatements(migrations []Migration) error {
+	if !m.singleStatements {
+		return nil
+	}
+	for _, migration := range migrations {
+		definition, ok := migration.(*Definition)
+		if !ok {
+			continue
+		}
+		if statements := definition.statements(); len(statements) > 1 {
+			return errors.Errorf("migration %d has %d statements, WithSingleStatements only allows one", migration.Version(), len(statements))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionWithSeveralStatements(t *testing.T) {
+	dbname := "statementstest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `
+				CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+				-- a comment; with a semicolon
+				INSERT INTO blarg (id, name) VALUES (1, 'semi;colon'), (2, 'it''s; quoted');
+			`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var names string
+	must(conn.QueryRow("SELECT GROUP_CONCAT(name ORDER BY id SEPARATOR '|') FROM blarg").Scan(&names))
+	require.Equal(t, "semi;colon|it's; quoted", names)
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 2,
+		Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB; ALTER TABLE gralb ADD COLUMN di INT; SELECT 1`,
+	})
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed executing migration 2: statement 2 of 3 failed (ALTER TABLE gralb ADD COLUMN di INT): Error 1060")
+}
+
+func TestWithSingleStatements(t *testing.T) {
+	dbname := "statementstest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB; SELECT 1`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithSingleStatements(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, "migration 2 has 2 statements, WithSingleStatements only allows one")
+	require.Len(t, queryVersions(fullDSN(dbname)), 0)
+}
+
+func TestDelimiterBlocks(t *testing.T) {
+	dbname := "delimitertest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations, err := migration.FromFS(os.DirFS("testdata"), "routines")
+	require.NoError(t, err)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	execSQL(fullDSN(dbname), "CALL add_blarg(2, 'second')")
+	execSQL(fullDSN(dbname), "CALL add_blarg(2, 'again')")
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var logged string
+	must(conn.QueryRow("SELECT GROUP_CONCAT(CONCAT(id, note) ORDER BY id SEPARATOR '|') FROM blarg_log").Scan(&logged))
+	require.Equal(t, "1inserted;|2inserted;", logged)
+}
+
+func TestLoadSchemaWithDelimiterBlocks(t *testing.T) {
+	dbname := "delimitertest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/delimitertest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+
+	routines, err := ioutil.ReadFile("testdata/routines/0001_create_blarg.sql")
+	require.NoError(t, err)
+	trigger, err := ioutil.ReadFile("testdata/routines/0002_log_blarg_inserts.sql")
+	require.NoError(t, err)
+	must(ioutil.WriteFile(dir+"/blarg.sql", append(routines, trigger...), 0644))
+
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithSchemaOnly(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	execSQL(fullDSN(dbname), "INSERT INTO blarg (id, name) VALUES (1, 'first')")
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var logged int
+	must(conn.QueryRow("SELECT COUNT(*) FROM blarg_log").Scan(&logged))
+	require.Equal(t, 1, logged)
+}
+
+func TestLoadSchemaWithSeveralStatementsPerFile(t *testing.T) {
+	dbname := "delimitertest"
+	dropDB(dbname)
+	dir := t.TempDir()
+
+	must(ioutil.WriteFile(dir+"/blarg.sql", []byte(`/*!40101 SET @saved_cs_client = @@character_set_client */;
+CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+INSERT INTO blarg (id, name) VALUES (1, 'first;'), (2, 'second');
+/*!40101 SET character_set_client = @saved_cs_client */;
+`), 0644))
+
+	err := migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithSchemaOnly(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, 2, countRows(fullDSN(dbname), "blarg"))
+
+	// a statement failing after others succeeded isn't lost
+	dropDB(dbname)
+	must(ioutil.WriteFile(dir+"/blarg.sql", []byte(`CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+INSERT INTO blarg (id) VALUES (1);
+INSERT INTO gralb (id) VALUES (1);
+`), 0644))
+
+	err = migration.LoadSchema(context.Background(), fullDSN(dbname), dir, migration.WithSchemaOnly(), migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `failed loading "blarg.sql": statement 3 of 3 failed (INSERT INTO gralb (id) VALUES (1))`)
+}
+
+func TestDefinitionUpStatements(t *testing.T) {
+	dbname := "statementstest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			UpStatements: []string{
+				`CREATE TABLE blarg ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+				`INSERT INTO blarg (id, name) VALUES (1, 'semi;colon')`,
+			},
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var name string
+	must(conn.QueryRow("SELECT name FROM blarg WHERE id = 1").Scan(&name))
+	require.Equal(t, "semi;colon", name)
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 2,
+		UpStatements: []string{
+			`CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+			`SELECT 1`,
+			`ALTER TABLE gralb ADD COLUMN di INT`,
+		},
+	})
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed executing migration 2: statement 3 of 3 failed (ALTER TABLE gralb ADD COLUMN di INT): Error 1060")
+}
+
+func TestDefinitionWithUpAndUpStatements(t *testing.T) {
+	dbname := "statementstest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:           1,
+			Up:           `SELECT 1`,
+			UpStatements: []string{`SELECT 1`},
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.EqualError(t, err, "invalid migration 1: both Up and UpStatements are set")
+	require.Len(t, queryVersions(fullDSN(dbname)), 0)
+}
@@ -0,0 +1,141 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// ExecutionStrategy executes the statements of a Definition in place of
+// executing them over the migration's connection, e.g. to run an ALTER TABLE
+// through an online schema change tool. The migration is only recorded as
+// applied when every statement executes without an error.
+type ExecutionStrategy interface {
+	Execute(ctx context.Context, target ExecutionTarget, statement string) error
+}
+
+// ExecutionTarget is what an ExecutionStrategy executes statements against.
+type ExecutionTarget struct {
+	Version int
+	// Conn is the connection the statement would otherwise be executed over.
+	Conn *sql.DB
+	// Config describes how to connect to the database, and is nil for
+	// Migrators created from a Source.
+	Config *mysql.Config
+
+	m   *Migrator
+	ctx context.Context
+}
+
+// Log logs msg through the Migrator's logger, e.g. for a tool's output.
+func (t ExecutionTarget) Log(msg string) {
+	t.m.log(t.ctx, slog.LevelInfo, msg,
+		slog.Int("version", t.Version),
+		slog.String("db", t.m.src.DBName),
+	)
+}
+
+// WithExecutionStrategy executes the statements of the Definition with
+// version using strategy, taking precedence over its Strategy field.
+func WithExecutionStrategy(version int, strategy ExecutionStrategy) Option {
+	return func(m *Migrator) {
+		if m.strategies == nil {
+			m.strategies = map[int]ExecutionStrategy{}
+		}
+		m.strategies[version] = strategy
+	}
+}
+
+func (m *Migrator) strategyFor(migration Migration) ExecutionStrategy {
+	if strategy, ok := m.strategies[migration.Version()]; ok {
+		return strategy
+	}
+	if definition, ok := migration.(*Definition); ok {
+		return definition.Strategy
+	}
+	return nil
+}
+
+type executionKey struct{}
+
+// execution is how the statements of a migration are executed. It's passed
+// to Definition.Migrate in its context, since a Definition doesn't have the
+// Migrator executing it.
+type execution struct {
+	m         *Migrator
+	version   int
+	strategy  ExecutionStrategy
+	onlineDDL bool
+	// rowsAffected is added to by Chunked migrations.
+	rowsAffected int64
+}
+
+// executionFor describes how the statements of migration are executed.
+func (m *Migrator) executionFor(migration Migration) execution {
+	e := execution{version: migration.Version(), strategy: m.strategyFor(migration)}
+	if definition, ok := migration.(*Definition); ok {
+		e.onlineDDL = m.enforceOnlineDDL && !definition.AllowTableCopy && e.strategy == nil
+	}
+	return e
+}
+
+// withExecution sets up ctx for execStatements and execFile to rewrite and
+// execute statements as e describes.
+func (m *Migrator) withExecution(ctx context.Context, e *execution) context.Context {
+	e.m = m
+	return context.WithValue(ctx, executionKey{}, e)
+}
+
+// changesStatements reports whether statements may be executed differently
+// from how they're written.
+func (e *execution) changesStatements() bool {
+	return e.m.rewriter != nil || e.strategy != nil || e.onlineDDL
+}
+
+func executionFrom(ctx context.Context) *execution {
+	e, _ := ctx.Value(executionKey{}).(*execution)
+	return e
+}
+
+// execStatement executes statement over conn, or with the migration's
+// ExecutionStrategy when it has one, logging it at debug level when it may
+// have been changed along the way.
+func execStatement(ctx context.Context, conn *sql.DB, statement string) error {
+	e := executionFrom(ctx)
+	if e == nil || !e.changesStatements() {
+		_, err := conn.ExecContext(ctx, statement)
+		return err
+	}
+
+	enforced := false
+	if e.onlineDDL {
+		statement, enforced = dialect.EnforceOnlineDDL(statement)
+	}
+
+	e.m.log(ctx, slog.LevelDebug,
+		fmt.Sprintf("executing %s", statement),
+		slog.Int("version", e.version),
+		slog.String("db", e.m.src.DBName),
+		slog.String("sql", statement),
+	)
+
+	if e.strategy == nil {
+		_, err := conn.ExecContext(ctx, statement)
+		if enforced && isOnlineDDLRejected(err) {
+			return errors.Wrapf(err, "WithEnforceOnlineDDL added %s as the ALTER has to be done without copying or locking the table, "+
+				"set AllowTableCopy on the Definition if that's acceptable", dialect.OnlineDDLClause)
+		}
+		return err
+	}
+
+	target := ExecutionTarget{Version: e.version, Conn: conn, m: e.m, ctx: ctx}
+	if e.m.src.cfg != nil {
+		target.Config = cloneConfig(e.m.src.cfg)
+	}
+	return e.strategy.Execute(ctx, target, statement)
+}
@@ -0,0 +1,231 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// WithLargeTableWarning logs a warning before running migrations that alter
+// an existing table with more than rows rows, going by
+// information_schema.TABLES. Definitions can opt out with SkipSizeCheck.
+func WithLargeTableWarning(rows int64) Option {
+	return func(m *Migrator) {
+		m.largeTableRows = rows
+	}
+}
+
+// WithMaxTableRows fails the run before anything is executed when a pending
+// migration alters an existing table with more than rows rows, going by
+// information_schema.TABLES. Definitions can opt out with SkipSizeCheck.
+func WithMaxTableRows(rows int64) Option {
+	return func(m *Migrator) {
+		m.maxTableRows = rows
+	}
+}
+
+// WithoutPlanTableSizes stops Plan and WithDryRun looking up the tables the
+// pending migrations touch, leaving PlannedMigration.Tables empty.
+func WithoutPlanTableSizes() Option {
+	return func(m *Migrator) {
+		m.skipPlanSizes = true
+	}
+}
+
+type tableSize struct {
+	rows int64
+	data int64
+}
+
+// checkTableSizes looks up the size of the existing tables altered by the
+// pending Definitions, warning about or refusing to alter those over the
+// configured limits. Table names are taken from the statements on a best
+// effort basis, see dialect.TargetTable.
+func (m *Migrator) checkTableSizes(ctx context.Context, conn *sql.DB, migrations []Migration, applied map[int]bool) error {
+	if m.largeTableRows <= 0 && m.maxTableRows <= 0 {
+		return nil
+	}
+
+	skipped := map[int]bool{}
+	for _, migration := range migrations {
+		if definition, ok := migration.(*Definition); ok && definition.SkipSizeCheck {
+			skipped[migration.Version()] = true
+		}
+	}
+
+	checked := map[string]bool{}
+	return forEachPendingStatement(ctx, migrations, applied, func(version int, statement string) error {
+		database, table, ok := dialect.TargetTable(statement)
+		if !ok || skipped[version] {
+			return nil
+		}
+		if len(database) == 0 {
+			database = m.src.DBName
+		}
+
+		key := fmt.Sprintf("%d %s.%s", version, database, table)
+		if checked[key] {
+			return nil
+		}
+		checked[key] = true
+
+		size, exists, err := m.readTableSize(ctx, conn, database, table)
+		if isVerificationTimeout(err) {
+			m.verificationSkipped(ctx, fmt.Sprintf("reading the size of table %s", table))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return nil
+		}
+
+		if m.maxTableRows > 0 && size.rows > m.maxTableRows {
+			return errors.Errorf(
+				"migration %d alters table %s with about %d rows, more than the %d WithMaxTableRows allows, use SkipSizeCheck if that's expected",
+				version, table, size.rows, m.maxTableRows,
+			)
+		}
+		if m.largeTableRows > 0 && size.rows > m.largeTableRows {
+			m.log(ctx, slog.LevelWarn,
+				fmt.Sprintf("migration %d alters table %s with about %d rows (%d bytes of data), it may take a while", version, table, size.rows, size.data),
+				slog.Int("version", version),
+				slog.String("db", m.src.DBName),
+				slog.String("table", table),
+				slog.Int64("rows", size.rows),
+				slog.Int64("data_length", size.data),
+			)
+		}
+		return nil
+	})
+}
+
+// readTableSize reads the estimated number of rows and size of the data of a
+// table, which is only as accurate as the table's statistics.
+func (m *Migrator) readTableSize(ctx context.Context, conn *sql.DB, database string, table string) (size tableSize, exists bool, err error) {
+	var rows, data sql.NullInt64
+	err = conn.QueryRowContext(ctx,
+		m.verificationQuery("SELECT TABLE_ROWS, DATA_LENGTH FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"),
+		database, table,
+	).Scan(&rows, &data)
+	if err == sql.ErrNoRows {
+		return tableSize{}, false, nil
+	}
+	if err != nil {
+		return tableSize{}, false, errors.Wrapf(err, "failed reading size of table %q", table)
+	}
+	return tableSize{rows: rows.Int64, data: data.Int64}, true, nil
+}
+
+// planTables lists the tables each of the pending Definitions creates or
+// alters, along with their size. Table names are taken from the statements on
+// a best effort basis, see dialect.TargetTable.
+func (m *Migrator) planTables(ctx context.Context, pending []PlannedMigration, migrations []Migration, applied map[int]bool) error {
+	tables := map[int][]PlannedTable{}
+	listed := map[string]bool{}
+	err := forEachPendingStatement(ctx, migrations, applied, func(version int, statement string) error {
+		database, table, ok := dialect.TargetTable(statement)
+		if !ok {
+			database, table, ok = dialect.CreatedTable(statement)
+		}
+		if !ok {
+			return nil
+		}
+		if len(database) == 0 {
+			database = m.src.DBName
+		}
+
+		key := fmt.Sprintf("%d %s", version, tableKey(database, table))
+		if listed[key] {
+			return nil
+		}
+		listed[key] = true
+		tables[version] = append(tables[version], PlannedTable{Database: database, Table: table})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	sizes, err := m.readTableSizes(ctx, pending, tables)
+	if isVerificationTimeout(err) {
+		m.verificationSkipped(ctx, "reading the size of the tables pending migrations touch")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		for _, table := range tables[pending[i].Version] {
+			size, exists := sizes[tableKey(table.Database, table.Table)]
+			table.New = !exists
+			table.EstimatedRows = size.rows
+			table.EstimatedDataLength = size.data
+			pending[i].Tables = append(pending[i].Tables, table)
+		}
+	}
+	return nil
+}
+
+// readTableSizes reads the size of every table in tables with a single query,
+// like readTableSize, keyed by tableKey. Tables that don't exist are left out.
+func (m *Migrator) readTableSizes(ctx context.Context, pending []PlannedMigration, tables map[int][]PlannedTable) (map[string]tableSize, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	queried := map[string]bool{}
+	for _, planned := range pending {
+		for _, table := range tables[planned.Version] {
+			key := tableKey(table.Database, table.Table)
+			if queried[key] {
+				continue
+			}
+			queried[key] = true
+			conditions = append(conditions, "(?, ?)")
+			args = append(args, table.Database, table.Table)
+		}
+	}
+
+	conn, err := m.src.Server(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx,
+		m.verificationQuery("SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_ROWS, DATA_LENGTH FROM information_schema.TABLES WHERE (TABLE_SCHEMA, TABLE_NAME) IN ("+strings.Join(conditions, ", ")+")"),
+		args...,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading size of tables")
+	}
+	defer rows.Close()
+
+	sizes := map[string]tableSize{}
+	for rows.Next() {
+		var database, table string
+		var count, data sql.NullInt64
+		if err := rows.Scan(&database, &table, &count, &data); err != nil {
+			return nil, errors.Wrap(err, "failed reading size of tables")
+		}
+		sizes[tableKey(database, table)] = tableSize{rows: count.Int64, data: data.Int64}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed reading size of tables")
+	}
+	return sizes, nil
+}
+
+// tableKey identifies a table regardless of how its name is capitalised.
+func tableKey(database string, table string) string {
+	return strings.ToLower(database + "." + table)
+}
@@ -0,0 +1,129 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableSizeChecks(t *testing.T) {
+	dbname := "tablesizetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	values := []string{}
+	for i := 1; i <= 500; i++ {
+		values = append(values, fmt.Sprintf("(%d)", i))
+	}
+	execSQL(fullDSN(dbname), "INSERT INTO blarg (id) VALUES "+strings.Join(values, ", "))
+	execSQL(fullDSN(dbname), "ANALYZE TABLE blarg")
+
+	alter := &migration.Definition{
+		ID: 2,
+		Up: `ALTER TABLE blarg ADD COLUMN name VARCHAR(64) NOT NULL DEFAULT ''`,
+	}
+	migrations = append(migrations, alter)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithMaxTableRows(100),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Regexp(t, `^migration 2 alters table blarg with about \d+ rows, more than the 100 WithMaxTableRows allows`, err.Error())
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithMaxTableRows(100000),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+
+	migrations = append(migrations, &migration.Definition{
+		ID: 3,
+		Up: `CREATE INDEX name ON blarg (name)`,
+	})
+	logger := &capturingLogger{}
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLargeTableWarning(100),
+		migration.WithLogger(logger),
+	)
+	require.NoError(t, err)
+	require.Len(t, findLines(logger.lines, "migration 3 alters table blarg with about "), 1)
+}
+
+func TestSkipSizeCheck(t *testing.T) {
+	dbname := "tablesizetest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB;
+				INSERT INTO blarg (id) VALUES (1), (2), (3), (4), (5);
+				ANALYZE TABLE blarg`,
+		},
+	}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+
+	migrations = append(migrations, &migration.Definition{
+		ID:            2,
+		Up:            `ALTER TABLE blarg ADD COLUMN name VARCHAR(64) NOT NULL DEFAULT ''`,
+		SkipSizeCheck: true,
+	})
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithMaxTableRows(1),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, queryVersions(fullDSN(dbname)), 2)
+}
+
+func findLines(lines []string, prefix string) []string {
+	found := []string{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			found = append(found, line)
+		}
+	}
+	return found
+}
+
+func TestVerificationTimeout(t *testing.T) {
+	dbname := "verificationtimeouttest"
+	dropDB(dbname)
+	migration.MustMigrate(context.Background(), fullDSN(dbname), nil, migration.WithLogger(migration.NopLogger{}))
+
+	m, err := migration.New(fullDSN(dbname), migration.WithVerificationTimeout(time.Millisecond))
+	require.NoError(t, err)
+
+	conn, err := sql.Open("mysql", fullDSN(dbname))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// deliberately slow, so the 1ms cap is always exceeded
+	query := m.VerificationQuery("SELECT COUNT(*) FROM information_schema.COLUMNS a, information_schema.COLUMNS b, information_schema.COLUMNS c")
+	require.Contains(t, query, "MAX_EXECUTION_TIME(1)")
+
+	var count int64
+	err = conn.QueryRow(query).Scan(&count)
+	require.Error(t, err)
+	require.True(t, migration.IsVerificationTimeout(err), "got %v", err)
+	require.False(t, migration.IsVerificationTimeout(errors.New("some other error")))
+}
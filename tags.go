@@ -0,0 +1,59 @@
+package migration
+
+// Tagged is implemented by migrations that only run in some environments, for
+// those other than Definition which has a Tags field.
+type Tagged interface {
+	Tags() []string
+}
+
+// WithTags runs tagged migrations only when one of their tags is one of tags,
+// e.g. WithTags("staging"). Untagged migrations always run. Those that are
+// filtered out aren't recorded as applied, so they run once a later run
+// includes one of their tags, and are listed in Report.Filtered.
+func WithTags(tags ...string) Option {
+	return func(m *Migrator) {
+		m.tags = append(m.tags, tags...)
+	}
+}
+
+func migrationTags(migration Migration) []string {
+	switch tagged := migration.(type) {
+	case *Definition:
+		return tagged.Tags
+	case Tagged:
+		return tagged.Tags()
+	}
+	return nil
+}
+
+// included reports whether migration runs with the Migrator's tags.
+func (m *Migrator) included(migration Migration) bool {
+	tags := migrationTags(migration)
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		for _, enabled := range m.tags {
+			if tag == enabled {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterByTags removes the pending migrations that don't run with the
+// Migrator's tags, returning their versions. Applied migrations are kept
+// whatever their tags.
+func (m *Migrator) filterByTags(migrations []Migration, applied map[int]bool) (included []Migration, filtered []int) {
+	included = make([]Migration, 0, len(migrations))
+	filtered = []int{}
+	for _, migration := range migrations {
+		if applied[migration.Version()] || m.included(migration) {
+			included = append(included, migration)
+		} else {
+			filtered = append(filtered, migration.Version())
+		}
+	}
+	return included, filtered
+}
@@ -0,0 +1,133 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func taggedMigrations() []migration.Migration {
+	return []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE fixtures ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, Tags: []string{"test", "staging"}},
+		&migration.Definition{ID: 3, Up: `CREATE TABLE audit ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`, Tags: []string{"production"}},
+	}
+}
+
+func TestWithTags(t *testing.T) {
+	dbname := "tagstest"
+	dropDB(dbname)
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), taggedMigrations(),
+		migration.WithTags("production"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 2)
+	require.Equal(t, []int{2}, report.Filtered)
+	require.True(t, tableExists(fullDSN(dbname), "audit"))
+	require.False(t, tableExists(fullDSN(dbname), "fixtures"))
+
+	versions := queryVersions(fullDSN(dbname))
+	require.Len(t, versions, 2)
+	require.Equal(t, 1, versions[0].ID)
+	require.Equal(t, 3, versions[1].ID)
+
+	plan, err := migration.Plan(context.Background(), fullDSN(dbname), taggedMigrations(), migration.WithTags("production"))
+	require.NoError(t, err)
+	require.Equal(t, []migration.PlannedMigration{
+		{
+			Version:  2,
+			SQL:      `CREATE TABLE fixtures ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+			Filtered: true,
+			Tables:   []migration.PlannedTable{{Database: "migration_test_tagstest", Table: "fixtures", New: true}},
+		},
+	}, plan)
+	require.NoError(t, migration.CheckNoPending(context.Background(), fullDSN(dbname), taggedMigrations(), migration.WithTags("production")))
+
+	upToDate, err := migration.IsUpToDate(context.Background(), fullDSN(dbname), taggedMigrations(), migration.WithTags("production"))
+	require.NoError(t, err)
+	require.True(t, upToDate)
+
+	// the filtered migration runs once its tag is enabled, and the
+	// production one that's already applied isn't executed again
+	report, err = migration.MigrateWithReport(context.Background(), fullDSN(dbname), taggedMigrations(),
+		migration.WithTags("staging"),
+		migration.WithAllowOutOfOrder(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, 2, report.Applied[0].Version)
+	require.Equal(t, []int{1, 3}, report.Skipped)
+	require.Empty(t, report.Filtered)
+	require.True(t, tableExists(fullDSN(dbname), "fixtures"))
+}
+
+func TestWithoutTagsOnlyRunsUntagged(t *testing.T) {
+	dbname := "tagstest"
+	dropDB(dbname)
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), taggedMigrations(), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.Equal(t, []int{2, 3}, report.Filtered)
+	require.Len(t, queryVersions(fullDSN(dbname)), 1)
+
+	pending, err := migration.CheckPending(context.Background(), fullDSN(dbname), taggedMigrations())
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
+
+func TestDryRunReportsFilteredMigrations(t *testing.T) {
+	dbname := "tagstest"
+	dropDB(dbname)
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), taggedMigrations(),
+		migration.WithTags("test"),
+		migration.WithDryRun(),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []int{3}, report.Filtered)
+	require.Len(t, report.Pending, 3)
+	require.True(t, report.Pending[2].Filtered)
+}
+
+type taggedMigration struct {
+	version int
+	tags    []string
+}
+
+func (m *taggedMigration) Version() int {
+	return m.version
+}
+
+func (m *taggedMigration) Tags() []string {
+	return m.tags
+}
+
+func (m *taggedMigration) Migrate(ctx context.Context, conn *sql.DB) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE bralg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`)
+	return err
+}
+
+func TestTaggedMigration(t *testing.T) {
+	dbname := "tagstest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{&taggedMigration{version: 1, tags: []string{"staging"}}}
+
+	report, err := migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations, migration.WithTags("production"), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, report.Filtered)
+	require.False(t, tableExists(fullDSN(dbname), "bralg"))
+
+	report, err = migration.MigrateWithReport(context.Background(), fullDSN(dbname), migrations, migration.WithTags("staging"), migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+	require.Len(t, report.Applied, 1)
+	require.True(t, tableExists(fullDSN(dbname), "bralg"))
+}
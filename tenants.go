@@ -0,0 +1,114 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sort"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+// systemDatabases are never matched by MigrateMatching.
+var systemDatabases = map[string]bool{
+	"information_schema": true,
+	"mysql":              true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+// WithExpectedDatabases makes MigrateMatching create and migrate each of
+// names that doesn't exist yet, e.g. for tenants that have just signed up.
+func WithExpectedDatabases(names ...string) Option {
+	return func(m *Migrator) {
+		m.expectedDatabases = append(m.expectedDatabases, names...)
+	}
+}
+
+// MigrateMatching runs migrations against every database on the server
+// serverDSN connects to whose name matches pattern, such as one database per
+// tenant, like MigrateAll. pattern is a regular expression matched against
+// the start of each name, so a plain prefix like "tenant_" works as is. Any
+// database name in serverDSN is ignored.
+func MigrateMatching(ctx context.Context, serverDSN string, pattern string, migrations []Migration, opts ...Option) ([]DatabaseResult, error) {
+	cfg, err := mysql.ParseDSN(serverDSN)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse dsn %q", sanitizeDSN(serverDSN))
+	}
+
+	matcher, err := regexp.Compile(`\A(?:` + pattern + `)`)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern %q", pattern)
+	}
+
+	settings := &Migrator{}
+	for _, opt := range opts {
+		opt(settings)
+	}
+
+	server := cloneConfig(cfg)
+	server.DBName = ""
+	conn, err := connect(server.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	names, err := matchingDatabases(ctx, conn, matcher)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool, len(names))
+	for _, name := range names {
+		matched[name] = true
+	}
+	for _, name := range settings.expectedDatabases {
+		if !matched[name] {
+			names = append(names, name)
+			matched[name] = true
+		}
+	}
+
+	migrators := make([]*Migrator, len(names))
+	results := make([]DatabaseResult, len(names))
+	for i, name := range names {
+		database := cloneConfig(cfg)
+		database.DBName = name
+		m, err := NewConfig(database, opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid database %q", name)
+		}
+		migrators[i] = m
+		results[i].Database = m.databaseLabel()
+	}
+	return migrateEach(ctx, migrators, results, migrations, opts)
+}
+
+// matchingDatabases lists the databases on the server whose name matches
+// matcher, in name order.
+func matchingDatabases(ctx context.Context, conn *sql.DB, matcher *regexp.Regexp) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT SCHEMA_NAME FROM information_schema.SCHEMATA")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed listing databases")
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.Wrap(err, "failed listing databases")
+		}
+		if !systemDatabases[name] && matcher.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed listing databases")
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
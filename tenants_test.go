@@ -0,0 +1,65 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateMatching(t *testing.T) {
+	for _, db := range []string{"tenant_alpha", "tenant_beta", "tenant_gamma", "other"} {
+		dropDB(db)
+	}
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+	}
+
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_tenant_alpha")
+	execSQL(partialDSN(), "CREATE DATABASE migration_test_other")
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN("tenant_beta"), migrations, migration.WithLogger(migration.NopLogger{})))
+
+	results, err := migration.MigrateMatching(context.Background(), partialDSN(), "migration_test_tenant_", migrations,
+		migration.WithExpectedDatabases("migration_test_tenant_beta", "migration_test_tenant_gamma"),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	require.Equal(t, shardLabel("tenant_alpha"), results[0].Database)
+	require.NoError(t, results[0].Err)
+	require.Len(t, results[0].Report.Applied, 1)
+	require.False(t, results[0].UpToDate())
+
+	require.Equal(t, shardLabel("tenant_beta"), results[1].Database)
+	require.True(t, results[1].UpToDate())
+
+	require.Equal(t, shardLabel("tenant_gamma"), results[2].Database)
+	require.Len(t, results[2].Report.Applied, 1)
+	require.True(t, dbExists("tenant_gamma"))
+
+	require.True(t, tableExists(fullDSN("tenant_alpha"), "blarg"))
+	require.False(t, tableExists(fullDSN("other"), "blarg"))
+}
+
+func TestMigrateMatchingRegexp(t *testing.T) {
+	for _, db := range []string{"tenant_alpha", "tenant_beta", "tenant_gamma"} {
+		dropDB(db)
+		execSQL(partialDSN(), "CREATE DATABASE migration_test_"+db)
+	}
+
+	results, err := migration.MigrateMatching(context.Background(), partialDSN(), `migration_test_tenant_(alpha|gamma)\z`, nil,
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, shardLabel("tenant_alpha"), results[0].Database)
+	require.Equal(t, shardLabel("tenant_gamma"), results[1].Database)
+}
+
+func TestMigrateMatchingInvalidPattern(t *testing.T) {
+	_, err := migration.MigrateMatching(context.Background(), partialDSN(), "tenant_(", nil)
+	require.EqualError(t, err, "invalid pattern \"tenant_(\": error parsing regexp: missing closing ): `\\A(?:tenant_()`")
+}
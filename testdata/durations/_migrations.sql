@@ -0,0 +1,4 @@
+INSERT INTO `_migrations` (id, created_at, name, applied_by, duration_ms) VALUES
+(1, "2024-01-02 03:04:05.000000", "create_blarg", "deploy@staging, pid 12", 1500),
+(2, "2024-01-02 03:04:07.000000", "", "deploy@staging, pid 12", NULL),
+(3, "2024-01-02 03:04:08.000000", "backfill \"names\"", "deploy@staging, pid 12", 120000)
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE blarg ADD COLUMN name VARCHAR(64), ALGORITHM=INSTANT;
@@ -0,0 +1,10 @@
+CREATE TABLE blarg (
+  id INT NOT NULL,
+  name VARCHAR(64) NOT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB;
+
+CREATE TABLE blarg_log (
+  id INT NOT NULL,
+  note VARCHAR(64) NOT NULL
+) ENGINE=InnoDB;
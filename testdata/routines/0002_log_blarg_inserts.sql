@@ -0,0 +1,8 @@
+DELIMITER $$
+
+CREATE TRIGGER blarg_insert AFTER INSERT ON blarg FOR EACH ROW
+BEGIN
+  INSERT INTO blarg_log (id, note) VALUES (NEW.id, 'inserted;');
+END$$
+
+DELIMITER ;
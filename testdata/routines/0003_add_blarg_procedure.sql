@@ -0,0 +1,14 @@
+DELIMITER //
+
+CREATE PROCEDURE add_blarg(IN new_id INT, IN new_name VARCHAR(64))
+BEGIN
+  DECLARE existing INT;
+  SELECT COUNT(*) INTO existing FROM blarg WHERE id = new_id;
+  IF existing = 0 THEN
+    INSERT INTO blarg (id, name) VALUES (new_id, new_name);
+  END IF;
+END //
+
+DELIMITER ;
+
+CALL add_blarg(1, 'first');
@@ -0,0 +1,31 @@
+package migration
+
+import (
+	"context"
+	"time"
+)
+
+// WithMigrationTimeout limits how long each migration can run for, unless a
+// Definition sets its own Timeout. A migration that runs out of time fails
+// the run and is left dirty, see DirtyError. Zero, the default, is no limit.
+func WithMigrationTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.migrationTimeout = timeout
+	}
+}
+
+func (m *Migrator) migrationTimeoutFor(migration Migration) time.Duration {
+	if definition, ok := migration.(*Definition); ok && definition.Timeout > 0 {
+		return definition.Timeout
+	}
+	return m.migrationTimeout
+}
+
+// withOptionalTimeout is context.WithTimeout, other than a zero timeout being
+// no limit.
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,59 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationTimeout(t *testing.T) {
+	dbname := "timeouttest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID:      1,
+			Up:      `SELECT SLEEP(0.5)`,
+			Timeout: 10 * time.Second,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `SELECT SLEEP(0.5)`,
+		},
+		&migration.Definition{
+			ID: 3,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithMigrationTimeout(100*time.Millisecond),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed executing migration 2: timed out after 100ms")
+
+	current, err := migration.CurrentVersion(context.Background(), fullDSN(dbname))
+	require.NoError(t, err)
+	require.Equal(t, 1, current)
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+}
+
+func TestNoMigrationTimeoutByDefault(t *testing.T) {
+	dbname := "timeouttest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `SELECT SLEEP(0.5)`,
+		},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+	require.NoError(t, err)
+}
@@ -0,0 +1,30 @@
+package migration
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Tracer starts spans around the work Migrate does: the run as a whole, each
+// migration, creating the database and the queries against the migrations
+// table. See the otelmigration package for an OpenTelemetry implementation.
+type Tracer interface {
+	// Start begins a span named name as a child of any span in ctx, returning
+	// the context for work inside it and a func ending it with the error that
+	// work failed with, if any.
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(err error))
+}
+
+// WithTracer traces Migrate with tracer.
+func WithTracer(tracer Tracer) Option {
+	return func(m *Migrator) {
+		m.tracer = tracer
+	}
+}
+
+func (m *Migrator) startSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(err error)) {
+	if m.tracer == nil {
+		return ctx, func(error) {}
+	}
+	return m.tracer.Start(ctx, name, attrs...)
+}
@@ -0,0 +1,127 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// triggerSuffix names the files triggers are dumped to, so LoadSchema can
+// create them after the tables and views they use.
+const triggerSuffix = ".trigger.sql"
+
+// schemaDelimiter ends statements with semicolons in their body, e.g. a
+// trigger's BEGIN ... END, in dumped files. LoadSchema and the mysql client
+// both understand the DELIMITER lines around them.
+const schemaDelimiter = ";;"
+
+type trigger struct {
+	name string
+	// follows is the trigger on the same table, event and timing that
+	// this one runs after, if any.
+	follows string
+}
+
+// triggers lists the triggers in the database, in the order they run in.
+func (m *Migrator) triggers(ctx context.Context, conn *sql.DB) ([]trigger, error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT TRIGGER_NAME, EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION FROM information_schema.TRIGGERS "+
+			"WHERE TRIGGER_SCHEMA = DATABASE() ORDER BY EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER",
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to show triggers")
+	}
+	defer rows.Close()
+
+	triggers := []trigger{}
+	previous, previousGroup := "", ""
+	for rows.Next() {
+		var name, table, timing, event string
+		if err := rows.Scan(&name, &table, &timing, &event); err != nil {
+			return nil, errors.Wrap(err, "unable to scan trigger name")
+		}
+		t := trigger{name: name}
+		group := table + "\x00" + timing + "\x00" + event
+		if group == previousGroup {
+			t.follows = previous
+		}
+		triggers = append(triggers, t)
+		previous, previousGroup = name, group
+	}
+	return triggers, rows.Err()
+}
+
+// dumpTrigger writes t's CREATE TRIGGER statement to dir, between
+// DELIMITER lines. A FOLLOWS clause is added when it isn't the first trigger
+// for its table, event and timing, so they run in the same order once loaded.
+func (m *Migrator) dumpTrigger(ctx context.Context, conn *sql.DB, dir SchemaWriter, t trigger) error {
+	if !validFileName(t.name) {
+		return errors.Errorf("trigger %q can't be dumped as its name isn't a valid file name", t.name)
+	}
+
+	createStatement, err := showCreate(ctx, conn, "SHOW CREATE TRIGGER "+dialect.QuoteIdentifier(t.name), "SQL Original Statement")
+	if err != nil {
+		return errors.Wrapf(err, "failed showing create statement for trigger %q", t.name)
+	}
+	createStatement = strings.Replace(createStatement, dialect.QuoteIdentifier(m.src.DBName)+".", "", -1)
+	createStatement = m.normalizeDefinition(createStatement)
+
+	if len(t.follows) > 0 {
+		const forEachRow = "FOR EACH ROW "
+		if i := strings.Index(createStatement, forEachRow); i >= 0 {
+			i += len(forEachRow)
+			createStatement = createStatement[:i] + "FOLLOWS " + dialect.QuoteIdentifier(t.follows) + " " + createStatement[i:]
+		}
+	}
+
+	dump := fmt.Sprintf("DELIMITER %s\n%s%s\nDELIMITER ;\n", schemaDelimiter, createStatement, schemaDelimiter)
+	err = dir.WriteFile(t.name+triggerSuffix, []byte(dump))
+	if err != nil {
+		return errors.Wrapf(err, "failed writing out create trigger statement for trigger %q", t.name)
+	}
+	return nil
+}
+
+// showCreate runs a SHOW CREATE statement and returns its column named
+// column, as the other columns differ between servers and versions.
+func showCreate(ctx context.Context, conn *sql.DB, query string, column string) (string, error) {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", sql.ErrNoRows
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+
+	for i, name := range columns {
+		if name == column {
+			if !values[i].Valid {
+				return "", errors.Errorf("%s is NULL, the user may lack the privileges to see it", column)
+			}
+			return values[i].String, nil
+		}
+	}
+	return "", errors.Errorf("no %s column", column)
+}
@@ -0,0 +1,65 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpAndLoadTriggers(t *testing.T) {
+	dbname := "triggerstest"
+	dropDB(dbname)
+	dropDB("triggerstest_copy")
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE accounts ( id INT NOT NULL, balance INT NOT NULL, note VARCHAR(64) NOT NULL DEFAULT '', PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE audit ( account_id INT NOT NULL, balance INT NOT NULL ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 3, Up: `
+DELIMITER ;;
+CREATE TRIGGER zz_clamp BEFORE INSERT ON accounts FOR EACH ROW
+BEGIN
+  IF NEW.balance < 0 THEN
+    SET NEW.balance = 0;
+  END IF;
+  SET NEW.note = 'clamped';
+END;;
+DELIMITER ;`},
+		// sorts ahead of the trigger it has to run after
+		&migration.Definition{ID: 4, Up: `CREATE TRIGGER aa_note BEFORE INSERT ON accounts FOR EACH ROW SET NEW.note = CONCAT(NEW.note, ' then noted')`},
+		&migration.Definition{ID: 5, Up: `CREATE TRIGGER audit_insert AFTER INSERT ON accounts FOR EACH ROW INSERT INTO audit VALUES (NEW.id, NEW.balance)`},
+	}
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir))
+
+	trigger, err := ioutil.ReadFile(dir + "/aa_note.trigger.sql")
+	require.NoError(t, err)
+	require.Contains(t, string(trigger), "FOLLOWS `zz_clamp`")
+
+	// loaded into a database with another name
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("triggerstest_copy"), dir, migration.WithLogger(migration.NopLogger{})))
+
+	// a database migrated from scratch and one loaded from its dump behave
+	// the same
+	for _, name := range []string{dbname, "triggerstest_copy"} {
+		execSQL(fullDSN(name), "INSERT INTO accounts (id, balance) VALUES (1, -10)")
+
+		conn, err := sql.Open("mysql", fullDSN(name))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var balance int
+		var note string
+		require.NoError(t, conn.QueryRow("SELECT balance, note FROM accounts WHERE id = 1").Scan(&balance, &note))
+		require.Equal(t, 0, balance, name)
+		require.Equal(t, "clamped then noted", note, name)
+
+		require.NoError(t, conn.QueryRow("SELECT balance FROM audit WHERE account_id = 1").Scan(&balance))
+		require.Equal(t, 0, balance, name)
+	}
+}
@@ -0,0 +1,97 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+const (
+	errBadDB       = 1049 // ER_BAD_DB_ERROR
+	errNoSuchTable = 1146 // ER_NO_SUCH_TABLE
+	errBadField    = 1054 // ER_BAD_FIELD_ERROR
+)
+
+func IsUpToDate(ctx context.Context, dsn string, migrations []Migration, opts ...Option) (bool, error) {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return false, err
+	}
+	return m.IsUpToDate(ctx, migrations)
+}
+
+func IsUpToDateConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, opts ...Option) (bool, error) {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return false, err
+	}
+	return m.IsUpToDate(ctx, migrations)
+}
+
+func IsUpToDateSource(ctx context.Context, src Source, migrations []Migration, opts ...Option) (bool, error) {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return false, err
+	}
+	return m.IsUpToDate(ctx, migrations)
+}
+
+// IsUpToDate reports whether every one of migrations has been executed,
+// other than those WithTags excludes, in a single query and without creating
+// anything, e.g. for a readiness probe. A database or tracking table that
+// doesn't exist yet is behind rather than an error, so an error means the
+// database couldn't be asked.
+func (m *Migrator) IsUpToDate(ctx context.Context, migrations []Migration) (bool, error) {
+	if err := validateMigrations(migrations); err != nil {
+		return false, err
+	}
+
+	conn, err := m.src.Database(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	migrations, _ = m.filterByTags(migrations, nil)
+	if len(migrations) == 0 {
+		return true, conn.PingContext(ctx)
+	}
+
+	placeholders := make([]string, len(migrations))
+	versions := make([]interface{}, len(migrations))
+	for i, migration := range migrations {
+		placeholders[i] = "?"
+		versions[i] = migration.Version()
+	}
+
+	// migrations that are still executing, or failed part way, are dirty
+	var applied int
+	err = conn.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id IN (%s) AND dirty = 0", dialect.QuoteIdentifier(m.tableName), strings.Join(placeholders, ", ")),
+		versions...,
+	).Scan(&applied)
+
+	// tables that haven't been upgraded yet don't have the dirty column
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errBadField {
+		err = conn.QueryRowContext(
+			ctx,
+			fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id IN (%s)", dialect.QuoteIdentifier(m.tableName), strings.Join(placeholders, ", ")),
+			versions...,
+		).Scan(&applied)
+	}
+
+	if errors.As(err, &mysqlErr) && (mysqlErr.Number == errBadDB || mysqlErr.Number == errNoSuchTable) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed checking applied migrations in %q", m.tableName)
+	}
+
+	return applied == len(migrations), nil
+}
@@ -0,0 +1,53 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsUpToDate(t *testing.T) {
+	dbname := "uptodatetest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`,
+		},
+	}
+
+	upToDate, err := migration.IsUpToDate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.False(t, upToDate)
+	require.False(t, dbExists(dbname))
+
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations[:1], migration.WithLogger(migration.NopLogger{}))
+
+	upToDate, err = migration.IsUpToDate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.False(t, upToDate)
+
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	upToDate, err = migration.IsUpToDate(context.Background(), fullDSN(dbname), migrations)
+	require.NoError(t, err)
+	require.True(t, upToDate)
+}
+
+func TestIsUpToDateUnreachable(t *testing.T) {
+	cfg, err := mysql.ParseDSN(fullDSN("uptodatetest"))
+	require.NoError(t, err)
+	cfg.Addr = "127.0.0.1:1"
+
+	_, err = migration.IsUpToDateConfig(context.Background(), cfg, nil)
+	require.Error(t, err)
+}
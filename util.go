@@ -2,6 +2,24 @@ package migration
 
 import "database/sql"
 
+// The pools the package opens are short-lived and mostly used one statement
+// at a time, so they're kept small rather than growing with every Exec.
+const (
+	maxOpenConns = 8
+	maxIdleConns = 2
+)
+
 func connect(dsn string) (*sql.DB, error) {
-	return sql.Open("mysql", dsn)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	limitPool(db)
+	return db, nil
+}
+
+// limitPool caps a pool the package opened itself.
+func limitPool(db *sql.DB) {
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
 }
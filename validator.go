@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// WithValidator checks the statements of every pending Definition with
+// validate before any of them is executed, e.g. to enforce a policy such as no
+// RENAME TABLE, failing the run with the first error it returns. Statements
+// are validated one at a time, after WithStatementRewriter has rewritten
+// them. It can be given more than once.
+func WithValidator(validate func(version int, sql string) error) Option {
+	return func(m *Migrator) {
+		m.validators = append(m.validators, validate)
+	}
+}
+
+// checkPendingStatements rewrites and validates the statements of the pending
+// Definitions up front, so that an error stops the run before anything is
+// executed rather than part way through it. Destructive statements are
+// collected and returned together, so they can be reviewed in one go.
+func (m *Migrator) checkPendingStatements(ctx context.Context, migrations []Migration, applied map[int]bool) error {
+	if m.rewriter == nil && len(m.validators) == 0 && m.allowDestructive {
+		return nil
+	}
+
+	destructiveAllowed := map[int]bool{}
+	for _, migration := range migrations {
+		if definition, ok := migration.(*Definition); ok {
+			destructiveAllowed[migration.Version()] = m.allowDestructive || definition.Destructive
+		}
+	}
+
+	destructive := []DestructiveStatement{}
+	err := forEachPendingStatement(ctx, migrations, applied, func(version int, statement string) error {
+		if m.rewriter != nil {
+			rewritten, err := m.rewriter(version, statement)
+			if err != nil {
+				return errors.Wrapf(err, "failed rewriting statement of migration %d (%s)", version, dialect.Snippet(statement, 80))
+			}
+			statement = rewritten
+		}
+
+		for _, validate := range m.validators {
+			if err := validate(version, statement); err != nil {
+				return errors.Wrapf(err, "migration %d failed validation (%s)", version, dialect.Snippet(statement, 80))
+			}
+		}
+
+		if !destructiveAllowed[version] {
+			if kind := dialect.Destructive(statement); len(kind) > 0 {
+				destructive = append(destructive, DestructiveStatement{Version: version, Kind: kind, SQL: statement})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(destructive) > 0 {
+		return &DestructiveError{Statements: destructive}
+	}
+	return nil
+}
+
+// forEachPendingStatement calls fn with each statement of the pending
+// Definitions in order, reading those with an UpFile as it goes.
+func forEachPendingStatement(ctx context.Context, migrations []Migration, applied map[int]bool, fn func(version int, statement string) error) error {
+	for _, migration := range migrations {
+		definition, ok := migration.(*Definition)
+		if !ok || applied[migration.Version()] {
+			continue
+		}
+
+		if len(definition.UpFile) > 0 {
+			if err := forEachFileStatement(ctx, definition.UpFile, func(statement string) error {
+				return fn(migration.Version(), statement)
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, statement := range definition.statements() {
+			if err := fn(migration.Version(), statement); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package migration_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+var renameTablePattern = regexp.MustCompile(`(?i)^\s*RENAME\s+TABLE\b`)
+
+// noRenameTable is an example validator, refusing RENAME TABLE since the
+// application can't run against both names while it's deployed.
+func noRenameTable(version int, sql string) error {
+	if renameTablePattern.MatchString(sql) {
+		return fmt.Errorf("RENAME TABLE isn't allowed, copy the table instead")
+	}
+	return nil
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`(?i)^\s*CREATE\s+TABLE\b`)
+	rowFormatPattern   = regexp.MustCompile(`(?i)\bROW_FORMAT\s*=`)
+)
+
+// requireRowFormat is an example validator, requiring every CREATE TABLE to
+// set its ROW_FORMAT rather than depend on the server's default.
+func requireRowFormat(version int, sql string) error {
+	if createTablePattern.MatchString(sql) && !rowFormatPattern.MatchString(sql) {
+		return fmt.Errorf("CREATE TABLE must set ROW_FORMAT")
+	}
+	return nil
+}
+
+func TestWithValidator(t *testing.T) {
+	dbname := "validatortest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB ROW_FORMAT=DYNAMIC`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB ROW_FORMAT=DYNAMIC;
+				RENAME TABLE blarg TO blarg_old`,
+		},
+	}
+
+	validated := []string{}
+	recordValidated := func(version int, sql string) error {
+		validated = append(validated, fmt.Sprintf("%d: %s", version, sql))
+		return nil
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithValidator(recordValidated),
+		migration.WithValidator(requireRowFormat),
+		migration.WithValidator(noRenameTable),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, "migration 2 failed validation (RENAME TABLE blarg TO blarg_old): RENAME TABLE isn't allowed, copy the table instead")
+	require.Len(t, queryVersions(fullDSN(dbname)), 0)
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+	require.Equal(t, []string{
+		"1: CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB ROW_FORMAT=DYNAMIC",
+		"2: CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB ROW_FORMAT=DYNAMIC",
+		"2: RENAME TABLE blarg TO blarg_old",
+	}, validated)
+
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations[:1],
+		migration.WithValidator(requireRowFormat),
+		migration.WithValidator(noRenameTable),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+
+	// only pending migrations are validated
+	migrations[1] = &migration.Definition{ID: 2, Up: `CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB`}
+	err = migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithValidator(func(version int, sql string) error {
+			require.Equal(t, 2, version)
+			return nil
+		}),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.NoError(t, err)
+}
+
+func TestWithValidatorChecksUpFiles(t *testing.T) {
+	dbname := "validatortest"
+	dropDB(dbname)
+
+	dir := fmt.Sprintf("%s/validatortest", os.TempDir())
+
+	must(os.RemoveAll(dir))
+	must(os.MkdirAll(dir, os.ModeDir|0755))
+	must(ioutil.WriteFile(dir+"/0002.sql", []byte("CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB;\n"), 0644))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB ROW_FORMAT=DYNAMIC`,
+		},
+		&migration.Definition{ID: 2, UpFile: dir + "/0002.sql"},
+	}
+
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithValidator(requireRowFormat),
+		migration.WithLogger(migration.NopLogger{}),
+	)
+	require.EqualError(t, err, "migration 2 failed validation (CREATE TABLE gralb ( di INT NOT NULL, PRIMARY KEY(di) ) ENGINE=InnoDB): CREATE TABLE must set ROW_FORMAT")
+	require.False(t, tableExists(fullDSN(dbname), "blarg"))
+}
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+const errQueryTimeout = 3024 // ER_QUERY_TIMEOUT
+
+// WithVerificationTimeout caps how long each read query made to verify a run,
+// such as looking up the size of the tables WithLargeTableWarning and
+// WithMaxTableRows check, may take. A query that takes longer is aborted by
+// the server and the check it was for is skipped with a warning rather than
+// failing the run. It needs MySQL 5.7.8 or later, other servers ignore it.
+func WithVerificationTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.verifyTimeout = timeout
+	}
+}
+
+// verificationQuery adds the WithVerificationTimeout cap to query.
+func (m *Migrator) verificationQuery(query string) string {
+	if m.verifyTimeout <= 0 {
+		return query
+	}
+	milliseconds := int64(m.verifyTimeout / time.Millisecond)
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+	return dialect.MaxExecutionTime(query, milliseconds)
+}
+
+// isVerificationTimeout reports whether err is down to a query exceeding
+// the WithVerificationTimeout cap.
+func isVerificationTimeout(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errQueryTimeout
+}
+
+// verificationSkipped warns that what couldn't be verified as its query
+// exceeded the WithVerificationTimeout cap.
+func (m *Migrator) verificationSkipped(ctx context.Context, what string) {
+	m.log(ctx, slog.LevelWarn,
+		fmt.Sprintf("verification skipped: %s exceeded %s", what, m.verifyTimeout),
+		slog.String("db", m.src.DBName),
+		slog.String("check", what),
+		slog.Duration("timeout", m.verifyTimeout),
+	)
+}
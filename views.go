@@ -0,0 +1,156 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rbone/migration/internal/dialect"
+)
+
+// viewSuffix names the files views are dumped to, so LoadSchema can create
+// them after the tables they select from.
+const viewSuffix = ".view.sql"
+
+// schemaObjects lists the tables and views in the database, leaving out the
+// tracking tables and their bookkeeping tables.
+func (m *Migrator) schemaObjects(ctx context.Context, conn *sql.DB) (tables []string, views []string, err error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT TABLE_NAME, TABLE_TYPE FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME",
+	)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to show tables")
+	}
+	defer rows.Close()
+
+	tables, views = []string{}, []string{}
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			return nil, nil, errors.Wrap(err, "unable to scan table name")
+		}
+		switch {
+		case kind == "VIEW":
+			views = append(views, name)
+		case !m.isTrackingTable(name) && !m.isBookkeepingTable(name):
+			tables = append(tables, name)
+		}
+	}
+	return tables, views, rows.Err()
+}
+
+// dumpView writes view's CREATE VIEW statement to dir. References to
+// the database's own tables are unqualified, so the dump can be loaded into
+// a database with another name.
+func (m *Migrator) dumpView(ctx context.Context, conn *sql.DB, dir SchemaWriter, view string) error {
+	if !validFileName(view) {
+		return errors.Errorf("view %q can't be dumped as its name isn't a valid file name", view)
+	}
+
+	var name, createStatement, charset, collation string
+	err := conn.QueryRowContext(ctx, "SHOW CREATE VIEW "+dialect.QuoteIdentifier(view)).Scan(&name, &createStatement, &charset, &collation)
+	if err != nil {
+		return errors.Wrapf(err, "failed showing create statement for view %q", view)
+	}
+	createStatement = strings.Replace(createStatement, dialect.QuoteIdentifier(m.src.DBName)+".", "", -1)
+	createStatement = m.normalizeDefinition(createStatement)
+
+	err = dir.WriteFile(view+viewSuffix, []byte(createStatement))
+	if err != nil {
+		return errors.Wrapf(err, "failed writing out create view statement for view %q", view)
+	}
+	return nil
+}
+
+// orderSchemaFiles puts the files of names that LoadSchema loads in the order
+// they have to be loaded in: tables first, then stored routines, views and
+// triggers, with views and triggers ahead of those that name them.
+func orderSchemaFiles(location string, names []string) ([]string, error) {
+	ordered := []string{}
+	routines, views, triggers := []string{}, []string{}, []string{}
+	for _, name := range names {
+		switch {
+		case strings.HasSuffix(name, procedureSuffix) || strings.HasSuffix(name, functionSuffix):
+			routines = append(routines, name)
+		case strings.HasSuffix(name, viewSuffix):
+			views = append(views, name)
+		case strings.HasSuffix(name, triggerSuffix):
+			triggers = append(triggers, name)
+		default:
+			ordered = append(ordered, name)
+		}
+	}
+	// routines aren't resolved until they're called, so their order
+	// doesn't matter
+	ordered = append(ordered, routines...)
+
+	for _, group := range []struct {
+		suffix string
+		names  []string
+	}{{viewSuffix, views}, {triggerSuffix, triggers}} {
+		names, err := orderByDependencies(location, group.names, group.suffix)
+		if err != nil {
+			return nil, err
+		}
+		ordered = append(ordered, names...)
+	}
+	return ordered, nil
+}
+
+// orderByDependencies orders files of objects so that those an object's
+// definition names, e.g. the views a view selects from, are ahead of it.
+func orderByDependencies(location string, names []string, suffix string) ([]string, error) {
+	ordered := []string{}
+	definitions := map[string]string{}
+	objects := []string{}
+	for _, name := range names {
+		definition, err := ioutil.ReadFile(filepath.Join(location, name))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read %q", name)
+		}
+		object := strings.TrimSuffix(name, suffix)
+		definitions[object] = string(definition)
+		objects = append(objects, object)
+	}
+	sort.Strings(objects)
+
+	// an object depends on the others its definition names, which may be
+	// overcautious but never misses one
+	created := map[string]bool{}
+	for len(created) < len(objects) {
+		progress := false
+		for _, object := range objects {
+			if created[object] || !dependenciesCreated(object, definitions, objects, created) {
+				continue
+			}
+			ordered = append(ordered, object+suffix)
+			created[object] = true
+			progress = true
+		}
+		if progress {
+			continue
+		}
+		// names that can't all be satisfied are loaded as they are, and
+		// MySQL reports whichever is actually missing
+		for _, object := range objects {
+			if !created[object] {
+				ordered = append(ordered, object+suffix)
+				created[object] = true
+			}
+		}
+	}
+	return ordered, nil
+}
+
+func dependenciesCreated(object string, definitions map[string]string, objects []string, created map[string]bool) bool {
+	for _, other := range objects {
+		if other != object && !created[other] && strings.Contains(definitions[object], dialect.QuoteIdentifier(other)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"testing"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpAndLoadViews(t *testing.T) {
+	dbname := "viewstest"
+	dropDB(dbname)
+	dropDB("viewstest_copy")
+	dir := t.TempDir()
+
+	migrations := []migration.Migration{
+		&migration.Definition{ID: 1, Up: `CREATE TABLE customers ( id INT NOT NULL, name VARCHAR(64) NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 2, Up: `CREATE TABLE orders ( id INT NOT NULL, customer_id INT NOT NULL, total INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`},
+		&migration.Definition{ID: 3, Up: `CREATE VIEW order_totals AS SELECT c.name, SUM(o.total) AS total FROM customers c JOIN orders o ON o.customer_id = c.id GROUP BY c.name`},
+		// sorts ahead of the view it selects from
+		&migration.Definition{ID: 4, Up: `CREATE VIEW big_spenders AS SELECT name FROM order_totals WHERE total > 100`},
+	}
+
+	require.NoError(t, migration.Migrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{})))
+	require.NoError(t, migration.DumpSchema(context.Background(), fullDSN(dbname), dir))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	require.Equal(t, []string{"_migrations.sql", "big_spenders.view.sql", "customers.sql", "order_totals.view.sql", "orders.sql"}, names)
+
+	view, err := ioutil.ReadFile(dir + "/order_totals.view.sql")
+	require.NoError(t, err)
+	require.NotContains(t, string(view), "migration_test_viewstest")
+
+	// loaded into a database with another name
+	require.NoError(t, migration.LoadSchema(context.Background(), fullDSN("viewstest_copy"), dir, migration.WithLogger(migration.NopLogger{})))
+	require.Len(t, queryVersions(fullDSN("viewstest_copy")), 4)
+
+	execSQL(fullDSN("viewstest_copy"), "INSERT INTO customers VALUES (1, 'alice'), (2, 'bob')")
+	execSQL(fullDSN("viewstest_copy"), "INSERT INTO orders VALUES (1, 1, 150), (2, 2, 50)")
+
+	conn, err := sql.Open("mysql", fullDSN("viewstest_copy"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var name string
+	require.NoError(t, conn.QueryRow("SELECT name FROM big_spenders").Scan(&name))
+	require.Equal(t, "alice", name)
+}
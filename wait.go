@@ -0,0 +1,117 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+// defaultWaitLogInterval is how often WaitUntilMigrated logs that it's still
+// waiting when WithProgressInterval isn't given.
+const defaultWaitLogInterval = time.Minute
+
+func WaitUntilMigrated(ctx context.Context, dsn string, migrations []Migration, pollInterval time.Duration, opts ...Option) error {
+	m, err := New(dsn, opts...)
+	if err != nil {
+		return err
+	}
+	return m.WaitUntilMigrated(ctx, migrations, pollInterval)
+}
+
+func WaitUntilMigratedConfig(ctx context.Context, cfg *mysql.Config, migrations []Migration, pollInterval time.Duration, opts ...Option) error {
+	m, err := NewConfig(cfg, opts...)
+	if err != nil {
+		return err
+	}
+	return m.WaitUntilMigrated(ctx, migrations, pollInterval)
+}
+
+func WaitUntilMigratedSource(ctx context.Context, src Source, migrations []Migration, pollInterval time.Duration, opts ...Option) error {
+	m, err := NewSource(src, opts...)
+	if err != nil {
+		return err
+	}
+	return m.WaitUntilMigrated(ctx, migrations, pollInterval)
+}
+
+// WaitUntilMigrated polls with IsUpToDate every pollInterval until all of
+// migrations have been executed, for instances that leave running Migrate to
+// another. The database being unreachable or not existing yet just means
+// polling again. It returns ctx's error if ctx is done first.
+func (m *Migrator) WaitUntilMigrated(ctx context.Context, migrations []Migration, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		return errors.Errorf("poll interval must be positive, got %s", pollInterval)
+	}
+	if err := validateMigrations(migrations); err != nil {
+		return err
+	}
+
+	logInterval := m.progressInterval
+	if logInterval <= 0 {
+		logInterval = defaultWaitLogInterval
+	}
+
+	target := 0
+	for _, migration := range migrations {
+		if migration.Version() > target {
+			target = migration.Version()
+		}
+	}
+
+	start := time.Now()
+	lastLogged := time.Time{}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		upToDate, err := m.IsUpToDate(ctx, migrations)
+		if err == nil && upToDate {
+			if !lastLogged.IsZero() {
+				m.log(ctx, slog.LevelInfo,
+					fmt.Sprintf("db %q has been migrated after waiting %s", m.src.DBName, time.Now().Sub(start).Round(time.Second)),
+					slog.String("db", m.src.DBName),
+				)
+			}
+			return nil
+		}
+
+		if time.Now().Sub(lastLogged) >= logInterval {
+			m.logWaiting(ctx, start, target, err)
+			lastLogged = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "gave up waiting for db %q to be migrated", m.src.DBName)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Migrator) logWaiting(ctx context.Context, start time.Time, target int, err error) {
+	elapsed := time.Now().Sub(start).Round(time.Second)
+	attrs := []slog.Attr{
+		slog.String("db", m.src.DBName),
+		slog.Int("target", target),
+		slog.Duration("elapsed", elapsed),
+	}
+
+	if err != nil {
+		m.log(ctx, slog.LevelWarn,
+			fmt.Sprintf("waiting for db %q to be migrated to %d after %s, can't check it: %s", m.src.DBName, target, elapsed, err),
+			append(attrs, slog.Any("error", err))...,
+		)
+		return
+	}
+
+	// only for the log line, it's 0 if the database can't be read
+	current, _ := m.CurrentVersion(ctx)
+	m.log(ctx, slog.LevelInfo,
+		fmt.Sprintf("waiting for db %q to be migrated to %d after %s, it's at %d", m.src.DBName, target, elapsed, current),
+		append(attrs, slog.Int("version", current))...,
+	)
+}
@@ -0,0 +1,72 @@
+package migration_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitUntilMigrated(t *testing.T) {
+	dbname := "waittest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- migration.WaitUntilMigrated(context.Background(), fullDSN(dbname), migrations, 50*time.Millisecond,
+			migration.WithLogger(migration.NopLogger{}),
+		)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case err := <-waited:
+		t.Fatalf("returned before migrating: %v", err)
+	default:
+	}
+
+	migration.MustMigrate(context.Background(), fullDSN(dbname), migrations, migration.WithLogger(migration.NopLogger{}))
+
+	select {
+	case err := <-waited:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("still waiting after migrating")
+	}
+}
+
+func TestWaitUntilMigratedGivesUp(t *testing.T) {
+	dbname := "waittest"
+	dropDB(dbname)
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	logger := &capturingLogger{}
+	err := migration.WaitUntilMigrated(ctx, fullDSN(dbname), migrations, 50*time.Millisecond, migration.WithLogger(logger))
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+	require.Contains(t, logger.lines, `waiting for db "migration_test_waittest" to be migrated to 1 after 0s, it's at 0`)
+}
+
+func TestWaitUntilMigratedRejectsNonPositivePollInterval(t *testing.T) {
+	err := migration.WaitUntilMigrated(context.Background(), fullDSN("waittest"), nil, 0)
+	require.EqualError(t, err, "poll interval must be positive, got 0s")
+}
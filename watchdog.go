@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// WithProgressInterval logs a "still running" line every interval while a
+// migration, or a file in LoadSchema, is executing, so long ALTERs don't leave
+// the logs silent. It is off by default.
+func WithProgressInterval(interval time.Duration) Option {
+	return func(m *Migrator) {
+		m.progressInterval = interval
+	}
+}
+
+// watch logs msg, with how long it's been, every progressInterval until the
+// returned func is called or ctx is done. The func waits for the watchdog to
+// stop, so nothing is logged after it returns.
+func (m *Migrator) watch(ctx context.Context, msg string, attrs ...slog.Attr) func() {
+	if m.progressInterval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		start := time.Now()
+		ticker := time.NewTicker(m.progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				elapsed := time.Now().Sub(start).Round(time.Second)
+				m.log(ctx, slog.LevelInfo,
+					fmt.Sprintf("%s after %s", msg, elapsed),
+					append(attrs[:len(attrs):len(attrs)], slog.Duration("elapsed", elapsed))...,
+				)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,54 @@
+package migration_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbone/migration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProgressIntervalLogsSlowMigrations(t *testing.T) {
+	dbname := "watchdogtest"
+	dropDB(dbname)
+	require.False(t, dbExists(dbname))
+
+	migrations := []migration.Migration{
+		&migration.Definition{
+			ID: 1,
+			Up: `CREATE TABLE blarg ( id INT NOT NULL, PRIMARY KEY(id) ) ENGINE=InnoDB`,
+		},
+		&migration.Definition{
+			ID: 2,
+			Up: `DO SLEEP(1.5)`,
+		},
+	}
+
+	logger := &capturingLogger{}
+	err := migration.Migrate(context.Background(), fullDSN(dbname), migrations,
+		migration.WithLogger(logger),
+		migration.WithLogLevel(slog.LevelInfo),
+		migration.WithProgressInterval(500*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	stillRunning := []string{}
+	for _, line := range logger.lines {
+		if strings.Contains(line, "still running") {
+			stillRunning = append(stillRunning, line)
+		}
+	}
+
+	require.True(t, len(stillRunning) >= 2, "expected progress lines, got %v", logger.lines)
+	for _, line := range stillRunning {
+		require.Regexp(t, `\Amigration 2 still running after \d+s\z`, line)
+	}
+
+	// nothing is logged once the migration has finished
+	count := len(logger.lines)
+	time.Sleep(time.Second)
+	require.Equal(t, count, len(logger.lines))
+}